@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// APITokensHandler
+// Tokens personales de acceso (scripts / integraciones)
+// =============================
+
+type APITokensHandler struct {
+	DB *pgxpool.Pool
+}
+
+// Prefijo de los tokens personales: permite distinguirlos de un JWT
+// sin tener que parsearlos.
+const APITokenPrefix = "pat_"
+
+// Scopes válidos
+const (
+	ScopeRead      = "read"
+	ScopeReadWrite = "read_write"
+)
+
+// Límite de tokens activos por usuario
+const maxAPITokensPorUsuario = 20
+
+// RegisterAPITokensRoutes registra /api/me/tokens (requiere AuthMiddleware).
+func RegisterAPITokensRoutes(rg *gin.RouterGroup, h *APITokensHandler) {
+	g := rg.Group("/me/tokens")
+
+	g.GET("", h.List)          // GET /api/me/tokens
+	g.POST("", h.Create)       // POST /api/me/tokens
+	g.DELETE("/:id", h.Revoke) // DELETE /api/me/tokens/:id
+}
+
+// =============================
+// DTOs
+// =============================
+
+type createAPITokenRequest struct {
+	Nombre        string     `json:"nombre"`
+	Scope         string     `json:"scope"`
+	ExpiresInDays *int       `json:"expires_in_days,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// =============================
+// Helpers
+// =============================
+
+// HashAPIToken devuelve el SHA-256 (hex) del token en claro.
+// Los tokens son aleatorios de 256 bits, así que no hace falta bcrypt
+// y el hash se puede buscar directamente por índice.
+func HashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsReadOnlyMethod indica si el método HTTP no modifica datos.
+func IsReadOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// ResolveAPIToken valida un token personal y devuelve claims equivalentes
+// a los del JWT más el scope del token. Actualiza last_used_at.
+func ResolveAPIToken(ctx context.Context, db *pgxpool.Pool, raw string) (*PlaneacionClaims, string, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return nil, "", errors.New("token inválido")
+	}
+
+	const q = `
+		SELECT t.id, t.scope, t.expires_at, t.revoked_at,
+		       u.id, u.email, u.role, u.unidad_id, u.is_active
+		FROM public.api_tokens t
+		JOIN public.usuarios u ON u.id = t.usuario_id
+		WHERE t.token_hash = $1;
+	`
+
+	var (
+		tokenID   int64
+		scope     string
+		expiresAt *time.Time
+		revokedAt *time.Time
+		isActive  bool
+		claims    PlaneacionClaims
+	)
+
+	err := db.QueryRow(ctx, q, HashAPIToken(raw)).Scan(
+		&tokenID,
+		&scope,
+		&expiresAt,
+		&revokedAt,
+		&claims.UserID,
+		&claims.Email,
+		&claims.Role,
+		&claims.UnidadID,
+		&isActive,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", errors.New("token inválido")
+		}
+		return nil, "", err
+	}
+
+	if revokedAt != nil {
+		return nil, "", errors.New("token revocado")
+	}
+	if expiresAt != nil && time.Now().After(*expiresAt) {
+		return nil, "", errors.New("token expirado")
+	}
+	if !isActive {
+		return nil, "", errors.New("usuario inactivo")
+	}
+
+	// best effort: no bloquea la petición si falla
+	_, _ = db.Exec(ctx, `UPDATE public.api_tokens SET last_used_at = now() WHERE id = $1`, tokenID)
+
+	claims.Subject = strconv.Itoa(claims.UserID)
+	return &claims, scope, nil
+}
+
+// Los tokens personales no pueden administrar otros tokens ni la contraseña
+// (evita que un token filtrado se auto-renueve o tome la cuenta).
+func rejectIfAPIToken(c *gin.Context) bool {
+	if method, ok := c.Get("auth_method"); ok && method == "api_token" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "operación no permitida con token personal; inicia sesión",
+		})
+		return true
+	}
+	return false
+}
+
+// =============================
+// Handler: LIST
+// =============================
+
+// GET /api/me/tokens
+func (h *APITokensHandler) List(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectIfAPIToken(c) {
+		return
+	}
+
+	const query = `
+		SELECT id, usuario_id, nombre, prefijo, scope, expires_at, last_used_at, revoked_at, created_at
+		FROM public.api_tokens
+		WHERE usuario_id = $1
+		ORDER BY created_at DESC;
+	`
+
+	rows, err := h.DB.Query(c.Request.Context(), query, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar tokens",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.APIToken, 0)
+	for rows.Next() {
+		var t models.APIToken
+		if err := rows.Scan(
+			&t.ID,
+			&t.UsuarioID,
+			&t.Nombre,
+			&t.Prefijo,
+			&t.Scope,
+			&t.ExpiresAt,
+			&t.LastUsedAt,
+			&t.RevokedAt,
+			&t.CreatedAt,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer tokens",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar tokens",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// =============================
+// Handler: CREATE
+// =============================
+
+// POST /api/me/tokens
+// Body: { "nombre": "export semestral", "scope": "read" | "read_write", "expires_in_days": 90 }
+// El token en claro solo se devuelve en esta respuesta.
+func (h *APITokensHandler) Create(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectIfAPIToken(c) {
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
+		return
+	}
+
+	nombre := strings.TrimSpace(req.Nombre)
+	if nombre == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "el nombre del token es obligatorio"})
+		return
+	}
+	if utf8.RuneCountInString(nombre) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "el nombre del token no debe exceder 100 caracteres"})
+		return
+	}
+
+	scope := strings.TrimSpace(strings.ToLower(req.Scope))
+	if scope == "" {
+		scope = ScopeRead
+	}
+	if scope != ScopeRead && scope != ScopeReadWrite {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope inválido (usa 'read' o 'read_write')"})
+		return
+	}
+
+	var expiresAt *time.Time
+	switch {
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at debe ser una fecha futura"})
+			return
+		}
+		expiresAt = req.ExpiresAt
+	case req.ExpiresInDays != nil:
+		if *req.ExpiresInDays <= 0 || *req.ExpiresInDays > 3650 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days debe estar entre 1 y 3650"})
+			return
+		}
+		t := time.Now().Add(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	ctx := c.Request.Context()
+
+	var activos int
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT COUNT(*) FROM public.api_tokens
+		 WHERE usuario_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())`,
+		claims.UserID,
+	).Scan(&activos); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al validar tokens existentes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	if activos >= maxAPITokensPorUsuario {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "alcanzaste el máximo de tokens activos; revoca alguno antes de crear otro",
+		})
+		return
+	}
+
+	raw, err := generateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo generar el token",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	prefijo := raw[:len(APITokenPrefix)+8]
+
+	const insertSQL = `
+		INSERT INTO public.api_tokens (usuario_id, nombre, token_hash, prefijo, scope, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, usuario_id, nombre, prefijo, scope, expires_at, last_used_at, revoked_at, created_at;
+	`
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var t models.APIToken
+	if err := tx.QueryRow(
+		ctx,
+		insertSQL,
+		claims.UserID,
+		nombre,
+		HashAPIToken(raw),
+		prefijo,
+		scope,
+		expiresAt,
+	).Scan(
+		&t.ID,
+		&t.UsuarioID,
+		&t.Nombre,
+		&t.Prefijo,
+		&t.Scope,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.RevokedAt,
+		&t.CreatedAt,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al crear token",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionTokenCrear, "api_token", t.ID, gin.H{
+		"nombre":     t.Nombre,
+		"prefijo":    t.Prefijo,
+		"scope":      t.Scope,
+		"expires_at": t.ExpiresAt,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token": raw, // ⚠️ solo se muestra una vez
+		"item":  t,
+	})
+}
+
+// =============================
+// Handler: REVOKE
+// =============================
+
+// DELETE /api/me/tokens/:id
+// No se borra la fila: se marca revoked_at para conservar el historial.
+func (h *APITokensHandler) Revoke(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectIfAPIToken(c) {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		prefijo    string
+		yaRevocado bool
+	)
+	err = tx.QueryRow(
+		ctx,
+		`SELECT prefijo, revoked_at IS NOT NULL FROM public.api_tokens
+		 WHERE id = $1 AND usuario_id = $2 FOR UPDATE`,
+		id,
+		claims.UserID,
+	).Scan(&prefijo, &yaRevocado)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al revocar token",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	// Revocar dos veces no falla ni se vuelve a registrar
+	if !yaRevocado {
+		if _, err := tx.Exec(ctx, `UPDATE public.api_tokens SET revoked_at = now() WHERE id = $1`, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al revocar token",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionTokenRevocar, "api_token", id, gin.H{
+			"prefijo": prefijo,
+		})); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "no se pudo registrar auditoría",
+				"msg":   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Planeaciones archivadas (status 'archivada')
+// Una planeación archivada es de solo lectura y sale del listado por
+// default. Si estaba publicada (finalizada_at) sigue en /api/public marcada
+// como histórica. Al desarchivar recupera su status anterior (status_previo).
+// =============================
+
+// sqlPublicada: condición de visibilidad en /api/public (alias p).
+const sqlPublicada = "(p.status = 'finalizada' OR (p.status = 'archivada' AND p.finalizada_at IS NOT NULL))"
+
+// =============================
+// POST /api/planeaciones/:id/archivar
+// =============================
+
+func (h *PlaneacionesHandler) Archivar(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var status string
+	err = tx.QueryRow(
+		c,
+		`SELECT status::text FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		id,
+		claims.UserID,
+	).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	// idempotente: archivar dos veces no falla
+	if status == "archivada" {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "status": "archivada"})
+		return
+	}
+
+	if _, err := tx.Exec(
+		c,
+		`
+UPDATE planeaciones
+SET
+  status_previo = status,
+  status = 'archivada',
+  archivada_at = now(),
+  updated_at = now()
+WHERE id = $1
+		`,
+		id,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo archivar la planeación: " + err.Error()})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionArchivar, "planeacion", int64(id), gin.H{
+		"status": gin.H{"de": status, "a": "archivada"},
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
+		return
+	}
+	if status == "finalizada" {
+		invalidarCachePublica() // ahora se muestra como histórica
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "status": "archivada"})
+}
+
+// =============================
+// POST /api/planeaciones/:id/desarchivar
+// Regresa al status que tenía antes de archivarse; si era 'finalizada' se
+// publica de nuevo con las mismas validaciones (400 con el reporte si ya no
+// es consistente)
+// =============================
+
+func (h *PlaneacionesHandler) Desarchivar(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var status string
+	err = tx.QueryRow(
+		c,
+		`SELECT status::text FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		id,
+		claims.UserID,
+	).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	if status != "archivada" {
+		c.JSON(http.StatusConflict, gin.H{"error": "La planeación no está archivada."})
+		return
+	}
+
+	// Sin status_previo (archivada antes de 016): publicada -> finalizada
+	var nuevo string
+	if err := tx.QueryRow(
+		c,
+		`
+UPDATE planeaciones
+SET
+  status = COALESCE(status_previo, CASE WHEN finalizada_at IS NOT NULL THEN 'finalizada' ELSE 'borrador' END::planeacion_status),
+  status_previo = NULL,
+  archivada_at = NULL,
+  updated_at = now()
+WHERE id = $1
+RETURNING status::text
+		`,
+		id,
+	).Scan(&nuevo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo desarchivar la planeación: " + err.Error()})
+		return
+	}
+
+	// Vuelve a publicarse: mismas reglas que al finalizar desde Update
+	if nuevo == "finalizada" {
+		if err := prepararPublicacion(c, tx, id); err != nil {
+			if respondValidacionError(c, err) {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo publicar: " + err.Error()})
+			return
+		}
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionDesarchivar, "planeacion", int64(id), gin.H{
+		"status": gin.H{"de": "archivada", "a": nuevo},
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
+		return
+	}
+	invalidarCachePublica()
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "status": nuevo})
+}
+
+// =============================
+// POST /api/admin/calendario/periodos/:id/archivar-planeaciones
+// Cierre de periodo: archiva todas las planeaciones cuya clave de periodo
+// coincide (con la misma prioridad que calendarioDePlaneacion: un periodo
+// general no toca unidades que tienen su propio periodo con esa clave).
+// Solo periodos terminados (fecha_fin < hoy).
+// =============================
+
+func (h *CalendarioHandler) ArchivarPlaneacionesPeriodo(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		clave    string
+		cerrado  bool
+		fechaFin string
+	)
+	err = tx.QueryRow(
+		ctx,
+		`SELECT clave, fecha_fin < current_date, to_char(fecha_fin, 'YYYY-MM-DD')
+		 FROM public.periodos_escolares WHERE id = $1`,
+		id,
+	).Scan(&clave, &cerrado, &fechaFin)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "periodo escolar no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar periodo escolar",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	if !cerrado {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "el periodo escolar aún no termina",
+			"fecha_fin": fechaFin,
+		})
+		return
+	}
+
+	// Una fila de bitácora por planeación archivada
+	rows, err := tx.Query(
+		ctx,
+		`
+WITH pe AS (
+  SELECT id, clave, unidad_academica_id FROM public.periodos_escolares WHERE id = $1
+),
+archivadas AS (
+  UPDATE planeaciones p
+  SET
+    status_previo = p.status,
+    status = 'archivada',
+    archivada_at = now(),
+    updated_at = now()
+  FROM pe
+  WHERE lower(btrim(p.periodo)) = lower(btrim(pe.clave))
+    AND p.deleted_at IS NULL
+    AND p.status <> 'archivada'
+    AND (pe.unidad_academica_id IS NULL OR p.unidad_academica_id = pe.unidad_academica_id)
+    AND (pe.unidad_academica_id IS NOT NULL OR NOT EXISTS (
+      SELECT 1 FROM public.periodos_escolares propio
+      WHERE propio.unidad_academica_id = p.unidad_academica_id
+        AND lower(btrim(propio.clave)) = lower(btrim(pe.clave))
+    ))
+  RETURNING p.id, p.status_previo::text AS de
+),
+bitacora AS (
+  INSERT INTO public.audit_log (actor_id, actor_email, accion, entidad, entidad_id, ip, user_agent, cambios)
+  SELECT $2, NULLIF($3, ''), $4, 'planeacion', a.id, NULLIF($5, ''), NULLIF($6, ''),
+         jsonb_build_object('status', jsonb_build_object('de', a.de, 'a', 'archivada'), 'periodo_escolar_id', $1::bigint)
+  FROM archivadas a
+)
+SELECT de, COUNT(*) FROM archivadas GROUP BY de
+		`,
+		id,
+		claims.UserID,
+		recortar(claims.Email, 255),
+		accionPlaneacionArchivar,
+		recortar(c.ClientIP(), 64),
+		recortar(c.Request.UserAgent(), 512),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al archivar planeaciones",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	porStatus := map[string]int{}
+	total := 0
+	for rows.Next() {
+		var (
+			de string
+			n  int
+		)
+		if err := rows.Scan(&de, &n); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer planeaciones archivadas",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		porStatus[de] = n
+		total += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al archivar planeaciones",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	if porStatus["finalizada"] > 0 {
+		invalidarCachePublica()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"periodo_escolar_id": id,
+		"clave":              strings.TrimSpace(clave),
+		"archivadas":         total,
+		"por_status":         porStatus,
+	})
+}
@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// Bitácora de auditoría (public.audit_log)
+// Los handlers que modifican datos llaman a registrarAuditoria con su
+// transacción, así el registro se confirma o se descarta junto con el cambio.
+// =============================
+
+// Acciones registradas
+const (
+	accionPlaneacionCrear           = "planeacion.crear"
+	accionPlaneacionEditar          = "planeacion.editar"
+	accionPlaneacionFinalizar       = "planeacion.finalizar"
+	accionPlaneacionReabrir         = "planeacion.reabrir"
+	accionPlaneacionEliminar        = "planeacion.eliminar" // a la papelera
+	accionPlaneacionRestaurar       = "planeacion.restaurar"
+	accionPlaneacionPurgar          = "planeacion.purgar" // borrado definitivo (sin actor)
+	accionPlaneacionArchivar        = "planeacion.archivar"
+	accionPlaneacionDesarchivar     = "planeacion.desarchivar"
+	accionPlaneacionSlug            = "planeacion.slug"
+	accionAuthRegistro              = "auth.registro"
+	accionAuthLogin                 = "auth.login"
+	accionAuthLoginFallido          = "auth.login_fallido"
+	accionAuthGoogle                = "auth.google"
+	accionAuthPassword              = "auth.password"
+	accionTokenCrear                = "token.crear"
+	accionTokenRevocar              = "token.revocar"
+	accionIdentidadVincular         = "identidad.vincular"
+	accionIdentidadDesvincular      = "identidad.desvincular"
+	accionPerfilEditar              = "perfil.editar"
+	accionSolicitudUnidadCrear      = "solicitud_unidad.crear"
+	accionSolicitudUnidadAprobar    = "solicitud_unidad.aprobar"
+	accionSolicitudUnidadRechazar   = "solicitud_unidad.rechazar"
+	accionSolicitudUnidadCancelar   = "solicitud_unidad.cancelar"
+	accionCatalogoCrear             = "catalogo.crear"
+	accionCatalogoEditar            = "catalogo.editar"
+	accionCatalogoEliminar          = "catalogo.eliminar"
+	accionUnidadAcademicaCrear      = "unidad_academica.crear"
+	accionUnidadAcademicaEditar     = "unidad_academica.editar"
+	accionUnidadAcademicaDesactivar = "unidad_academica.desactivar"
+	accionCalendarioCrear           = "calendario.crear"
+	accionCalendarioEditar          = "calendario.editar"
+	accionCalendarioEliminar        = "calendario.eliminar"
+	accionPlantillaCrear            = "plantilla.crear"
+	accionPlantillaEditar           = "plantilla.editar"
+	accionPlantillaEliminar         = "plantilla.eliminar"
+)
+
+// ejecutorSQL: pgx.Tx o *pgxpool.Pool
+type ejecutorSQL interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type eventoAuditoria struct {
+	ActorID    int // 0 = anónimo (p. ej. login fallido)
+	ActorEmail string
+	Accion     string
+	Entidad    string
+	EntidadID  int64 // 0 = sin entidad
+	Cambios    any   // resumen compacto; nil = sin detalle
+}
+
+// eventoDeClaims arma un evento cuyo actor es el usuario de la sesión.
+func eventoDeClaims(claims *PlaneacionClaims, accion, entidad string, entidadID int64, cambios any) eventoAuditoria {
+	return eventoAuditoria{
+		ActorID:    claims.UserID,
+		ActorEmail: claims.Email,
+		Accion:     accion,
+		Entidad:    entidad,
+		EntidadID:  entidadID,
+		Cambios:    cambios,
+	}
+}
+
+// eventoDeSesion: como eventoDeClaims, con el usuario ya autenticado por el
+// middleware (rutas admin que no leen los claims por su cuenta).
+func eventoDeSesion(c *gin.Context, accion, entidad string, entidadID int64, cambios any) eventoAuditoria {
+	ev := eventoAuditoria{Accion: accion, Entidad: entidad, EntidadID: entidadID, Cambios: cambios}
+	if claims, err := getClaimsFromHeader(c); err == nil {
+		ev.ActorID, ev.ActorEmail = claims.UserID, claims.Email
+	}
+	return ev
+}
+
+// conAuditoria ejecuta cambio y registra el evento que regresa en la misma
+// transacción. Los errores de cambio se regresan tal cual (pgx.ErrNoRows,
+// violaciones de unicidad, ...) para que el handler los traduzca.
+func conAuditoria(c *gin.Context, db *pgxpool.Pool, cambio func(tx pgx.Tx) (eventoAuditoria, error)) error {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ev, err := cambio(tx)
+	if err != nil {
+		return err
+	}
+	if err := registrarAuditoria(c, tx, ev); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func recortar(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+	return s
+}
+
+// registrarAuditoria inserta el evento con la IP y el user agent de la petición.
+func registrarAuditoria(c *gin.Context, db ejecutorSQL, ev eventoAuditoria) error {
+	var cambios any
+	if ev.Cambios != nil {
+		raw, err := json.Marshal(ev.Cambios)
+		if err != nil {
+			return err
+		}
+		cambios = string(raw)
+	}
+
+	_, err := db.Exec(
+		c,
+		`INSERT INTO public.audit_log (actor_id, actor_email, accion, entidad, entidad_id, ip, user_agent, cambios)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8::jsonb)`,
+		idOrNil(int64(ev.ActorID)),
+		recortar(ev.ActorEmail, 255),
+		ev.Accion,
+		ev.Entidad,
+		idOrNil(ev.EntidadID),
+		recortar(c.ClientIP(), 64),
+		recortar(c.Request.UserAgent(), 512),
+		cambios,
+	)
+	return err
+}
+
+// idOrNil: 0 = sin id (NULL)
+func idOrNil(n int64) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// resumenSolicitud: ruta, parámetros y nombres de los campos enviados
+// (sin valores, para que la bitácora se mantenga compacta).
+func resumenSolicitud(c *gin.Context, body any) map[string]any {
+	out := map[string]any{"ruta": c.Request.Method + " " + c.FullPath()}
+	params := map[string]string{}
+	for _, p := range c.Params {
+		if p.Key != "id" {
+			params[p.Key] = p.Value
+		}
+	}
+	if len(params) > 0 {
+		out["parametros"] = params
+	}
+	if body != nil {
+		campos := make([]string, 0)
+		for k := range aMapa(body) {
+			campos = append(campos, k)
+		}
+		sort.Strings(campos)
+		out["campos"] = campos
+	}
+	return out
+}
+
+// =============================
+// Consulta (solo admin)
+// =============================
+
+type AuditoriaHandler struct {
+	DB *pgxpool.Pool
+}
+
+// RegisterAuditoriaAdminRoutes registra la consulta de la bitácora (solo admin).
+func RegisterAuditoriaAdminRoutes(rg *gin.RouterGroup, h *AuditoriaHandler) {
+	g := rg.Group("/auditoria")
+
+	g.GET("", h.List)          // GET /api/admin/auditoria?actor_id=&actor=&accion=&entidad=&entidad_id=&desde=&hasta=&limit=&offset=
+	g.GET("/csv", h.ExportCSV) // GET /api/admin/auditoria/csv (mismos filtros)
+}
+
+const auditoriaCols = `id, actor_id, actor_email, accion, entidad, entidad_id, ip, user_agent, cambios, created_at`
+
+// filtrosAuditoria arma el WHERE común de List y ExportCSV.
+// accion admite prefijo con punto final (p. ej. "auth." = todas las de autenticación).
+func filtrosAuditoria(c *gin.Context) (string, []any, bool) {
+	where := []string{"TRUE"}
+	args := []any{}
+	agregar := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, strings.ReplaceAll(cond, "$?", "$"+strconv.Itoa(len(args))))
+	}
+
+	for _, f := range []string{"actor_id", "entidad_id"} {
+		s := strings.TrimSpace(c.Query(f))
+		if s == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f + " inválido"})
+			return "", nil, false
+		}
+		agregar(f+" = $?", n)
+	}
+	if s := strings.TrimSpace(c.Query("actor")); s != "" {
+		agregar("actor_email ILIKE '%' || $? || '%'", s)
+	}
+	if s := strings.TrimSpace(c.Query("accion")); s != "" {
+		if strings.HasSuffix(s, ".") {
+			agregar("accion LIKE $? || '%'", s)
+		} else {
+			agregar("accion = $?", s)
+		}
+	}
+	if s := strings.TrimSpace(c.Query("entidad")); s != "" {
+		agregar("entidad = $?", s)
+	}
+	for _, f := range []struct{ param, cond string }{
+		{"desde", "created_at >= $?::date"},
+		{"hasta", "created_at < $?::date + 1"},
+	} {
+		s := strings.TrimSpace(c.Query(f.param))
+		if s == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.param + " debe tener formato YYYY-MM-DD"})
+			return "", nil, false
+		}
+		agregar(f.cond, s)
+	}
+	return strings.Join(where, " AND "), args, true
+}
+
+// GET /api/admin/auditoria
+func (h *AuditoriaHandler) List(c *gin.Context) {
+	where, args, ok := filtrosAuditoria(c)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 500"})
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset inválido"})
+			return
+		}
+		offset = n
+	}
+
+	ctx := c.Request.Context()
+
+	var total int
+	if err := h.DB.QueryRow(ctx, `SELECT COUNT(*) FROM public.audit_log WHERE `+where, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	rows, err := h.DB.Query(
+		ctx,
+		`SELECT `+auditoriaCols+` FROM public.audit_log
+		 WHERE `+where+`
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT `+strconv.Itoa(limit)+` OFFSET `+strconv.Itoa(offset),
+		args...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.RegistroAuditoria, 0)
+	for rows.Next() {
+		var r models.RegistroAuditoria
+		if err := rows.Scan(
+			&r.ID, &r.ActorID, &r.ActorEmail, &r.Accion, &r.Entidad, &r.EntidadID,
+			&r.IP, &r.UserAgent, &r.Cambios, &r.CreatedAt,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer auditoría",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, r)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// Máximo de filas por exportación
+const maxFilasCSVAuditoria = 100000
+
+// GET /api/admin/auditoria/csv
+// Si la lectura falla a media exportación (el 200 ya se envió), el archivo
+// termina con una fila "#ERROR" en lugar de cortarse sin aviso.
+func (h *AuditoriaHandler) ExportCSV(c *gin.Context) {
+	where, args, ok := filtrosAuditoria(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.DB.Query(
+		c.Request.Context(),
+		`SELECT `+auditoriaCols+` FROM public.audit_log
+		 WHERE `+where+`
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT `+strconv.Itoa(maxFilasCSVAuditoria),
+		args...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	nombre := "auditoria-" + time.Now().Format("20060102-150405") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+nombre+`"`)
+	c.Status(http.StatusOK)
+
+	// BOM para que Excel detecte UTF-8
+	_, _ = c.Writer.WriteString("\ufeff")
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "fecha", "actor_id", "actor_email", "accion", "entidad", "entidad_id", "ip", "user_agent", "cambios"})
+
+	var errLectura error
+	for rows.Next() {
+		var r models.RegistroAuditoria
+		if err := rows.Scan(
+			&r.ID, &r.ActorID, &r.ActorEmail, &r.Accion, &r.Entidad, &r.EntidadID,
+			&r.IP, &r.UserAgent, &r.Cambios, &r.CreatedAt,
+		); err != nil {
+			errLectura = err
+			break
+		}
+		actorID, entidadID := "", ""
+		if r.ActorID != nil {
+			actorID = strconv.Itoa(*r.ActorID)
+		}
+		if r.EntidadID != nil {
+			entidadID = strconv.FormatInt(*r.EntidadID, 10)
+		}
+		_ = w.Write([]string{
+			strconv.FormatInt(r.ID, 10),
+			r.CreatedAt.UTC().Format(time.RFC3339),
+			actorID,
+			deref(r.ActorEmail),
+			r.Accion,
+			r.Entidad,
+			entidadID,
+			deref(r.IP),
+			deref(r.UserAgent),
+			string(r.Cambios),
+		})
+	}
+	if errLectura == nil {
+		errLectura = rows.Err()
+	}
+	if errLectura != nil {
+		// Los encabezados ya se enviaron: se marca el archivo como incompleto
+		log.Println("⚠️ Exportación de auditoría incompleta:", errLectura)
+		_ = w.Write([]string{"#ERROR", "exportación incompleta: " + errLectura.Error()})
+		_ = c.Error(errLectura)
+	}
+	w.Flush()
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -39,9 +40,11 @@ func RegisterAuthRoutes(rg *gin.RouterGroup, h *AuthHandler) {
 
 	// 👇 NUEVO: login con Google (id_token)
 	rg.POST("/auth/google", h.GoogleLogin)
+}
 
-	// Usuario actual
-	rg.GET("/me", h.Me)
+// RegisterMeRoutes registra el usuario actual (requiere AuthMiddleware).
+func RegisterMeRoutes(rg *gin.RouterGroup, h *AuthHandler) {
+	rg.GET("/me", h.Me) // GET /api/me
 }
 
 // =============================
@@ -124,6 +127,13 @@ func randomPasswordHashLike() string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
+// Usuarios Google tienen un password_hash aleatorio (no bcrypt):
+// eso indica que aún no han definido contraseña propia.
+func hasUsablePassword(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
 func signJWTForUser(u models.Usuario) (string, error) {
 	secret, err := getJWTSecret()
 	if err != nil {
@@ -174,14 +184,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	const checkUnidadSQL = `
 		SELECT 1
 		FROM public.unidades_academicas
-		WHERE id = $1;
+		WHERE id = $1 AND activa;
 	`
 
 	var dummy int
 	if err := h.DB.QueryRow(ctx, checkUnidadSQL, req.UnidadID).Scan(&dummy); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "la unidad académica especificada no existe",
+				"error": "la unidad académica especificada no existe o está inactiva",
 			})
 			return
 		}
@@ -229,8 +239,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		RETURNING id, unidad_id, nombre_completo, email, role, is_active, created_at, updated_at;
 	`
 
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	var u models.Usuario
-	if err := h.DB.QueryRow(
+	if err := tx.QueryRow(
 		ctx,
 		insertSQL,
 		req.UnidadID,
@@ -254,6 +274,29 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if err := registrarAuditoria(c, tx, eventoAuditoria{
+		ActorID:    u.ID,
+		ActorEmail: u.Email,
+		Accion:     accionAuthRegistro,
+		Entidad:    "usuario",
+		EntidadID:  int64(u.ID),
+		Cambios:    gin.H{"unidad_id": u.UnidadID, "metodo": "password"},
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"user": u,
 	})
@@ -305,6 +348,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
+			h.auditarLogin(c, accionAuthLoginFallido, 0, email, "usuario_inexistente")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "credenciales inválidas",
 			})
@@ -318,6 +362,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	if !u.IsActive {
+		h.auditarLogin(c, accionAuthLoginFallido, u.ID, u.Email, "usuario_inactivo")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "usuario inactivo",
 		})
@@ -325,6 +370,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	if err := hashMatchesPassword(passwordHash, req.Password); err != nil {
+		h.auditarLogin(c, accionAuthLoginFallido, u.ID, u.Email, "password_incorrecto")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "credenciales inválidas",
 		})
@@ -340,6 +386,8 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	h.auditarLogin(c, accionAuthLogin, u.ID, u.Email, "")
+
 	c.JSON(http.StatusOK, gin.H{
 		"access_token": signed,
 		"token_type":   "bearer",
@@ -347,47 +395,70 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// auditarLogin registra el intento de inicio de sesión. No modifica datos,
+// así que un fallo de la bitácora no impide entrar (solo se reporta en el log).
+func (h *AuthHandler) auditarLogin(c *gin.Context, accion string, usuarioID int, email, motivo string) {
+	ev := eventoAuditoria{
+		ActorID:    usuarioID,
+		ActorEmail: email,
+		Accion:     accion,
+		Entidad:    "usuario",
+		EntidadID:  int64(usuarioID),
+		Cambios:    gin.H{"metodo": "password"},
+	}
+	if motivo != "" {
+		ev.Cambios = gin.H{"metodo": "password", "motivo": motivo}
+	}
+	if err := registrarAuditoria(c, h.DB, ev); err != nil {
+		log.Println("⚠️ No se pudo registrar auditoría de login:", err)
+	}
+}
+
 // =============================
 // Handler: GOOGLE LOGIN
 // =============================
 
-// POST /api/auth/google
-// Body: { "id_token": "...", "unidad_id": 1 }  (unidad_id solo si es usuario nuevo)
-func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	var req googleLoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
-		return
-	}
-
-	idTok := strings.TrimSpace(req.IDToken)
-	if idTok == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id_token es obligatorio"})
-		return
-	}
+// Identidad verificada a partir de un id_token de Google
+type googleIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Nombre        string
+}
 
+// verifyGoogleIDToken valida el id_token y extrae sub / email / nombre.
+// Devuelve el status HTTP sugerido junto con el error.
+func verifyGoogleIDToken(ctx context.Context, idTok string) (*googleIdentity, int, error) {
 	aud, err := getGoogleClientID()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "configuración Google inválida", "msg": err.Error()})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
-	payload, err := idtoken.Validate(context.Background(), idTok, aud)
+	payload, err := idtoken.Validate(ctx, idTok, aud)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "id_token inválido", "msg": err.Error()})
-		return
+		return nil, http.StatusUnauthorized, err
 	}
 
-	emailAny, ok := payload.Claims["email"]
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "id_token sin email"})
-		return
+	sub := strings.TrimSpace(payload.Subject)
+	if sub == "" {
+		return nil, http.StatusUnauthorized, errors.New("id_token sin sub")
+	}
+
+	email := ""
+	if v, ok := payload.Claims["email"]; ok {
+		if s, ok := v.(string); ok {
+			email = strings.TrimSpace(s)
+		}
 	}
-	email, _ := emailAny.(string)
-	email = strings.TrimSpace(email)
 	if email == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "email inválido en token"})
-		return
+		return nil, http.StatusUnauthorized, errors.New("id_token sin email")
+	}
+
+	verified := false
+	if v, ok := payload.Claims["email_verified"]; ok {
+		if b, ok := v.(bool); ok {
+			verified = b
+		}
 	}
 
 	// nombre opcional
@@ -401,17 +472,16 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 		nombre = email
 	}
 
-	ctx := c.Request.Context()
-
-	// buscar usuario por email
-	const qUser = `
-		SELECT id, unidad_id, nombre_completo, email, role, is_active, created_at, updated_at
-		FROM public.usuarios
-		WHERE email = $1;
-	`
+	return &googleIdentity{
+		Subject:       sub,
+		Email:         email,
+		EmailVerified: verified,
+		Nombre:        nombre,
+	}, http.StatusOK, nil
+}
 
-	var u models.Usuario
-	err = h.DB.QueryRow(ctx, qUser, email).Scan(
+func scanUsuario(row pgx.Row, u *models.Usuario) error {
+	return row.Scan(
 		&u.ID,
 		&u.UnidadID,
 		&u.NombreCompleto,
@@ -421,10 +491,127 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	)
+}
+
+// POST /api/auth/google
+// Body: { "id_token": "...", "unidad_id": 1 }  (unidad_id solo si es usuario nuevo)
+//
+// Resolución del usuario:
+//  1. identidad vinculada (provider='google', subject=sub)
+//  2. cuentas previas a usuario_identidades: por email verificado, y se vincula
+//  3. usuario nuevo (requiere unidad_id) + identidad
+func (h *AuthHandler) GoogleLogin(c *gin.Context) {
+	var req googleLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
+		return
+	}
 
+	idTok := strings.TrimSpace(req.IDToken)
+	if idTok == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id_token es obligatorio"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	gid, status, err := verifyGoogleIDToken(ctx, idTok)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			// usuario nuevo: requiere unidad_id
+		if status == http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": "configuración Google inválida", "msg": err.Error()})
+			return
+		}
+		c.JSON(status, gin.H{"error": "id_token inválido", "msg": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "no se pudo iniciar transacción", "msg": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// 1) buscar por identidad (sub)
+	const qByIdentity = `
+		SELECT u.id, u.unidad_id, u.nombre_completo, u.email, u.role, u.is_active, u.created_at, u.updated_at
+		FROM public.usuario_identidades i
+		JOIN public.usuarios u ON u.id = i.usuario_id
+		WHERE i.provider = 'google' AND i.subject = $1;
+	`
+
+	var u models.Usuario
+	err = scanUsuario(tx.QueryRow(ctx, qByIdentity, gid.Subject), &u)
+	resultado := "identidad_existente" // para la bitácora
+
+	switch {
+	case err == nil:
+		// identidad conocida: refrescar email del proveedor y último acceso
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE public.usuario_identidades
+			 SET email = $2, last_login_at = now()
+			 WHERE provider = 'google' AND subject = $1`,
+			gid.Subject,
+			gid.Email,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error al actualizar identidad", "msg": err.Error()})
+			return
+		}
+
+	case errors.Is(err, pgx.ErrNoRows):
+		// 2) cuenta existente por email (anterior a usuario_identidades)
+		const qByEmail = `
+			SELECT id, unidad_id, nombre_completo, email, role, is_active, created_at, updated_at
+			FROM public.usuarios
+			WHERE email = $1;
+		`
+		err = scanUsuario(tx.QueryRow(ctx, qByEmail, gid.Email), &u)
+
+		if err == nil {
+			// El usuario desvinculó Google: no se vuelve a vincular solo por email
+			var desvinculado bool
+			if err := tx.QueryRow(
+				ctx,
+				`SELECT google_desvinculado_at IS NOT NULL FROM public.usuarios WHERE id = $1`,
+				u.ID,
+			).Scan(&desvinculado); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error al consultar usuario", "msg": err.Error()})
+				return
+			}
+			if desvinculado {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "desvinculaste Google de esta cuenta; inicia sesión con contraseña y vuelve a vincularla",
+					"hint":  "POST /api/me/identidades/google",
+				})
+				return
+			}
+
+			if !gid.EmailVerified {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "el email de Google no está verificado; inicia sesión con contraseña y vincula la cuenta",
+				})
+				return
+			}
+
+			var yaVinculada int
+			err := tx.QueryRow(
+				ctx,
+				`SELECT 1 FROM public.usuario_identidades WHERE usuario_id = $1 AND provider = 'google'`,
+				u.ID,
+			).Scan(&yaVinculada)
+			if err == nil {
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "este email ya está vinculado a otra cuenta de Google",
+				})
+				return
+			} else if !errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error al consultar identidades", "msg": err.Error()})
+				return
+			}
+			resultado = "cuenta_vinculada"
+		} else if errors.Is(err, pgx.ErrNoRows) {
+			// 3) usuario nuevo: requiere unidad_id
 			if req.UnidadID <= 0 {
 				c.JSON(http.StatusBadRequest, gin.H{
 					"error": "usuario nuevo: unidad_id es obligatorio para crearlo",
@@ -436,12 +623,12 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 			const checkUnidadSQL = `
 				SELECT 1
 				FROM public.unidades_academicas
-				WHERE id = $1;
+				WHERE id = $1 AND activa;
 			`
 			var dummy int
-			if err := h.DB.QueryRow(ctx, checkUnidadSQL, req.UnidadID).Scan(&dummy); err != nil {
+			if err := tx.QueryRow(ctx, checkUnidadSQL, req.UnidadID).Scan(&dummy); err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "la unidad académica especificada no existe"})
+					c.JSON(http.StatusBadRequest, gin.H{"error": "la unidad académica especificada no existe o está inactiva"})
 					return
 				}
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "error al validar unidad académica", "msg": err.Error()})
@@ -456,23 +643,32 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 			`
 
 			pw := randomPasswordHashLike()
-			if err := h.DB.QueryRow(ctx, insertSQL, req.UnidadID, nombre, email, pw).Scan(
-				&u.ID,
-				&u.UnidadID,
-				&u.NombreCompleto,
-				&u.Email,
-				&u.Role,
-				&u.IsActive,
-				&u.CreatedAt,
-				&u.UpdatedAt,
-			); err != nil {
+			if err := scanUsuario(tx.QueryRow(ctx, insertSQL, req.UnidadID, gid.Nombre, gid.Email, pw), &u); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "error al crear usuario google", "msg": err.Error()})
 				return
 			}
+			resultado = "usuario_nuevo"
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error al consultar usuario", "msg": err.Error()})
 			return
 		}
+
+		// vincular identidad (casos 2 y 3)
+		if _, err := tx.Exec(
+			ctx,
+			`INSERT INTO public.usuario_identidades (usuario_id, provider, subject, email, last_login_at)
+			 VALUES ($1, 'google', $2, $3, now())`,
+			u.ID,
+			gid.Subject,
+			gid.Email,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error al vincular identidad google", "msg": err.Error()})
+			return
+		}
+
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error al consultar identidad", "msg": err.Error()})
+		return
 	}
 
 	if !u.IsActive {
@@ -480,6 +676,23 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 		return
 	}
 
+	if err := registrarAuditoria(c, tx, eventoAuditoria{
+		ActorID:    u.ID,
+		ActorEmail: u.Email,
+		Accion:     accionAuthGoogle,
+		Entidad:    "usuario",
+		EntidadID:  int64(u.ID),
+		Cambios:    gin.H{"metodo": "google", "resultado": resultado},
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "no se pudo registrar auditoría", "msg": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "no se pudo confirmar transacción", "msg": err.Error()})
+		return
+	}
+
 	signed, err := signJWTForUser(u)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "no se pudo firmar el token", "msg": err.Error()})
@@ -502,54 +715,12 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 // =============================
 
 // GET /api/me
+// La identidad (JWT o token personal) la resuelve AuthMiddleware.
 func (h *AuthHandler) Me(c *gin.Context) {
-	// 1) Intentar Bearer token
-	tokenStr := ""
-	authHeader := c.GetHeader("Authorization")
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		tokenStr = strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
-	}
-
-	// 2) Si no hay Bearer, intentar cookie auth_token (set por Next /api/session)
-	if tokenStr == "" {
-		if ck, err := c.Cookie("auth_token"); err == nil {
-			tokenStr = strings.TrimSpace(ck)
-		}
-	}
-
-	if tokenStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "token no proporcionado",
-		})
-		return
-	}
-
-	secret, err := getJWTSecret()
+	claims, err := getClaimsFromHeader(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "configuración de JWT inválida",
-			"msg":   err.Error(),
-		})
-		return
-	}
-
-	token, err := jwt.ParseWithClaims(tokenStr, &PlaneacionClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != jwt.SigningMethodHS256 {
-			return nil, errors.New("algoritmo de firma inválido")
-		}
-		return []byte(secret), nil
-	})
-	if err != nil || !token.Valid {
 		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "token inválido",
-		})
-		return
-	}
-
-	claims, ok := token.Claims.(*PlaneacionClaims)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "claims inválidos",
+			"error": err.Error(),
 		})
 		return
 	}
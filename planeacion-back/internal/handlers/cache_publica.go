@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================
+// Cache en proceso de /api/public/*
+// Guarda respuestas GET 200 por URL (query normalizada) durante
+// PUBLIC_CACHE_TTL_SECONDS (default 60; 0 = sin cache), hasta
+// PUBLIC_CACHE_MAX_ENTRADAS (default 1000). Se vacía al publicar, reabrir
+// o eliminar planeaciones, al editar nombres que aparecen en los documentos
+// (docente, unidad académica, catálogos) y al refrescar las estadísticas
+// (invalidarCachePublica).
+//
+// Validación condicional: ETag / Last-Modified con respuesta 304. Los
+// handlers que conocen updated_at los fijan antes de armar el documento
+// (responderNoModificado); si no, se usa un hash del cuerpo.
+// =============================
+
+const maxCuerpoCache = 2 << 20 // 2 MiB
+
+// Encabezados que se guardan con la respuesta
+var encabezadosCache = []string{"Content-Type", "Content-Disposition", "ETag", "Last-Modified"}
+
+type entradaCache struct {
+	status int
+	header http.Header
+	body   []byte
+	expira time.Time
+}
+
+type cachePublica struct {
+	mu       sync.RWMutex
+	entradas map[string]*entradaCache
+}
+
+var respuestasPublicas = &cachePublica{entradas: map[string]*entradaCache{}}
+
+func envEnteroNoNegativo(nombre string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(nombre))); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+func (cp *cachePublica) obtener(clave string) *entradaCache {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	e := cp.entradas[clave]
+	if e == nil || time.Now().After(e.expira) {
+		return nil
+	}
+	return e
+}
+
+func (cp *cachePublica) guardar(clave string, e *entradaCache, max int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if len(cp.entradas) >= max {
+		ahora := time.Now()
+		for k, v := range cp.entradas {
+			if ahora.After(v.expira) {
+				delete(cp.entradas, k)
+			}
+		}
+		// Sigue lleno: se descarta una entrada cualquiera
+		for k := range cp.entradas {
+			if len(cp.entradas) < max {
+				break
+			}
+			delete(cp.entradas, k)
+		}
+	}
+	cp.entradas[clave] = e
+}
+
+// invalidarCachePublica vacía la cache (listados, documentos y estadísticas).
+func invalidarCachePublica() {
+	respuestasPublicas.mu.Lock()
+	respuestasPublicas.entradas = map[string]*entradaCache{}
+	respuestasPublicas.mu.Unlock()
+}
+
+// etagCoincide evalúa If-None-Match (comparación débil, admite lista y "*").
+func etagCoincide(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	limpiar := func(s string) string { return strings.TrimPrefix(strings.TrimSpace(s), "W/") }
+	for _, candidato := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimSpace(candidato) == "*" || limpiar(candidato) == limpiar(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// noModificado: If-None-Match tiene prioridad sobre If-Modified-Since.
+func noModificado(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagCoincide(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	desde, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(lastModified)
+	return err == nil && !lm.After(desde)
+}
+
+// responderNoModificado fija ETag y Last-Modified a partir de updated_at y
+// responde 304 si el cliente ya tiene esa versión (regresa true).
+func responderNoModificado(c *gin.Context, etag string, updatedAt time.Time) bool {
+	lm := updatedAt.UTC().Format(http.TimeFormat)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lm)
+	if noModificado(c.Request, etag, lm) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// sqlVersionPublica: versión de un documento público (alias p). El documento
+// incluye el nombre del docente y de la unidad académica, así que editarlos
+// también cambia la versión.
+const sqlVersionPublica = `(SELECT GREATEST(p.updated_at, u.updated_at, ua.updated_at)
+	 FROM usuarios u, unidades_academicas ua
+	 WHERE u.id = p.docente_id AND ua.id = p.unidad_academica_id)`
+
+// etagVersion: ETag débil de un recurso con updated_at.
+func etagVersion(recurso string, id int64, updatedAt time.Time) string {
+	return `W/"` + recurso + "-" + strconv.FormatInt(id, 10) + "-" + strconv.FormatInt(updatedAt.UnixMicro(), 10) + `"`
+}
+
+// escritorBuffer retiene la respuesta para guardarla antes de enviarla.
+type escritorBuffer struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *escritorBuffer) WriteHeader(code int)              { w.status = code }
+func (w *escritorBuffer) WriteHeaderNow()                   {}
+func (w *escritorBuffer) Status() int                       { return w.status }
+func (w *escritorBuffer) Written() bool                     { return w.body.Len() > 0 }
+func (w *escritorBuffer) Size() int                         { return w.body.Len() }
+func (w *escritorBuffer) Write(b []byte) (int, error)       { return w.body.Write(b) }
+func (w *escritorBuffer) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+func enviarEntrada(c *gin.Context, e *entradaCache) {
+	for k, v := range e.header {
+		c.Writer.Header()[k] = v
+	}
+	c.Header("Cache-Control", "public, no-cache")
+	if e.status == http.StatusOK && noModificado(c.Request, e.header.Get("ETag"), e.header.Get("Last-Modified")) {
+		c.Writer.WriteHeader(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Writer.WriteHeader(e.status)
+	_, _ = c.Writer.Write(e.body)
+}
+
+// CachePublica es el middleware de cache y validación condicional para /api/public/*.
+func CachePublica() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		ttl := time.Duration(envEnteroNoNegativo("PUBLIC_CACHE_TTL_SECONDS", 60)) * time.Second
+		max := envEnteroNoNegativo("PUBLIC_CACHE_MAX_ENTRADAS", 1000)
+
+		clave := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode()
+		if ttl > 0 {
+			if e := respuestasPublicas.obtener(clave); e != nil {
+				c.Header("X-Cache", "HIT")
+				enviarEntrada(c, e)
+				c.Abort()
+				return
+			}
+		}
+
+		original := c.Writer
+		buf := &escritorBuffer{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buf
+		c.Next()
+		c.Writer = original
+
+		e := &entradaCache{status: buf.status, header: http.Header{}, body: buf.body.Bytes()}
+		if e.status == http.StatusOK && original.Header().Get("ETag") == "" {
+			suma := sha1.Sum(e.body)
+			original.Header().Set("ETag", `W/"`+hex.EncodeToString(suma[:10])+`"`)
+		}
+		for _, k := range encabezadosCache {
+			if v := original.Header().Values(k); len(v) > 0 {
+				e.header[http.CanonicalHeaderKey(k)] = v
+			}
+		}
+
+		if ttl > 0 && max > 0 && e.status == http.StatusOK && len(e.body) <= maxCuerpoCache {
+			e.expira = time.Now().Add(ttl)
+			respuestasPublicas.guardar(clave, e, max)
+			c.Header("X-Cache", "MISS")
+		}
+		enviarEntrada(c, e)
+	}
+}
@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// CalendarioHandler
+// Calendario académico: periodos escolares, días inhábiles y ventanas de
+// evaluación. Lectura pública; altas, cambios y bajas solo admin.
+// =============================
+
+type CalendarioHandler struct {
+	DB *pgxpool.Pool
+}
+
+// RegisterCalendarioRoutes registra la consulta pública del calendario.
+func RegisterCalendarioRoutes(rg *gin.RouterGroup, h *CalendarioHandler) {
+	g := rg.Group("/calendario")
+
+	g.GET("/periodos", h.ListPeriodos)   // GET /api/calendario/periodos?unidad_academica_id=
+	g.GET("/periodos/:id", h.GetPeriodo) // GET /api/calendario/periodos/:id
+}
+
+// RegisterCalendarioAdminRoutes registra la administración del calendario (solo admin).
+func RegisterCalendarioAdminRoutes(rg *gin.RouterGroup, h *CalendarioHandler) {
+	g := rg.Group("/calendario")
+
+	g.POST("/periodos", h.CreatePeriodo)       // POST /api/admin/calendario/periodos
+	g.PUT("/periodos/:id", h.UpdatePeriodo)    // PUT /api/admin/calendario/periodos/:id
+	g.DELETE("/periodos/:id", h.DeletePeriodo) // DELETE /api/admin/calendario/periodos/:id
+
+	g.POST("/periodos/:id/archivar-planeaciones", h.ArchivarPlaneacionesPeriodo) // POST /api/admin/calendario/periodos/:id/archivar-planeaciones
+
+	g.POST("/periodos/:id/dias-inhabiles", h.CreateDiaInhabil) // POST /api/admin/calendario/periodos/:id/dias-inhabiles
+	g.DELETE("/dias-inhabiles/:id", h.DeleteDiaInhabil)        // DELETE /api/admin/calendario/dias-inhabiles/:id
+	g.POST("/periodos/:id/evaluaciones", h.CreateVentana)      // POST /api/admin/calendario/periodos/:id/evaluaciones
+	g.DELETE("/evaluaciones/:id", h.DeleteVentana)             // DELETE /api/admin/calendario/evaluaciones/:id
+}
+
+// =============================
+// DTOs
+// =============================
+
+type periodoRequest struct {
+	UnidadAcademicaID *int    `json:"unidad_academica_id"`
+	Clave             string  `json:"clave"`
+	Nombre            *string `json:"nombre"`
+	FechaInicio       string  `json:"fecha_inicio"`
+	FechaFin          string  `json:"fecha_fin"`
+}
+
+type rangoFechasRequest struct {
+	Nombre      string  `json:"nombre"`      // ventanas de evaluación
+	Descripcion *string `json:"descripcion"` // días inhábiles
+	FechaDel    string  `json:"fecha_del"`
+	FechaAl     string  `json:"fecha_al"` // omitido = mismo día
+}
+
+// Periodo con sus días inhábiles y ventanas de evaluación
+type periodoDetalle struct {
+	models.PeriodoEscolar
+	DiasInhabiles []models.DiaInhabil        `json:"dias_inhabiles"`
+	Evaluaciones  []models.VentanaEvaluacion `json:"evaluaciones"`
+}
+
+var errCalendarioFueraDeFechas = errors.New("hay días inhábiles o evaluaciones fuera de las nuevas fechas")
+var errRangoFueraDePeriodo = errors.New("el rango debe quedar dentro del periodo escolar")
+
+const periodoCols = `id, unidad_academica_id, clave, nombre,
+	to_char(fecha_inicio, 'YYYY-MM-DD'), to_char(fecha_fin, 'YYYY-MM-DD'), created_at, updated_at`
+
+func scanPeriodo(row pgx.Row, p *models.PeriodoEscolar) error {
+	return row.Scan(
+		&p.ID,
+		&p.UnidadAcademicaID,
+		&p.Clave,
+		&p.Nombre,
+		&p.FechaInicio,
+		&p.FechaFin,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+}
+
+func (r *periodoRequest) validar() error {
+	r.Clave = strings.TrimSpace(r.Clave)
+	if r.Clave == "" {
+		return errors.New("clave es obligatoria")
+	}
+	if _, err := dateOrNil(&r.FechaInicio); err != nil || strings.TrimSpace(r.FechaInicio) == "" {
+		return errors.New("fecha_inicio inválida (formato YYYY-MM-DD)")
+	}
+	if _, err := dateOrNil(&r.FechaFin); err != nil || strings.TrimSpace(r.FechaFin) == "" {
+		return errors.New("fecha_fin inválida (formato YYYY-MM-DD)")
+	}
+	r.FechaInicio, r.FechaFin = strings.TrimSpace(r.FechaInicio), strings.TrimSpace(r.FechaFin)
+	if r.FechaFin < r.FechaInicio {
+		return errors.New("fecha_fin no puede ser anterior a fecha_inicio")
+	}
+	return nil
+}
+
+func (r *rangoFechasRequest) validar() error {
+	r.FechaDel, r.FechaAl = strings.TrimSpace(r.FechaDel), strings.TrimSpace(r.FechaAl)
+	if r.FechaAl == "" {
+		r.FechaAl = r.FechaDel
+	}
+	if _, err := dateOrNil(&r.FechaDel); err != nil || r.FechaDel == "" {
+		return errors.New("fecha_del inválida (formato YYYY-MM-DD)")
+	}
+	if _, err := dateOrNil(&r.FechaAl); err != nil {
+		return errors.New("fecha_al inválida (formato YYYY-MM-DD)")
+	}
+	if r.FechaAl < r.FechaDel {
+		return errors.New("fecha_al no puede ser anterior a fecha_del")
+	}
+	return nil
+}
+
+func esViolacionUnica(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func esViolacionFK(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+func paramID64(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// =============================
+// Consulta
+// =============================
+
+// GET /api/calendario/periodos?unidad_academica_id=
+// Con unidad_academica_id: los de esa unidad y los generales.
+func (h *CalendarioHandler) ListPeriodos(c *gin.Context) {
+	query := `SELECT ` + periodoCols + ` FROM public.periodos_escolares`
+	args := []any{}
+	if s := strings.TrimSpace(c.Query("unidad_academica_id")); s != "" {
+		ua, err := strconv.Atoi(s)
+		if err != nil || ua <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_academica_id inválido"})
+			return
+		}
+		query += ` WHERE unidad_academica_id = $1 OR unidad_academica_id IS NULL`
+		args = append(args, ua)
+	}
+	query += ` ORDER BY fecha_inicio DESC, unidad_academica_id NULLS LAST, clave`
+
+	rows, err := h.DB.Query(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar periodos escolares",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.PeriodoEscolar, 0)
+	for rows.Next() {
+		var p models.PeriodoEscolar
+		if err := scanPeriodo(rows, &p); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer periodos escolares",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, p)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar periodos escolares",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// GET /api/calendario/periodos/:id
+func (h *CalendarioHandler) GetPeriodo(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	cal, err := cargarCalendario(c.Request.Context(), h.DB, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "periodo escolar no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar periodo escolar",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, cal.detalle())
+}
+
+// =============================
+// Administración
+// =============================
+
+// POST /api/admin/calendario/periodos
+func (h *CalendarioHandler) CreatePeriodo(c *gin.Context) {
+	var req periodoRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var p models.PeriodoEscolar
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanPeriodo(tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.periodos_escolares (unidad_academica_id, clave, nombre, fecha_inicio, fecha_fin)
+			 VALUES ($1, $2, NULLIF(btrim($3), ''), $4::date, $5::date)
+			 RETURNING `+periodoCols,
+			intOrNil(req.UnidadAcademicaID),
+			req.Clave,
+			strOrNil(req.Nombre),
+			req.FechaInicio,
+			req.FechaFin,
+		), &p)
+		return eventoDeSesion(c, accionCalendarioCrear, "periodo_escolar", p.ID, aMapa(p)), err
+	})
+	if err != nil {
+		h.responderErrorPeriodo(c, "error al crear periodo escolar", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// PUT /api/admin/calendario/periodos/:id
+// Las fechas nuevas deben seguir cubriendo los días inhábiles y evaluaciones registrados.
+func (h *CalendarioHandler) UpdatePeriodo(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	var req periodoRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var (
+		p     models.PeriodoEscolar
+		fuera int
+	)
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		// Con el periodo bloqueado no entran días inhábiles ni evaluaciones
+		// nuevos (crearRango lo lee FOR SHARE) mientras se revisan las fechas
+		var existe int
+		if err := tx.QueryRow(
+			ctx,
+			`SELECT 1 FROM public.periodos_escolares WHERE id = $1 FOR UPDATE`,
+			id,
+		).Scan(&existe); err != nil {
+			return eventoAuditoria{}, err
+		}
+		if err := tx.QueryRow(
+			ctx,
+			`SELECT
+			   (SELECT COUNT(*) FROM public.dias_inhabiles
+			    WHERE periodo_id = $1 AND (fecha_del < $2::date OR fecha_al > $3::date))
+			 + (SELECT COUNT(*) FROM public.ventanas_evaluacion
+			    WHERE periodo_id = $1 AND (fecha_del < $2::date OR fecha_al > $3::date))`,
+			id,
+			req.FechaInicio,
+			req.FechaFin,
+		).Scan(&fuera); err != nil {
+			return eventoAuditoria{}, err
+		}
+		if fuera > 0 {
+			return eventoAuditoria{}, errCalendarioFueraDeFechas
+		}
+
+		err := scanPeriodo(tx.QueryRow(
+			ctx,
+			`UPDATE public.periodos_escolares
+			 SET unidad_academica_id = $2, clave = $3, nombre = NULLIF(btrim($4), ''),
+			     fecha_inicio = $5::date, fecha_fin = $6::date
+			 WHERE id = $1
+			 RETURNING `+periodoCols,
+			id,
+			intOrNil(req.UnidadAcademicaID),
+			req.Clave,
+			strOrNil(req.Nombre),
+			req.FechaInicio,
+			req.FechaFin,
+		), &p)
+		return eventoDeSesion(c, accionCalendarioEditar, "periodo_escolar", p.ID, aMapa(p)), err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "periodo escolar no encontrado"})
+			return
+		}
+		if errors.Is(err, errCalendarioFueraDeFechas) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+				"total": fuera,
+			})
+			return
+		}
+		h.responderErrorPeriodo(c, "error al actualizar periodo escolar", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// DELETE /api/admin/calendario/periodos/:id
+// Borra también sus días inhábiles y evaluaciones.
+func (h *CalendarioHandler) DeletePeriodo(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		var borrado map[string]any
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`DELETE FROM public.periodos_escolares t WHERE t.id = $1 RETURNING to_jsonb(t)`,
+			id,
+		).Scan(&borrado)
+		return eventoDeSesion(c, accionCalendarioEliminar, "periodo_escolar", id, borrado), err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "periodo escolar no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al eliminar periodo escolar",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// POST /api/admin/calendario/periodos/:id/dias-inhabiles
+// Body: { "fecha_del": "2026-03-16", "fecha_al": "2026-03-20", "descripcion": "..." }
+func (h *CalendarioHandler) CreateDiaInhabil(c *gin.Context) {
+	h.crearRango(c, "dia_inhabil", func(tx pgx.Tx, req *rangoFechasRequest, periodoID int64) (any, int64, error) {
+		var d models.DiaInhabil
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.dias_inhabiles (periodo_id, fecha_del, fecha_al, descripcion)
+			 VALUES ($1, $2::date, $3::date, NULLIF(btrim($4), ''))
+			 RETURNING id, periodo_id, to_char(fecha_del, 'YYYY-MM-DD'), to_char(fecha_al, 'YYYY-MM-DD'), descripcion`,
+			periodoID,
+			req.FechaDel,
+			req.FechaAl,
+			strOrNil(req.Descripcion),
+		).Scan(&d.ID, &d.PeriodoID, &d.FechaDel, &d.FechaAl, &d.Descripcion)
+		return d, d.ID, err
+	})
+}
+
+// POST /api/admin/calendario/periodos/:id/evaluaciones
+// Body: { "nombre": "Primer parcial", "fecha_del": "...", "fecha_al": "..." }
+func (h *CalendarioHandler) CreateVentana(c *gin.Context) {
+	h.crearRango(c, "ventana_evaluacion", func(tx pgx.Tx, req *rangoFechasRequest, periodoID int64) (any, int64, error) {
+		nombre := strings.TrimSpace(req.Nombre)
+		if nombre == "" {
+			return nil, 0, errValidacion{errors.New("nombre es obligatorio")}
+		}
+		var v models.VentanaEvaluacion
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.ventanas_evaluacion (periodo_id, nombre, fecha_del, fecha_al)
+			 VALUES ($1, $2, $3::date, $4::date)
+			 RETURNING id, periodo_id, nombre, to_char(fecha_del, 'YYYY-MM-DD'), to_char(fecha_al, 'YYYY-MM-DD')`,
+			periodoID,
+			nombre,
+			req.FechaDel,
+			req.FechaAl,
+		).Scan(&v.ID, &v.PeriodoID, &v.Nombre, &v.FechaDel, &v.FechaAl)
+		return v, v.ID, err
+	})
+}
+
+// crearRango: decodifica y valida el rango contra las fechas del periodo.
+func (h *CalendarioHandler) crearRango(c *gin.Context, entidad string, insertar func(tx pgx.Tx, req *rangoFechasRequest, periodoID int64) (any, int64, error)) {
+	periodoID, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	var req rangoFechasRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var (
+		out         any
+		inicio, fin string
+	)
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		// FOR SHARE: UpdatePeriodo no puede mover las fechas mientras tanto
+		if err := tx.QueryRow(
+			c.Request.Context(),
+			`SELECT to_char(fecha_inicio, 'YYYY-MM-DD'), to_char(fecha_fin, 'YYYY-MM-DD')
+			 FROM public.periodos_escolares WHERE id = $1 FOR SHARE`,
+			periodoID,
+		).Scan(&inicio, &fin); err != nil {
+			return eventoAuditoria{}, err
+		}
+		if req.FechaDel < inicio || req.FechaAl > fin {
+			return eventoAuditoria{}, errRangoFueraDePeriodo
+		}
+
+		creado, id, err := insertar(tx, &req, periodoID)
+		out = creado
+		return eventoDeSesion(c, accionCalendarioCrear, entidad, id, aMapa(creado)), err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "periodo escolar no encontrado"})
+			return
+		}
+		if errors.Is(err, errRangoFueraDePeriodo) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":        err.Error(),
+				"fecha_inicio": inicio,
+				"fecha_fin":    fin,
+			})
+			return
+		}
+		var ve errValidacion
+		if errors.As(err, &ve) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ve.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al guardar en el calendario",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, out)
+}
+
+// DELETE /api/admin/calendario/dias-inhabiles/:id
+func (h *CalendarioHandler) DeleteDiaInhabil(c *gin.Context) {
+	h.borrarDeTabla(c, "dias_inhabiles", "dia_inhabil", "día inhábil no encontrado")
+}
+
+// DELETE /api/admin/calendario/evaluaciones/:id
+func (h *CalendarioHandler) DeleteVentana(c *gin.Context) {
+	h.borrarDeTabla(c, "ventanas_evaluacion", "ventana_evaluacion", "ventana de evaluación no encontrada")
+}
+
+func (h *CalendarioHandler) borrarDeTabla(c *gin.Context, tabla, entidad, noEncontrado string) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		var borrado map[string]any
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`DELETE FROM public.`+tabla+` t WHERE t.id = $1 RETURNING to_jsonb(t)`,
+			id,
+		).Scan(&borrado)
+		return eventoDeSesion(c, accionCalendarioEliminar, entidad, id, borrado), err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": noEncontrado})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al eliminar del calendario",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *CalendarioHandler) responderErrorPeriodo(c *gin.Context, msg string, err error) {
+	switch {
+	case esViolacionUnica(err):
+		c.JSON(http.StatusConflict, gin.H{"error": "ya existe un periodo con esa clave para la unidad académica"})
+	case esViolacionFK(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_academica_id no existe"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+			"msg":   err.Error(),
+		})
+	}
+}
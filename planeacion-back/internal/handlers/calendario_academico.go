@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// Calendario aplicado a una planeación: días de clase (lunes a viernes,
+// dentro del periodo y fuera de días inhábiles) y validación de los periodos
+// de desarrollo de las unidades temáticas.
+// =============================
+
+const formatoFecha = "2006-01-02"
+
+type rangoFechas struct {
+	del, al time.Time
+}
+
+func (r rangoFechas) contiene(t time.Time) bool {
+	return !t.Before(r.del) && !t.After(r.al)
+}
+
+// calendarioPeriodo: periodo escolar cargado con sus días inhábiles y evaluaciones.
+type calendarioPeriodo struct {
+	periodo      models.PeriodoEscolar
+	rango        rangoFechas
+	inhabiles    []models.DiaInhabil
+	evaluaciones []models.VentanaEvaluacion
+	rangosInhab  []rangoFechas
+}
+
+func parseFecha(s string) (time.Time, error) {
+	return time.Parse(formatoFecha, s)
+}
+
+// cargarCalendario lee el periodo (pgx.ErrNoRows si no existe).
+func cargarCalendario(ctx context.Context, db consultaDB, periodoID int64) (*calendarioPeriodo, error) {
+	cal := &calendarioPeriodo{
+		inhabiles:    []models.DiaInhabil{},
+		evaluaciones: []models.VentanaEvaluacion{},
+	}
+	if err := scanPeriodo(db.QueryRow(
+		ctx,
+		`SELECT `+periodoCols+` FROM public.periodos_escolares WHERE id = $1`,
+		periodoID,
+	), &cal.periodo); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if cal.rango.del, err = parseFecha(cal.periodo.FechaInicio); err != nil {
+		return nil, err
+	}
+	if cal.rango.al, err = parseFecha(cal.periodo.FechaFin); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		ctx,
+		`SELECT id, periodo_id, to_char(fecha_del, 'YYYY-MM-DD'), to_char(fecha_al, 'YYYY-MM-DD'), descripcion
+		 FROM public.dias_inhabiles WHERE periodo_id = $1 ORDER BY fecha_del, id`,
+		periodoID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d models.DiaInhabil
+		if err := rows.Scan(&d.ID, &d.PeriodoID, &d.FechaDel, &d.FechaAl, &d.Descripcion); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		del, err1 := parseFecha(d.FechaDel)
+		al, err2 := parseFecha(d.FechaAl)
+		if err := errors.Join(err1, err2); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		cal.inhabiles = append(cal.inhabiles, d)
+		cal.rangosInhab = append(cal.rangosInhab, rangoFechas{del, al})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(
+		ctx,
+		`SELECT id, periodo_id, nombre, to_char(fecha_del, 'YYYY-MM-DD'), to_char(fecha_al, 'YYYY-MM-DD')
+		 FROM public.ventanas_evaluacion WHERE periodo_id = $1 ORDER BY fecha_del, id`,
+		periodoID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.VentanaEvaluacion
+		if err := rows.Scan(&v.ID, &v.PeriodoID, &v.Nombre, &v.FechaDel, &v.FechaAl); err != nil {
+			return nil, err
+		}
+		cal.evaluaciones = append(cal.evaluaciones, v)
+	}
+	return cal, rows.Err()
+}
+
+// calendarioDePlaneacion: periodo cuya clave coincide con planeaciones.periodo
+// (el de la unidad académica tiene prioridad sobre el general). nil si no hay.
+func calendarioDePlaneacion(ctx context.Context, db consultaDB, planeacionID int) (*calendarioPeriodo, error) {
+	var periodoID int64
+	err := db.QueryRow(
+		ctx,
+		`
+SELECT pe.id
+FROM planeaciones p
+JOIN periodos_escolares pe
+  ON lower(btrim(pe.clave)) = lower(btrim(p.periodo))
+ AND (pe.unidad_academica_id = p.unidad_academica_id OR pe.unidad_academica_id IS NULL)
+WHERE p.id = $1
+ORDER BY pe.unidad_academica_id NULLS LAST
+LIMIT 1
+		`,
+		planeacionID,
+	).Scan(&periodoID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cargarCalendario(ctx, db, periodoID)
+}
+
+func (cal *calendarioPeriodo) detalle() periodoDetalle {
+	return periodoDetalle{
+		PeriodoEscolar: cal.periodo,
+		DiasInhabiles:  cal.inhabiles,
+		Evaluaciones:   cal.evaluaciones,
+	}
+}
+
+// esHabil: dentro del periodo y fuera de días inhábiles (cualquier día de la semana).
+func (cal *calendarioPeriodo) esHabil(t time.Time) bool {
+	if !cal.rango.contiene(t) {
+		return false
+	}
+	for _, r := range cal.rangosInhab {
+		if r.contiene(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// esDiaDeClase: lunes a viernes hábil.
+func (cal *calendarioPeriodo) esDiaDeClase(t time.Time) bool {
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return cal.esHabil(t)
+}
+
+// diasDeClase lista los días de clase entre del y al (inclusive).
+func (cal *calendarioPeriodo) diasDeClase(del, al time.Time) []time.Time {
+	var out []time.Time
+	for t := del; !t.After(al); t = t.AddDate(0, 0, 1) {
+		if cal.esDiaDeClase(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Periodo de desarrollo de una unidad temática
+type periodoUnidad struct {
+	Numero int     `json:"numero"`
+	Nombre string  `json:"nombre_unidad_tematica"`
+	Del    *string `json:"periodo_del"`
+	Al     *string `json:"periodo_al"`
+}
+
+func cargarPeriodosUnidades(ctx context.Context, db consultaDB, planeacionID int) ([]periodoUnidad, error) {
+	rows, err := db.Query(
+		ctx,
+		`SELECT numero, nombre_unidad_tematica,
+		        to_char(periodo_del, 'YYYY-MM-DD'), to_char(periodo_al, 'YYYY-MM-DD')
+		 FROM unidades_tematicas WHERE planeacion_id = $1 ORDER BY numero`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []periodoUnidad
+	for rows.Next() {
+		var u periodoUnidad
+		if err := rows.Scan(&u.Numero, &u.Nombre, &u.Del, &u.Al); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// Unidad con periodo inválido
+type unidadFueraDePeriodo struct {
+	Numero int    `json:"numero"`
+	Motivo string `json:"motivo"`
+}
+
+// errUnidadesFueraDePeriodo: periodos de unidad inválidos (400).
+type errUnidadesFueraDePeriodo struct {
+	Periodo  *models.PeriodoEscolar
+	Unidades []unidadFueraDePeriodo
+}
+
+func (e errUnidadesFueraDePeriodo) Error() string {
+	return "Los periodos de desarrollo de las unidades temáticas deben quedar dentro del periodo escolar."
+}
+
+// revisarPeriodoUnidad regresa el motivo por el que el periodo de la unidad es inválido ("" si es válido).
+func revisarPeriodoUnidad(u periodoUnidad, cal *calendarioPeriodo) string {
+	var del, al time.Time
+	var err error
+	if u.Del != nil {
+		if del, err = parseFecha(*u.Del); err != nil {
+			return "periodo_del inválido"
+		}
+	}
+	if u.Al != nil {
+		if al, err = parseFecha(*u.Al); err != nil {
+			return "periodo_al inválido"
+		}
+	}
+	if u.Del != nil && u.Al != nil && al.Before(del) {
+		return "periodo_al es anterior a periodo_del"
+	}
+	if cal == nil {
+		return ""
+	}
+	if (u.Del != nil && !cal.rango.contiene(del)) || (u.Al != nil && !cal.rango.contiene(al)) {
+		return "fuera del periodo " + cal.periodo.FechaInicio + " – " + cal.periodo.FechaFin
+	}
+	return ""
+}
+
+// verificarPeriodosUnidades valida los periodos de desarrollo de las unidades
+// contra el periodo escolar de la planeación (si hay uno registrado).
+// Con numeros solo revisa esas unidades (edición de una unidad).
+func verificarPeriodosUnidades(ctx context.Context, tx pgx.Tx, planeacionID int, numeros ...int) error {
+	cal, err := calendarioDePlaneacion(ctx, tx, planeacionID)
+	if err != nil {
+		return err
+	}
+	unidades, err := cargarPeriodosUnidades(ctx, tx, planeacionID)
+	if err != nil {
+		return err
+	}
+
+	var fuera []unidadFueraDePeriodo
+	for _, u := range unidades {
+		if len(numeros) > 0 && !slices.Contains(numeros, u.Numero) {
+			continue
+		}
+		if motivo := revisarPeriodoUnidad(u, cal); motivo != "" {
+			fuera = append(fuera, unidadFueraDePeriodo{Numero: u.Numero, Motivo: motivo})
+		}
+	}
+	if len(fuera) == 0 {
+		return nil
+	}
+
+	e := errUnidadesFueraDePeriodo{Unidades: fuera}
+	if cal != nil {
+		e.Periodo = &cal.periodo
+	}
+	return e
+}
+
+// =============================
+// GET /api/planeaciones/:id/calendario
+// Periodo escolar de la planeación y días de clase reales por unidad.
+// =============================
+func (h *PlaneacionesHandler) Calendario(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var periodo *string
+	err = h.DB.QueryRow(
+		c,
+		`SELECT periodo FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL`,
+		id,
+		claims.UserID,
+	).Scan(&periodo)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	cal, err := calendarioDePlaneacion(c, h.DB, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	unidades, err := cargarPeriodosUnidades(c, h.DB, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	type unidadCalendario struct {
+		periodoUnidad
+		DiasClase *int   `json:"dias_clase"` // null: sin periodo escolar o sin fechas
+		Problema  string `json:"problema,omitempty"`
+	}
+
+	items := make([]unidadCalendario, 0, len(unidades))
+	total := 0
+	for _, u := range unidades {
+		item := unidadCalendario{periodoUnidad: u, Problema: revisarPeriodoUnidad(u, cal)}
+		if cal != nil && u.Del != nil && u.Al != nil && item.Problema == "" {
+			del, _ := parseFecha(*u.Del)
+			al, _ := parseFecha(*u.Al)
+			n := len(cal.diasDeClase(del, al))
+			item.DiasClase = &n
+			total += n
+		}
+		items = append(items, item)
+	}
+
+	resp := gin.H{
+		"periodo_texto": periodo,
+		"periodo":       nil,
+		"unidades":      items,
+		"dias_clase":    total,
+	}
+	if cal != nil {
+		resp["periodo"] = cal.detalle()
+		resp["dias_clase_periodo"] = len(cal.diasDeClase(cal.rango.del, cal.rango.al))
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Calendario de la planeación en iCalendar (RFC 5545)
+// Unidades (periodo de desarrollo), periodo de registro de evaluación,
+// evaluaciones del calendario académico y sesiones con fecha.
+// Los UID dependen de números (unidad/sesión), no de ids, para que una
+// actualización reemplace los eventos ya importados.
+// =============================
+
+const (
+	uidDominioICS   = "planeacion"
+	zonaICSDefault  = "America/Mexico_City"
+	maxOctetosLinea = 75
+)
+
+// Evento del calendario
+type eventoICS struct {
+	uid         string
+	resumen     string
+	descripcion string
+	inicio, fin time.Time // todoElDia: fin exclusivo
+	todoElDia   bool
+}
+
+var (
+	reFechaISO = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	reFechaMX  = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+)
+
+// zonaICS: zona de las horas de sesión (ICS_TZ; por defecto America/Mexico_City).
+func zonaICS() *time.Location {
+	nombre := strings.TrimSpace(os.Getenv("ICS_TZ"))
+	if nombre == "" {
+		nombre = zonaICSDefault
+	}
+	if loc, err := time.LoadLocation(nombre); err == nil {
+		return loc
+	}
+	return time.FixedZone("CST", -6*3600)
+}
+
+// fechasRegistroEval extrae el rango de periodo_registro_eval (texto libre):
+// acepta YYYY-MM-DD o DD/MM/YYYY; una fecha = un día.
+func fechasRegistroEval(s string) (del, al time.Time, ok bool) {
+	type pos struct {
+		i int
+		t time.Time
+	}
+	var fechas []pos
+	for _, m := range reFechaISO.FindAllStringSubmatchIndex(s, -1) {
+		if t, err := time.Parse("2006-1-2", s[m[2]:m[3]]+"-"+s[m[4]:m[5]]+"-"+s[m[6]:m[7]]); err == nil {
+			fechas = append(fechas, pos{m[0], t})
+		}
+	}
+	for _, m := range reFechaMX.FindAllStringSubmatchIndex(s, -1) {
+		if t, err := time.Parse("2/1/2006", s[m[0]:m[1]]); err == nil {
+			fechas = append(fechas, pos{m[0], t})
+		}
+	}
+	if len(fechas) == 0 {
+		return del, al, false
+	}
+	// En el orden en que aparecen en el texto
+	primero, ultimo := fechas[0], fechas[0]
+	for _, f := range fechas[1:] {
+		if f.i < primero.i {
+			primero = f
+		}
+		if f.i > ultimo.i {
+			ultimo = f
+		}
+	}
+	del, al = primero.t, ultimo.t
+	if al.Before(del) {
+		del, al = al, del
+	}
+	return del, al, true
+}
+
+// eventosPlaneacion arma los eventos de la planeación.
+func eventosPlaneacion(ctx context.Context, db consultaDB, planeacionID int) (nombre string, modificado time.Time, eventos []eventoICS, err error) {
+	var asignatura, grupo *string
+	if err = db.QueryRow(
+		ctx,
+		`SELECT nombre_planeacion, asignatura, grupo, updated_at FROM planeaciones WHERE id = $1`,
+		planeacionID,
+	).Scan(&nombre, &asignatura, &grupo, &modificado); err != nil {
+		return
+	}
+	if a := strings.TrimSpace(deref(asignatura)); a != "" {
+		nombre = a
+	}
+	if g := strings.TrimSpace(deref(grupo)); g != "" {
+		nombre += " (" + g + ")"
+	}
+
+	uid := func(partes ...any) string {
+		s := fmt.Sprintf("planeacion-%d", planeacionID)
+		for _, p := range partes {
+			s += fmt.Sprintf("-%v", p)
+		}
+		return s + "@" + uidDominioICS
+	}
+
+	// Unidades y periodo de registro de evaluación
+	rows, err := db.Query(
+		ctx,
+		`SELECT numero, nombre_unidad_tematica, periodo_del, periodo_al, COALESCE(periodo_registro_eval, '')
+		 FROM unidades_tematicas WHERE planeacion_id = $1 ORDER BY numero`,
+		planeacionID,
+	)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var (
+			numero      int
+			nombreUT    string
+			del, al     *time.Time
+			registroEva string
+		)
+		if err = rows.Scan(&numero, &nombreUT, &del, &al, &registroEva); err != nil {
+			rows.Close()
+			return
+		}
+		titulo := fmt.Sprintf("Unidad %d: %s", numero, nombreUT)
+		if del != nil && al != nil && !al.Before(*del) {
+			eventos = append(eventos, eventoICS{
+				uid:         uid("unidad", numero),
+				resumen:     titulo,
+				descripcion: nombre,
+				inicio:      *del,
+				fin:         al.AddDate(0, 0, 1),
+				todoElDia:   true,
+			})
+		}
+		if ed, ea, ok := fechasRegistroEval(registroEva); ok {
+			eventos = append(eventos, eventoICS{
+				uid:         uid("unidad", numero, "evaluacion"),
+				resumen:     fmt.Sprintf("Registro de evaluación · Unidad %d", numero),
+				descripcion: titulo + "\n" + registroEva,
+				inicio:      ed,
+				fin:         ea.AddDate(0, 0, 1),
+				todoElDia:   true,
+			})
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	// Sesiones con fecha
+	loc := zonaICS()
+	rows, err = db.Query(
+		ctx,
+		`
+SELECT ut.numero, sd.numero_sesion, COALESCE(sd.temas_subtemas, ''), sd.fecha,
+       to_char(sd.hora_inicio, 'HH24:MI'), to_char(sd.hora_fin, 'HH24:MI'), sd.espacio
+FROM sesiones_didacticas sd
+JOIN unidades_tematicas ut ON ut.id = sd.unidad_tematica_id
+WHERE ut.planeacion_id = $1 AND sd.fecha IS NOT NULL
+ORDER BY sd.fecha, sd.hora_inicio NULLS FIRST, ut.numero, sd.numero_sesion
+		`,
+		planeacionID,
+	)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var (
+			unidad, sesion   int
+			temas            string
+			fecha            time.Time
+			horaIni, horaFin *string
+			espacio          *string
+		)
+		if err = rows.Scan(&unidad, &sesion, &temas, &fecha, &horaIni, &horaFin, &espacio); err != nil {
+			rows.Close()
+			return
+		}
+		ev := eventoICS{
+			uid:         uid("unidad", unidad, "sesion", sesion),
+			resumen:     fmt.Sprintf("%s · U%d S%d", nombre, unidad, sesion),
+			descripcion: strings.TrimSpace(temas),
+			inicio:      fecha,
+			fin:         fecha.AddDate(0, 0, 1),
+			todoElDia:   true,
+		}
+		if e := strings.TrimSpace(deref(espacio)); e != "" {
+			ev.descripcion = strings.TrimSpace("Espacio: " + e + "\n" + ev.descripcion)
+		}
+		if horaIni != nil && horaFin != nil {
+			ini, err1 := time.ParseInLocation("2006-01-02 15:04", fecha.Format(formatoFecha)+" "+*horaIni, loc)
+			fin, err2 := time.ParseInLocation("2006-01-02 15:04", fecha.Format(formatoFecha)+" "+*horaFin, loc)
+			if errors.Join(err1, err2) == nil && fin.After(ini) {
+				ev.inicio, ev.fin, ev.todoElDia = ini, fin, false
+			}
+		}
+		eventos = append(eventos, ev)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	// Evaluaciones del calendario académico
+	cal, err := calendarioDePlaneacion(ctx, db, planeacionID)
+	if err != nil || cal == nil {
+		return
+	}
+
+	// La versión del .ics también cambia con el calendario del periodo
+	// (calendario_modificado_at cubre las bajas, ver 019)
+	var calModificado time.Time
+	if err = db.QueryRow(
+		ctx,
+		`SELECT GREATEST(
+		   pe.updated_at,
+		   pe.calendario_modificado_at,
+		   (SELECT max(v.created_at) FROM public.ventanas_evaluacion v WHERE v.periodo_id = pe.id),
+		   (SELECT max(d.created_at) FROM public.dias_inhabiles d WHERE d.periodo_id = pe.id)
+		 )
+		 FROM public.periodos_escolares pe WHERE pe.id = $1`,
+		cal.periodo.ID,
+	).Scan(&calModificado); err != nil {
+		return
+	}
+	if calModificado.After(modificado) {
+		modificado = calModificado
+	}
+
+	for _, v := range cal.evaluaciones {
+		del, err1 := parseFecha(v.FechaDel)
+		al, err2 := parseFecha(v.FechaAl)
+		if errors.Join(err1, err2) != nil {
+			continue
+		}
+		eventos = append(eventos, eventoICS{
+			uid:         fmt.Sprintf("periodo-%d-evaluacion-%d@%s", v.PeriodoID, v.ID, uidDominioICS),
+			resumen:     v.Nombre,
+			descripcion: "Periodo " + cal.periodo.Clave,
+			inicio:      del,
+			fin:         al.AddDate(0, 0, 1),
+			todoElDia:   true,
+		})
+	}
+	return
+}
+
+// generarICS serializa el VCALENDAR (líneas CRLF plegadas a 75 octetos).
+func generarICS(nombre string, modificado time.Time, eventos []eventoICS) []byte {
+	var b bytes.Buffer
+	linea := func(s string) {
+		b.WriteString(plegarLineaICS(s))
+		b.WriteString("\r\n")
+	}
+	stamp := modificado.UTC().Format("20060102T150405Z")
+
+	linea("BEGIN:VCALENDAR")
+	linea("VERSION:2.0")
+	linea("PRODID:-//planeacion//Planeación didáctica//ES")
+	linea("CALSCALE:GREGORIAN")
+	linea("METHOD:PUBLISH")
+	linea("X-WR-CALNAME:" + escaparICS(nombre))
+	for _, ev := range eventos {
+		linea("BEGIN:VEVENT")
+		linea("UID:" + ev.uid)
+		linea("DTSTAMP:" + stamp)
+		linea("LAST-MODIFIED:" + stamp)
+		if ev.todoElDia {
+			linea("DTSTART;VALUE=DATE:" + ev.inicio.Format("20060102"))
+			linea("DTEND;VALUE=DATE:" + ev.fin.Format("20060102"))
+			linea("TRANSP:TRANSPARENT")
+		} else {
+			linea("DTSTART:" + ev.inicio.UTC().Format("20060102T150405Z"))
+			linea("DTEND:" + ev.fin.UTC().Format("20060102T150405Z"))
+		}
+		linea("SUMMARY:" + escaparICS(ev.resumen))
+		if ev.descripcion != "" {
+			linea("DESCRIPTION:" + escaparICS(ev.descripcion))
+		}
+		linea("END:VEVENT")
+	}
+	linea("END:VCALENDAR")
+	return b.Bytes()
+}
+
+// escaparICS escapa TEXT (RFC 5545 §3.3.11).
+func escaparICS(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
+
+// plegarLineaICS parte líneas de más de 75 octetos sin cortar caracteres UTF-8.
+func plegarLineaICS(s string) string {
+	if len(s) <= maxOctetosLinea {
+		return s
+	}
+	var b strings.Builder
+	limite := maxOctetosLinea
+	n := 0
+	for _, r := range s {
+		tam := utf8.RuneLen(r)
+		if n+tam > limite {
+			b.WriteString("\r\n ")
+			n = 0
+			limite = maxOctetosLinea - 1 // el espacio inicial cuenta
+		}
+		b.WriteRune(r)
+		n += tam
+	}
+	return b.String()
+}
+
+func (h *PlaneacionesHandler) responderICS(c *gin.Context, db consultaDB, planeacionID int, archivo string) {
+	nombre, modificado, eventos, err := eventosPlaneacion(c, db, planeacionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if responderNoModificado(c, etagVersion("ics", int64(planeacionID), modificado), modificado) {
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+archivo+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", generarICS(nombre, modificado, eventos))
+}
+
+// =============================
+// GET /api/planeaciones/:id/calendar.ics
+// Privado (dueño). Para suscribirse desde una app de calendario se puede
+// usar un token personal en ?token=pat_...
+// =============================
+func (h *PlaneacionesHandler) CalendarICS(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var existe bool
+	if err := h.DB.QueryRow(
+		c,
+		`SELECT EXISTS (SELECT 1 FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL)`,
+		id,
+		claims.UserID,
+	).Scan(&existe); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if !existe {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+		return
+	}
+
+	h.responderICS(c, h.DB, id, fmt.Sprintf("planeacion-%d", id))
+}
+
+// =============================
+// GET /api/public/planeaciones/slug/:slug/calendar.ics
+// Solo planeaciones publicadas (finalizada, o archivada si se publicó).
+// =============================
+func (h *PublicPlaneacionesHandler) CalendarICSBySlug(c *gin.Context) {
+	slug := strings.TrimSpace(c.Param("slug"))
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug requerido"})
+		return
+	}
+
+	var (
+		id        int
+		eliminada bool
+	)
+	err := h.DB.QueryRow(
+		c,
+		`SELECT p.id, p.deleted_at IS NOT NULL FROM planeaciones p
+		 WHERE p.slug = $1 AND (`+sqlPublicada+` OR `+sqlEliminadaPublicada+`) LIMIT 1`,
+		slug,
+	).Scan(&id, &eliminada)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Slug anterior de una planeación renombrada: 301 al actual
+			if ok, err := h.redirigirSlugAnterior(c, slug); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+				return
+			} else if ok {
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada (o no publicada)"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if eliminada {
+		responderPlaneacionEliminada(c)
+		return
+	}
+
+	(&PlaneacionesHandler{DB: h.DB}).responderICS(c, h.DB, id, slug)
+}
@@ -0,0 +1,825 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// Catálogos académicos (UnidadesHandler)
+// unidad académica → programas académicos → academias → unidades de aprendizaje.
+// Lectura pública; altas, cambios y bajas solo admin.
+// =============================
+
+// RegisterUnidadesAdminRoutes registra la administración de unidades académicas y sus catálogos (solo admin).
+func RegisterUnidadesAdminRoutes(rg *gin.RouterGroup, h *UnidadesHandler) {
+	// Unidades académicas (ver unidades_admin.go)
+	rg.GET("/unidades", h.ListUnidadesAdmin)                   // GET /api/admin/unidades?incluir_inactivas=1
+	rg.POST("/unidades", h.CreateUnidad)                       // POST /api/admin/unidades
+	rg.PUT("/unidades/:id", h.UpdateUnidad)                    // PUT /api/admin/unidades/:id
+	rg.DELETE("/unidades/:id", h.DesactivarUnidad)             // DELETE /api/admin/unidades/:id (baja lógica)
+	rg.GET("/unidades/:id/estadisticas", h.EstadisticasUnidad) // GET /api/admin/unidades/:id/estadisticas
+
+	rg.POST("/unidades/:id/programas", h.CreatePrograma) // POST /api/admin/unidades/:id/programas
+	rg.PUT("/programas/:id", h.UpdatePrograma)           // PUT /api/admin/programas/:id
+	rg.DELETE("/programas/:id", h.DeletePrograma)        // DELETE /api/admin/programas/:id
+
+	rg.POST("/programas/:id/academias", h.CreateAcademia) // POST /api/admin/programas/:id/academias
+	rg.PUT("/academias/:id", h.UpdateAcademia)            // PUT /api/admin/academias/:id
+	rg.DELETE("/academias/:id", h.DeleteAcademia)         // DELETE /api/admin/academias/:id
+
+	rg.POST("/academias/:id/unidades-aprendizaje", h.CreateUnidadAprendizaje) // POST /api/admin/academias/:id/unidades-aprendizaje
+	rg.PUT("/unidades-aprendizaje/:id", h.UpdateUnidadAprendizaje)            // PUT /api/admin/unidades-aprendizaje/:id
+	rg.DELETE("/unidades-aprendizaje/:id", h.DeleteUnidadAprendizaje)         // DELETE /api/admin/unidades-aprendizaje/:id
+
+	rg.POST("/catalogos/vincular", h.VincularCatalogos) // POST /api/admin/catalogos/vincular
+}
+
+// =============================
+// DTOs
+// =============================
+
+type programaRequest struct {
+	Clave  *string `json:"clave"`
+	Nombre string  `json:"nombre"`
+}
+
+type academiaRequest struct {
+	Nombre string `json:"nombre"`
+}
+
+type unidadAprendizajeRequest struct {
+	Clave         *string  `json:"clave"`
+	Nombre        string   `json:"nombre"`
+	Semestre      *int     `json:"semestre"`
+	CreditosTepic *float64 `json:"creditos_tepic"`
+	CreditosSatca *float64 `json:"creditos_satca"`
+	HorasTeoria   *float64 `json:"horas_teoria"`
+	HorasPractica *float64 `json:"horas_practica"`
+	HorasTotal    *float64 `json:"horas_total"`
+}
+
+func validarNombreCatalogo(nombre *string) error {
+	*nombre = strings.Join(strings.Fields(*nombre), " ")
+	if *nombre == "" {
+		return errors.New("nombre es obligatorio")
+	}
+	if len(*nombre) > 255 {
+		return errors.New("nombre excede 255 caracteres")
+	}
+	return nil
+}
+
+func (r *unidadAprendizajeRequest) validar() error {
+	if err := validarNombreCatalogo(&r.Nombre); err != nil {
+		return err
+	}
+	if r.Semestre != nil && (*r.Semestre < 1 || *r.Semestre > 20) {
+		return errors.New("semestre debe estar entre 1 y 20")
+	}
+	for _, v := range []*float64{r.CreditosTepic, r.CreditosSatca, r.HorasTeoria, r.HorasPractica, r.HorasTotal} {
+		if v != nil && (*v < 0 || *v >= 1000) {
+			return errors.New("créditos y horas deben estar entre 0 y 999.99")
+		}
+	}
+	return nil
+}
+
+const programaCols = `id, unidad_academica_id, clave, nombre, created_at, updated_at`
+
+func scanPrograma(row pgx.Row, p *models.ProgramaAcademico) error {
+	return row.Scan(&p.ID, &p.UnidadAcademicaID, &p.Clave, &p.Nombre, &p.CreatedAt, &p.UpdatedAt)
+}
+
+const academiaCols = `id, programa_id, nombre, created_at, updated_at`
+
+func scanAcademia(row pgx.Row, a *models.Academia) error {
+	return row.Scan(&a.ID, &a.ProgramaID, &a.Nombre, &a.CreatedAt, &a.UpdatedAt)
+}
+
+var unidadAprendizajeCols = columnasUnidadAprendizaje("")
+
+// columnasUnidadAprendizaje: columnas para scanUnidadAprendizaje (prefijo = alias, p. ej. "ua.").
+func columnasUnidadAprendizaje(p string) string {
+	return p + "id, " + p + "academia_id, " + p + "clave, " + p + "nombre, " + p + "semestre, " +
+		p + "creditos_tepic::float8, " + p + "creditos_satca::float8, " +
+		p + "horas_teoria::float8, " + p + "horas_practica::float8, " + p + "horas_total::float8, " +
+		p + "created_at, " + p + "updated_at"
+}
+
+func scanUnidadAprendizaje(row pgx.Row, u *models.UnidadAprendizaje) error {
+	return row.Scan(
+		&u.ID,
+		&u.AcademiaID,
+		&u.Clave,
+		&u.Nombre,
+		&u.Semestre,
+		&u.CreditosTepic,
+		&u.CreditosSatca,
+		&u.HorasTeoria,
+		&u.HorasPractica,
+		&u.HorasTotal,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	)
+}
+
+// listarCatalogo ejecuta la consulta y responde { items, total }.
+func listarCatalogo[T any](c *gin.Context, h *UnidadesHandler, scan func(pgx.Row, *T) error, query string, args ...any) {
+	rows, err := h.DB.Query(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar catálogo",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]T, 0)
+	for rows.Next() {
+		var it T
+		if err := scan(rows, &it); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer catálogo",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar catálogo",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// responderErrorCatalogo traduce violaciones de unicidad/FK.
+func responderErrorCatalogo(c *gin.Context, msg, padre string, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "registro de catálogo no encontrado"})
+	case esViolacionUnica(err):
+		c.JSON(http.StatusConflict, gin.H{"error": "ya existe un registro con ese nombre en " + padre})
+	case esViolacionFK(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": padre + " no encontrado(a)"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+			"msg":   err.Error(),
+		})
+	}
+}
+
+// =============================
+// Consulta
+// =============================
+
+// GET /api/unidades/:id/programas
+func (h *UnidadesHandler) ListProgramas(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	listarCatalogo(c, h, scanPrograma,
+		`SELECT `+programaCols+` FROM public.programas_academicos WHERE unidad_academica_id = $1 ORDER BY nombre`,
+		id,
+	)
+}
+
+// GET /api/programas/:id/academias
+func (h *UnidadesHandler) ListAcademias(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	listarCatalogo(c, h, scanAcademia,
+		`SELECT `+academiaCols+` FROM public.academias WHERE programa_id = $1 ORDER BY nombre`,
+		id,
+	)
+}
+
+// GET /api/academias/:id/unidades-aprendizaje
+func (h *UnidadesHandler) ListUnidadesAprendizaje(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	listarCatalogo(c, h, scanUnidadAprendizaje,
+		`SELECT `+unidadAprendizajeCols+` FROM public.unidades_aprendizaje
+		 WHERE academia_id = $1 ORDER BY semestre NULLS LAST, nombre`,
+		id,
+	)
+}
+
+// GET /api/unidades/:id/unidades-aprendizaje?q=&programa_id=&academia_id=&semestre=
+// Búsqueda en el catálogo de la unidad académica (q por similitud, tolera acentos y erratas).
+func (h *UnidadesHandler) BuscarUnidadesAprendizaje(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	where := []string{"pa.unidad_academica_id = $1"}
+	args := []any{id}
+	orden := "ua.semestre NULLS LAST, ua.nombre"
+
+	for _, f := range []struct{ param, col string }{
+		{"programa_id", "a.programa_id"},
+		{"academia_id", "ua.academia_id"},
+		{"semestre", "ua.semestre"},
+	} {
+		s := strings.TrimSpace(c.Query(f.param))
+		if s == "" {
+			continue
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f.param + " inválido"})
+			return
+		}
+		args = append(args, v)
+		where = append(where, f.col+" = $"+strconv.Itoa(len(args)))
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		args = append(args, q)
+		ph := "$" + strconv.Itoa(len(args))
+		where = append(where, "(public.normalizar_catalogo(ua.nombre) % public.normalizar_catalogo("+ph+")"+
+			" OR public.normalizar_catalogo(ua.nombre) LIKE '%' || public.normalizar_catalogo("+ph+") || '%')")
+		orden = "public.similarity(public.normalizar_catalogo(ua.nombre), public.normalizar_catalogo(" + ph + ")) DESC, ua.nombre"
+	}
+
+	listarCatalogo(c, h, scanUnidadAprendizaje,
+		`SELECT `+columnasUnidadAprendizaje("ua.")+`
+		 FROM public.unidades_aprendizaje ua
+		 JOIN public.academias a ON a.id = ua.academia_id
+		 JOIN public.programas_academicos pa ON pa.id = a.programa_id
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY `+orden+`
+		 LIMIT 50`,
+		args...,
+	)
+}
+
+// =============================
+// Administración: programas
+// =============================
+
+// POST /api/admin/unidades/:id/programas
+// Body: { "nombre": "Ingeniería en Sistemas Computacionales", "clave": "ISC" }
+func (h *UnidadesHandler) CreatePrograma(c *gin.Context) {
+	uaID, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	var req programaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := validarNombreCatalogo(&req.Nombre); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var p models.ProgramaAcademico
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanPrograma(tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.programas_academicos (unidad_academica_id, clave, nombre)
+			 VALUES ($1, NULLIF(btrim($2), ''), $3)
+			 RETURNING `+programaCols,
+			uaID,
+			strOrNil(req.Clave),
+			req.Nombre,
+		), &p)
+		return eventoDeSesion(c, accionCatalogoCrear, "programa", p.ID, gin.H{"nombre": p.Nombre, "unidad_academica_id": uaID}), err
+	})
+	if err != nil {
+		responderErrorCatalogo(c, "error al crear programa académico", "la unidad académica", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// PUT /api/admin/programas/:id
+func (h *UnidadesHandler) UpdatePrograma(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	var req programaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := validarNombreCatalogo(&req.Nombre); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.actualizarCatalogo(c, "programa_id", "programa_academico", func(tx pgx.Tx) (any, string, error) {
+		var p models.ProgramaAcademico
+		err := scanPrograma(tx.QueryRow(
+			c.Request.Context(),
+			`UPDATE public.programas_academicos SET clave = NULLIF(btrim($2), ''), nombre = $3
+			 WHERE id = $1
+			 RETURNING `+programaCols,
+			id,
+			strOrNil(req.Clave),
+			req.Nombre,
+		), &p)
+		return p, p.Nombre, err
+	}, id, "la unidad académica")
+}
+
+// DELETE /api/admin/programas/:id
+// Borra sus academias y unidades de aprendizaje; las planeaciones quedan sin referencia.
+func (h *UnidadesHandler) DeletePrograma(c *gin.Context) {
+	h.borrarCatalogo(c, "programas_academicos", "programa", "programa académico no encontrado")
+}
+
+// =============================
+// Administración: academias
+// =============================
+
+// POST /api/admin/programas/:id/academias
+func (h *UnidadesHandler) CreateAcademia(c *gin.Context) {
+	programaID, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	var req academiaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := validarNombreCatalogo(&req.Nombre); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var a models.Academia
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanAcademia(tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.academias (programa_id, nombre) VALUES ($1, $2) RETURNING `+academiaCols,
+			programaID,
+			req.Nombre,
+		), &a)
+		return eventoDeSesion(c, accionCatalogoCrear, "academia", a.ID, gin.H{"nombre": a.Nombre, "programa_id": programaID}), err
+	})
+	if err != nil {
+		responderErrorCatalogo(c, "error al crear academia", "el programa académico", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// PUT /api/admin/academias/:id
+func (h *UnidadesHandler) UpdateAcademia(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	var req academiaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := validarNombreCatalogo(&req.Nombre); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.actualizarCatalogo(c, "academia_id", "academia", func(tx pgx.Tx) (any, string, error) {
+		var a models.Academia
+		err := scanAcademia(tx.QueryRow(
+			c.Request.Context(),
+			`UPDATE public.academias SET nombre = $2 WHERE id = $1 RETURNING `+academiaCols,
+			id,
+			req.Nombre,
+		), &a)
+		return a, a.Nombre, err
+	}, id, "el programa académico")
+}
+
+// DELETE /api/admin/academias/:id
+func (h *UnidadesHandler) DeleteAcademia(c *gin.Context) {
+	h.borrarCatalogo(c, "academias", "academia", "academia no encontrada")
+}
+
+// =============================
+// Administración: unidades de aprendizaje
+// =============================
+
+// POST /api/admin/academias/:id/unidades-aprendizaje
+// Body: { "nombre": "Cálculo", "clave": "C101", "semestre": 1, "creditos_tepic": 7.5, "horas_total": 81, ... }
+func (h *UnidadesHandler) CreateUnidadAprendizaje(c *gin.Context) {
+	academiaID, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	var req unidadAprendizajeRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var u models.UnidadAprendizaje
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanUnidadAprendizaje(tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.unidades_aprendizaje
+			   (academia_id, clave, nombre, semestre, creditos_tepic, creditos_satca, horas_teoria, horas_practica, horas_total)
+			 VALUES ($1, NULLIF(btrim($2), ''), $3, $4, $5, $6, $7, $8, $9)
+			 RETURNING `+unidadAprendizajeCols,
+			academiaID,
+			strOrNil(req.Clave),
+			req.Nombre,
+			intOrNil(req.Semestre),
+			floatOrNil(req.CreditosTepic),
+			floatOrNil(req.CreditosSatca),
+			floatOrNil(req.HorasTeoria),
+			floatOrNil(req.HorasPractica),
+			floatOrNil(req.HorasTotal),
+		), &u)
+		return eventoDeSesion(c, accionCatalogoCrear, "unidad_aprendizaje", u.ID, gin.H{"nombre": u.Nombre, "academia_id": academiaID}), err
+	})
+	if err != nil {
+		responderErrorCatalogo(c, "error al crear unidad de aprendizaje", "la academia", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, u)
+}
+
+// PUT /api/admin/unidades-aprendizaje/:id
+func (h *UnidadesHandler) UpdateUnidadAprendizaje(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+	var req unidadAprendizajeRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.actualizarCatalogo(c, "unidad_aprendizaje_id", "", func(tx pgx.Tx) (any, string, error) {
+		var u models.UnidadAprendizaje
+		err := scanUnidadAprendizaje(tx.QueryRow(
+			c.Request.Context(),
+			`UPDATE public.unidades_aprendizaje
+			 SET clave = NULLIF(btrim($2), ''), nombre = $3, semestre = $4,
+			     creditos_tepic = $5, creditos_satca = $6,
+			     horas_teoria = $7, horas_practica = $8, horas_total = $9
+			 WHERE id = $1
+			 RETURNING `+unidadAprendizajeCols,
+			id,
+			strOrNil(req.Clave),
+			req.Nombre,
+			intOrNil(req.Semestre),
+			floatOrNil(req.CreditosTepic),
+			floatOrNil(req.CreditosSatca),
+			floatOrNil(req.HorasTeoria),
+			floatOrNil(req.HorasPractica),
+			floatOrNil(req.HorasTotal),
+		), &u)
+		return u, u.Nombre, err
+	}, id, "la academia")
+}
+
+// DELETE /api/admin/unidades-aprendizaje/:id
+func (h *UnidadesHandler) DeleteUnidadAprendizaje(c *gin.Context) {
+	h.borrarCatalogo(c, "unidades_aprendizaje", "unidad_aprendizaje", "unidad de aprendizaje no encontrada")
+}
+
+// actualizarCatalogo guarda el cambio y copia el nombre nuevo al texto libre
+// de las planeaciones ligadas (columna de planeacion_datos_generales, o
+// planeaciones.asignatura si colTexto es ""). La auditoría lista las
+// planeaciones que cambiaron.
+func (h *UnidadesHandler) actualizarCatalogo(c *gin.Context, colFK, colTexto string, guardar func(pgx.Tx) (any, string, error), id int64, padre string) {
+	ctx := c.Request.Context()
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	out, nombre, err := guardar(tx)
+	if err != nil {
+		responderErrorCatalogo(c, "error al actualizar catálogo", padre, err)
+		return
+	}
+
+	afectadas, err := copiarNombreCatalogo(ctx, tx, colFK, colTexto, id, nombre)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al actualizar planeaciones ligadas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	cambios := aMapa(out)
+	if len(afectadas) > 0 {
+		cambios["planeaciones"] = afectadas
+	}
+	if err := registrarAuditoria(c, tx, eventoDeSesion(c, accionCatalogoEditar, strings.TrimSuffix(colFK, "_id"), id, cambios)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	invalidarCachePublica() // el nombre se copió a planeaciones publicadas
+
+	c.JSON(http.StatusOK, out)
+}
+
+// copiarNombreCatalogo regresa los ids de las planeaciones que cambiaron. A
+// esas se les mueve updated_at (ETag del documento público) y, si están
+// publicadas, se recalcula search_tsv, que de otro modo solo se arma al publicar.
+func copiarNombreCatalogo(ctx context.Context, tx pgx.Tx, colFK, colTexto string, id int64, nombre string) ([]int64, error) {
+	var rows pgx.Rows
+	var err error
+	if colTexto == "" {
+		rows, err = tx.Query(ctx,
+			`UPDATE planeaciones SET asignatura = $2, updated_at = now()
+			 WHERE `+colFK+` = $1 AND asignatura IS DISTINCT FROM $2
+			 RETURNING id`,
+			id, nombre)
+	} else {
+		rows, err = tx.Query(ctx,
+			`UPDATE planeacion_datos_generales dg SET `+colTexto+` = $2
+			 FROM planeaciones p
+			 WHERE p.id = dg.planeacion_id AND p.`+colFK+` = $1 AND dg.`+colTexto+` IS DISTINCT FROM $2
+			 RETURNING p.id`,
+			id, nombre)
+	}
+	if err != nil {
+		return nil, err
+	}
+	afectadas, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil || len(afectadas) == 0 {
+		return afectadas, err
+	}
+
+	if colTexto != "" {
+		if _, err := tx.Exec(ctx, `UPDATE planeaciones SET updated_at = now() WHERE id = ANY($1)`, afectadas); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`SELECT public.planeacion_refresh_search(p.id) FROM planeaciones p
+		 WHERE p.id = ANY($1) AND `+sqlPublicada,
+		afectadas); err != nil {
+		return nil, err
+	}
+	return afectadas, nil
+}
+
+func (h *UnidadesHandler) borrarCatalogo(c *gin.Context, tabla, entidad, noEncontrado string) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		var borrado map[string]any
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`DELETE FROM public.`+tabla+` t WHERE t.id = $1 RETURNING to_jsonb(t)`,
+			id,
+		).Scan(&borrado)
+		return eventoDeSesion(c, accionCatalogoEliminar, entidad, id, borrado), err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": noEncontrado})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al eliminar del catálogo",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// POST /api/admin/catalogos/vincular
+// Body opcional: { "umbral": 0.6 }
+// Liga las planeaciones sin referencia al catálogo por similitud de su texto libre
+// (útil tras dar de alta entradas nuevas).
+func (h *UnidadesHandler) VincularCatalogos(c *gin.Context) {
+	var req struct {
+		Umbral *float64 `json:"umbral"`
+	}
+	if c.Request.ContentLength != 0 {
+		if _, ok := decodeJSONBody(c, &req); !ok {
+			return
+		}
+	}
+	umbral := 0.6
+	if req.Umbral != nil {
+		if *req.Umbral < 0.3 || *req.Umbral > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "umbral debe estar entre 0.3 y 1"})
+			return
+		}
+		umbral = *req.Umbral
+	}
+
+	ctx := c.Request.Context()
+	var vinculadas int
+	if err := h.DB.QueryRow(ctx, `SELECT public.vincular_catalogos(NULL, $1::real)`, umbral).Scan(&vinculadas); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al vincular catálogos",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	var sinPrograma, sinAcademia, sinUnidad int
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT
+		   COUNT(*) FILTER (WHERE p.programa_id IS NULL AND btrim(COALESCE(dg.programa_academico, '')) <> ''),
+		   COUNT(*) FILTER (WHERE p.academia_id IS NULL AND btrim(COALESCE(dg.academia, '')) <> ''),
+		   COUNT(*) FILTER (WHERE p.unidad_aprendizaje_id IS NULL AND btrim(COALESCE(p.asignatura, '')) <> '')
+		 FROM planeaciones p
+		 LEFT JOIN planeacion_datos_generales dg ON dg.planeacion_id = p.id`,
+	).Scan(&sinPrograma, &sinAcademia, &sinUnidad); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al contar pendientes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vinculadas": vinculadas,
+		"umbral":     umbral,
+		"pendientes": gin.H{
+			"programa":           sinPrograma,
+			"academia":           sinAcademia,
+			"unidad_aprendizaje": sinUnidad,
+		},
+	})
+}
+
+// =============================
+// Referencias de la planeación al catálogo
+// =============================
+
+// ligarCatalogos actualiza programa_id / academia_id / unidad_aprendizaje_id.
+// Un id explícito se valida contra la unidad académica y la jerarquía de la
+// planeación, y su nombre reemplaza el texto libre; si solo llega texto, la
+// referencia se recalcula por similitud (public.vincular_catalogos). En un
+// PATCH, enviar el id o el texto como null también suelta la referencia.
+func ligarCatalogos(ctx context.Context, tx pgx.Tx, planeacionID int, p *DatosGeneralesPayload, enviados camposEnviados) error {
+	niveles := []struct {
+		campo    string
+		id       *int64
+		texto    *string
+		clave    string // clave JSON del texto libre
+		colTexto string // columna de planeacion_datos_generales ("" = planeaciones.asignatura)
+		nombre   string // $1 = id, $2 = planeación
+	}{
+		{
+			campo: "programa_id", id: p.ProgramaID, texto: p.ProgramaAcademico, clave: "programa_academico", colTexto: "programa_academico",
+			nombre: `SELECT pa.nombre FROM public.programas_academicos pa
+			         JOIN planeaciones p ON p.unidad_academica_id = pa.unidad_academica_id
+			         WHERE pa.id = $1 AND p.id = $2`,
+		},
+		{
+			campo: "academia_id", id: p.AcademiaID, texto: p.Academia, clave: "academia", colTexto: "academia",
+			nombre: `SELECT a.nombre FROM public.academias a
+			         JOIN public.programas_academicos pa ON pa.id = a.programa_id
+			         JOIN planeaciones p ON p.unidad_academica_id = pa.unidad_academica_id
+			         WHERE a.id = $1 AND p.id = $2 AND (p.programa_id IS NULL OR a.programa_id = p.programa_id)`,
+		},
+		{
+			campo: "unidad_aprendizaje_id", id: p.UnidadAprendizajeID, texto: p.UnidadAprendizajeNombre, clave: "unidad_aprendizaje_nombre",
+			nombre: `SELECT ua.nombre FROM public.unidades_aprendizaje ua
+			         JOIN public.academias a ON a.id = ua.academia_id
+			         JOIN public.programas_academicos pa ON pa.id = a.programa_id
+			         JOIN planeaciones p ON p.unidad_academica_id = pa.unidad_academica_id
+			         WHERE ua.id = $1 AND p.id = $2
+			           AND (p.academia_id IS NULL OR ua.academia_id = p.academia_id)
+			           AND (p.programa_id IS NULL OR a.programa_id = p.programa_id)`,
+		},
+	}
+
+	for _, n := range niveles {
+		switch {
+		case n.id != nil:
+			var nombre string
+			err := tx.QueryRow(ctx, n.nombre, *n.id, planeacionID).Scan(&nombre)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errValidacion{errors.New(n.campo + " no existe en el catálogo de la unidad académica de la planeación (o no corresponde al programa/academia elegidos)")}
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `UPDATE planeaciones SET `+n.campo+` = $2 WHERE id = $1`, planeacionID, *n.id); err != nil {
+				return err
+			}
+			if n.colTexto == "" {
+				_, err = tx.Exec(ctx, `UPDATE planeaciones SET asignatura = $2 WHERE id = $1`, planeacionID, nombre)
+			} else {
+				_, err = tx.Exec(ctx,
+					`UPDATE planeacion_datos_generales SET `+n.colTexto+` = $2 WHERE planeacion_id = $1`,
+					planeacionID, nombre)
+			}
+			if err != nil {
+				return err
+			}
+		case n.texto != nil || enviados[n.campo] || enviados[n.clave]:
+			// Texto nuevo (o borrado con null en un PATCH): se vuelve a ligar por similitud
+			if _, err := tx.Exec(ctx, `UPDATE planeaciones SET `+n.campo+` = NULL WHERE id = $1`, planeacionID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Referencias que ya no corresponden a la jerarquía
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE planeaciones p SET academia_id = NULL
+		 WHERE p.id = $1 AND p.academia_id IS NOT NULL AND p.programa_id IS NOT NULL
+		   AND NOT EXISTS (SELECT 1 FROM public.academias a WHERE a.id = p.academia_id AND a.programa_id = p.programa_id)`,
+		planeacionID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE planeaciones p SET unidad_aprendizaje_id = NULL
+		 WHERE p.id = $1 AND p.unidad_aprendizaje_id IS NOT NULL AND p.academia_id IS NOT NULL
+		   AND NOT EXISTS (SELECT 1 FROM public.unidades_aprendizaje ua WHERE ua.id = p.unidad_aprendizaje_id AND ua.academia_id = p.academia_id)`,
+		planeacionID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT public.vincular_catalogos($1)`, planeacionID); err != nil {
+		return err
+	}
+
+	// Unidad de aprendizaje elegida: completa semestre, créditos y horas vacíos
+	if p.UnidadAprendizajeID != nil {
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE planeacion_datos_generales dg
+			 SET semestre_nivel = COALESCE(dg.semestre_nivel, ua.semestre::text),
+			     creditos_tepic = COALESCE(dg.creditos_tepic, ua.creditos_tepic),
+			     creditos_satca = COALESCE(dg.creditos_satca, ua.creditos_satca),
+			     horas_teoria   = COALESCE(dg.horas_teoria, ua.horas_teoria),
+			     horas_practica = COALESCE(dg.horas_practica, ua.horas_practica),
+			     horas_total    = COALESCE(dg.horas_total, ua.horas_total)
+			 FROM planeaciones p
+			 JOIN public.unidades_aprendizaje ua ON ua.id = p.unidad_aprendizaje_id
+			 WHERE p.id = $1 AND dg.planeacion_id = p.id`,
+			planeacionID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
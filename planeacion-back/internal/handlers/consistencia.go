@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Reporte de consistencia de horas, sesiones y créditos.
+// Se consulta en GET /api/planeaciones/:id/consistencia y se exige al publicar
+// (status = "finalizada"): cualquier hallazgo con severidad "error" lo impide.
+// Los totales sin capturar son advertencias en el reporte y errores al publicar.
+// =============================
+
+// Fórmula institucional de créditos
+const (
+	horasPorCreditoSATCA   = 16.0 // SATCA: 16 horas de docencia = 1 crédito
+	creditosTEPICTeoria    = 2.0  // TEPIC: 1 h/semana de teoría = 2 créditos
+	creditosTEPICPractica  = 1.0  // TEPIC: 1 h/semana de práctica = 1 crédito
+	toleranciaConsistencia = 0.01
+)
+
+const (
+	severidadError       = "error"
+	severidadAdvertencia = "advertencia"
+)
+
+// Hallazgo del reporte
+type hallazgoConsistencia struct {
+	Regla     string   `json:"regla"`
+	Severidad string   `json:"severidad"`
+	Mensaje   string   `json:"mensaje"`
+	Esperado  *float64 `json:"esperado,omitempty"`
+	Actual    *float64 `json:"actual,omitempty"`
+	Unidades  []int    `json:"unidades,omitempty"`
+}
+
+type reporteConsistencia struct {
+	Consistente bool                   `json:"consistente"`
+	Errores     int                    `json:"errores"`
+	Hallazgos   []hallazgoConsistencia `json:"hallazgos"`
+}
+
+// consultaDB: lo común entre *pgxpool.Pool y pgx.Tx.
+type consultaDB interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Valores de datos generales (semestre)
+type datosSemestre struct {
+	Semanas                           *int
+	Sesiones                          *int
+	SesAula, SesLab, SesClin, SesOtro *int
+	Teoria, Practica, Total           *float64
+	Aula, Laboratorio, Clinica, Otro  *float64
+	CreditosTEPIC, CreditosSATCA      *float64
+}
+
+// Sumas de las unidades temáticas
+type sumasUnidades struct {
+	Total                     int
+	Aula, Laboratorio, Taller float64
+	Clinica, Otro             float64
+	Sesiones, Porcentaje      int
+}
+
+func cargarDatosSemestre(ctx context.Context, db consultaDB, planeacionID int) (datosSemestre, error) {
+	var d datosSemestre
+	err := db.QueryRow(
+		ctx,
+		`
+SELECT
+  semanas_por_semestre, sesiones_por_semestre,
+  sesiones_aula, sesiones_laboratorio, sesiones_clinica, sesiones_otro,
+  horas_teoria::float8, horas_practica::float8, horas_total::float8,
+  horas_aula::float8, horas_laboratorio::float8, horas_clinica::float8, horas_otro::float8,
+  creditos_tepic::float8, creditos_satca::float8
+FROM planeacion_datos_generales
+WHERE planeacion_id = $1
+		`,
+		planeacionID,
+	).Scan(
+		&d.Semanas, &d.Sesiones,
+		&d.SesAula, &d.SesLab, &d.SesClin, &d.SesOtro,
+		&d.Teoria, &d.Practica, &d.Total,
+		&d.Aula, &d.Laboratorio, &d.Clinica, &d.Otro,
+		&d.CreditosTEPIC, &d.CreditosSATCA,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, nil
+	}
+	return d, err
+}
+
+func cargarSumasUnidades(ctx context.Context, db consultaDB, planeacionID int) (sumasUnidades, error) {
+	var s sumasUnidades
+	err := db.QueryRow(
+		ctx,
+		`
+SELECT
+  COUNT(*),
+  COALESCE(SUM(horas_aula), 0)::float8,
+  COALESCE(SUM(horas_laboratorio), 0)::float8,
+  COALESCE(SUM(horas_taller), 0)::float8,
+  COALESCE(SUM(horas_clinica), 0)::float8,
+  COALESCE(SUM(horas_otro), 0)::float8,
+  COALESCE(SUM(sesiones_totales), 0)::int,
+  COALESCE(SUM(porcentaje), 0)::int
+FROM unidades_tematicas
+WHERE planeacion_id = $1
+		`,
+		planeacionID,
+	).Scan(&s.Total, &s.Aula, &s.Laboratorio, &s.Taller, &s.Clinica, &s.Otro, &s.Sesiones, &s.Porcentaje)
+	return s, err
+}
+
+// reporteConsistenciaPlaneacion arma el reporte completo de una planeación;
+// con publicar, los datos sin capturar cuentan como errores.
+func reporteConsistenciaPlaneacion(ctx context.Context, db consultaDB, planeacionID int, publicar bool) (reporteConsistencia, error) {
+	d, err := cargarDatosSemestre(ctx, db, planeacionID)
+	if err != nil {
+		return reporteConsistencia{}, err
+	}
+	u, err := cargarSumasUnidades(ctx, db, planeacionID)
+	if err != nil {
+		return reporteConsistencia{}, err
+	}
+	sinBasica, err := unidadesSinReferenciaBasica(ctx, db, planeacionID)
+	if err != nil {
+		return reporteConsistencia{}, err
+	}
+
+	hallazgos := revisarConsistencia(d, u, publicar)
+	if len(sinBasica) > 0 {
+		hallazgos = append(hallazgos, hallazgoConsistencia{
+			Regla:     "referencias_basicas",
+			Severidad: severidadError,
+			Mensaje:   "Cada unidad temática necesita al menos una referencia Básica.",
+			Unidades:  sinBasica,
+		})
+	}
+
+	r := reporteConsistencia{Hallazgos: hallazgos}
+	for _, h := range hallazgos {
+		if h.Severidad == severidadError {
+			r.Errores++
+		}
+	}
+	r.Consistente = r.Errores == 0
+	return r, nil
+}
+
+// revisarConsistencia aplica las reglas; los datos sin capturar generan
+// advertencias, o errores si se va a publicar.
+func revisarConsistencia(d datosSemestre, u sumasUnidades, publicar bool) []hallazgoConsistencia {
+	hallazgos := []hallazgoConsistencia{}
+	comparar := func(regla, mensaje string, esperado, actual float64) {
+		if math.Abs(esperado-actual) > toleranciaConsistencia {
+			hallazgos = append(hallazgos, hallazgoConsistencia{
+				Regla:     regla,
+				Severidad: severidadError,
+				Mensaje:   mensaje,
+				Esperado:  floatPtr(redondear2(esperado)),
+				Actual:    floatPtr(redondear2(actual)),
+			})
+		}
+	}
+	severidadFaltante := severidadAdvertencia
+	if publicar {
+		severidadFaltante = severidadError
+	}
+	faltante := func(regla, mensaje string) {
+		hallazgos = append(hallazgos, hallazgoConsistencia{
+			Regla:     regla,
+			Severidad: severidadFaltante,
+			Mensaje:   mensaje,
+		})
+	}
+
+	// Horas por espacio: suma de unidades vs. semestre (taller cuenta como "otro")
+	espacios := []struct {
+		nombre   string
+		semestre *float64
+		unidades float64
+	}{
+		{"aula", d.Aula, u.Aula},
+		{"laboratorio", d.Laboratorio, u.Laboratorio},
+		{"clinica", d.Clinica, u.Clinica},
+		{"otro", d.Otro, u.Otro + u.Taller},
+	}
+	for _, e := range espacios {
+		if e.semestre == nil {
+			if e.unidades > 0 {
+				faltante("horas_espacio_"+e.nombre, "Las unidades registran horas de "+e.nombre+" pero el semestre no.")
+			}
+			continue
+		}
+		comparar(
+			"horas_espacio_"+e.nombre,
+			"La suma de horas de "+e.nombre+" de las unidades no coincide con las horas del semestre.",
+			*e.semestre,
+			e.unidades,
+		)
+	}
+
+	// Total de horas: espacios y teoría + práctica
+	if d.Total == nil {
+		faltante("horas_total", "No se capturó el total de horas por semestre.")
+	} else {
+		comparar(
+			"horas_total_espacios",
+			"La suma de horas por espacio no coincide con el total de horas.",
+			*d.Total,
+			floatVal(d.Aula)+floatVal(d.Laboratorio)+floatVal(d.Clinica)+floatVal(d.Otro),
+		)
+		if d.Teoria == nil && d.Practica == nil {
+			faltante("horas_teoria_practica", "No se capturaron horas de teoría ni de práctica.")
+		} else {
+			comparar(
+				"horas_teoria_practica",
+				"Teoría + práctica no coincide con el total de horas.",
+				*d.Total,
+				floatVal(d.Teoria)+floatVal(d.Practica),
+			)
+		}
+	}
+
+	// Sesiones
+	if d.Sesiones == nil {
+		faltante("sesiones_semestre", "No se capturaron las sesiones por semestre.")
+	} else {
+		comparar(
+			"sesiones_semestre_espacios",
+			"La suma de sesiones por espacio no coincide con las sesiones por semestre.",
+			float64(*d.Sesiones),
+			float64(intVal(d.SesAula)+intVal(d.SesLab)+intVal(d.SesClin)+intVal(d.SesOtro)),
+		)
+		if u.Total > 0 {
+			comparar(
+				"sesiones_unidades",
+				"La suma de sesiones totales de las unidades no coincide con las sesiones por semestre.",
+				float64(*d.Sesiones),
+				float64(u.Sesiones),
+			)
+		}
+	}
+
+	// Porcentaje de las unidades
+	if u.Total > 0 {
+		comparar(
+			"porcentaje_unidades",
+			"El porcentaje de las unidades temáticas debe sumar 100.",
+			100,
+			float64(u.Porcentaje),
+		)
+	}
+
+	// Créditos
+	if d.Total != nil {
+		if d.CreditosSATCA == nil {
+			faltante("creditos_satca", "No se capturaron los créditos SATCA.")
+		} else {
+			comparar(
+				"creditos_satca",
+				"Los créditos SATCA no corresponden a las horas del semestre ("+
+					strconv.FormatFloat(horasPorCreditoSATCA, 'f', -1, 64)+" h = 1 crédito).",
+				redondear2(*d.Total/horasPorCreditoSATCA),
+				*d.CreditosSATCA,
+			)
+		}
+	}
+	switch {
+	case d.CreditosTEPIC == nil:
+		faltante("creditos_tepic", "No se capturaron los créditos TEPIC.")
+	case d.Semanas == nil || *d.Semanas <= 0:
+		faltante("creditos_tepic", "Sin semanas por semestre no se pueden verificar los créditos TEPIC.")
+	case d.Teoria != nil || d.Practica != nil:
+		semanas := float64(*d.Semanas)
+		esperado := (creditosTEPICTeoria*floatVal(d.Teoria) + creditosTEPICPractica*floatVal(d.Practica)) / semanas
+		comparar(
+			"creditos_tepic",
+			fmt.Sprintf(
+				"Los créditos TEPIC no corresponden a las horas semanales (teoría × %g + práctica × %g).",
+				creditosTEPICTeoria,
+				creditosTEPICPractica,
+			),
+			redondear2(esperado),
+			*d.CreditosTEPIC,
+		)
+	}
+
+	return hallazgos
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+func floatVal(p *float64) float64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func intVal(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func redondear2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// errInconsistente: la planeación no se puede publicar (400 con el reporte).
+type errInconsistente reporteConsistencia
+
+func (e errInconsistente) Error() string {
+	return "La planeación tiene inconsistencias; corrígelas antes de publicar."
+}
+
+// exigirConsistencia regresa errInconsistente si el reporte tiene errores.
+func exigirConsistencia(ctx context.Context, db consultaDB, planeacionID int) error {
+	r, err := reporteConsistenciaPlaneacion(ctx, db, planeacionID, true)
+	if err != nil {
+		return err
+	}
+	if !r.Consistente {
+		return errInconsistente(r)
+	}
+	return nil
+}
+
+// prepararPublicacion: lo que exige y recalcula pasar a 'finalizada', ya sea
+// desde Update o al desarchivar. Publicar exige el reporte de consistencia
+// sin errores (horas, sesiones, créditos y referencias básicas por unidad) y
+// recalcula el índice de búsqueda pública.
+func prepararPublicacion(ctx context.Context, tx pgx.Tx, planeacionID int) error {
+	if err := exigirConsistencia(ctx, tx, planeacionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `SELECT public.planeacion_refresh_search($1)`, planeacionID); err != nil {
+		return fmt.Errorf("índice de búsqueda: %w", err)
+	}
+	return nil
+}
+
+// =============================
+// GET /api/planeaciones/:id/consistencia
+// =============================
+func (h *PlaneacionesHandler) Consistencia(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var status string
+	err = h.DB.QueryRow(
+		c,
+		`SELECT status FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL`,
+		id,
+		claims.UserID,
+	).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	r, err := reporteConsistenciaPlaneacion(c, h.DB, id, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"planeacion_id": id,
+		"status":        strings.TrimSpace(status),
+		"reporte":       r,
+	})
+}
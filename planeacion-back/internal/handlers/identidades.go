@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// Identidades vinculadas (Google / contraseña) del usuario actual
+// La identidad "password" es implícita: existe si password_hash es bcrypt.
+// =============================
+
+// RegisterIdentidadesRoutes registra /api/me/identidades (requiere AuthMiddleware).
+func RegisterIdentidadesRoutes(rg *gin.RouterGroup, h *PerfilHandler) {
+	g := rg.Group("/me/identidades")
+
+	g.GET("", h.ListIdentidades)              // GET /api/me/identidades
+	g.POST("/google", h.LinkGoogle)           // POST /api/me/identidades/google
+	g.DELETE("/:provider", h.UnlinkIdentidad) // DELETE /api/me/identidades/google
+}
+
+type linkGoogleRequest struct {
+	IDToken string `json:"id_token"`
+}
+
+// GET /api/me/identidades
+func (h *PerfilHandler) ListIdentidades(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var passwordHash string
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT password_hash FROM public.usuarios WHERE id = $1`,
+		claims.UserID,
+	).Scan(&passwordHash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "usuario no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar usuario",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	rows, err := h.DB.Query(
+		ctx,
+		`SELECT id, usuario_id, provider, subject, email, last_login_at, created_at, updated_at
+		 FROM public.usuario_identidades
+		 WHERE usuario_id = $1
+		 ORDER BY provider`,
+		claims.UserID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar identidades",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.UsuarioIdentidad, 0)
+	for rows.Next() {
+		var i models.UsuarioIdentidad
+		if err := rows.Scan(
+			&i.ID,
+			&i.UsuarioID,
+			&i.Provider,
+			&i.Subject,
+			&i.Email,
+			&i.LastLoginAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer identidades",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar identidades",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          items,
+		"tiene_password": hasUsablePassword(passwordHash),
+	})
+}
+
+// POST /api/me/identidades/google
+// Body: { "id_token": "..." }
+// Vincula la cuenta de Google del id_token al usuario actual.
+func (h *PerfilHandler) LinkGoogle(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectIfAPIToken(c) {
+		return
+	}
+
+	var req linkGoogleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
+		return
+	}
+
+	idTok := strings.TrimSpace(req.IDToken)
+	if idTok == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id_token es obligatorio"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	gid, status, err := verifyGoogleIDToken(ctx, idTok)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": "configuración Google inválida", "msg": err.Error()})
+			return
+		}
+		c.JSON(status, gin.H{"error": "id_token inválido", "msg": err.Error()})
+		return
+	}
+
+	// ¿ese sub ya pertenece a alguien?
+	var ownerID int
+	err = h.DB.QueryRow(
+		ctx,
+		`SELECT usuario_id FROM public.usuario_identidades WHERE provider = 'google' AND subject = $1`,
+		gid.Subject,
+	).Scan(&ownerID)
+	if err == nil {
+		if ownerID == claims.UserID {
+			c.JSON(http.StatusOK, gin.H{"ok": true, "provider": "google"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "esa cuenta de Google ya está vinculada a otro usuario"})
+		return
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar identidades",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var i models.UsuarioIdentidad
+	err = tx.QueryRow(
+		ctx,
+		`INSERT INTO public.usuario_identidades (usuario_id, provider, subject, email)
+		 VALUES ($1, 'google', $2, $3)
+		 ON CONFLICT (usuario_id, provider) DO NOTHING
+		 RETURNING id, usuario_id, provider, subject, email, last_login_at, created_at, updated_at`,
+		claims.UserID,
+		gid.Subject,
+		gid.Email,
+	).Scan(
+		&i.ID,
+		&i.UsuarioID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.LastLoginAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "tu cuenta ya tiene otra cuenta de Google vinculada; desvincúlala primero",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al vincular identidad",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	// Vinculación explícita: el login con Google vuelve a funcionar
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE public.usuarios SET google_desvinculado_at = NULL WHERE id = $1`,
+		claims.UserID,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al vincular identidad",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionIdentidadVincular, "usuario", int64(claims.UserID), gin.H{
+		"provider": "google",
+		"email":    gid.Email,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, i)
+}
+
+// DELETE /api/me/identidades/:provider
+// No permite dejar la cuenta sin forma de iniciar sesión.
+func (h *PerfilHandler) UnlinkIdentidad(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectIfAPIToken(c) {
+		return
+	}
+
+	provider := strings.TrimSpace(strings.ToLower(c.Param("provider")))
+	if provider == "" || provider == "password" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proveedor inválido"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		passwordHash string
+		otras        int
+	)
+	if err := tx.QueryRow(
+		ctx,
+		`SELECT u.password_hash,
+		        (SELECT COUNT(*) FROM public.usuario_identidades i
+		         WHERE i.usuario_id = u.id AND i.provider <> $2)
+		 FROM public.usuarios u
+		 WHERE u.id = $1
+		 FOR UPDATE`,
+		claims.UserID,
+		provider,
+	).Scan(&passwordHash, &otras); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "usuario no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar usuario",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if !hasUsablePassword(passwordHash) && otras == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "define una contraseña antes de desvincular tu único método de acceso",
+			"hint":  "POST /api/me/password",
+		})
+		return
+	}
+
+	res, err := tx.Exec(
+		ctx,
+		`DELETE FROM public.usuario_identidades WHERE usuario_id = $1 AND provider = $2`,
+		claims.UserID,
+		provider,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al desvincular identidad",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	if res.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "identidad no vinculada"})
+		return
+	}
+
+	// Sin esta marca, el siguiente login con Google la volvería a vincular por email
+	if provider == "google" {
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE public.usuarios SET google_desvinculado_at = now() WHERE id = $1`,
+			claims.UserID,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al desvincular identidad",
+				"msg":   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionIdentidadDesvincular, "usuario", int64(claims.UserID), gin.H{
+		"provider": provider,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
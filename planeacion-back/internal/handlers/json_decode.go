@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================
+// Decodificación estricta de JSON
+// Valida el cuerpo contra el struct destino antes de decodificarlo:
+//   - tipos incorrectos → 400 con la ruta y el tipo esperado de cada campo
+//   - campos desconocidos → advertencias (o 400 con ?strict=1 / X-Strict-JSON: 1)
+// =============================
+
+const (
+	motivoTipoInvalido     = "tipo_invalido"
+	motivoCampoDesconocido = "campo_desconocido"
+)
+
+// JSONFieldError describe un problema en un campo del cuerpo JSON.
+type JSONFieldError struct {
+	Campo    string `json:"campo"` // ej. unidades_tematicas[0].bloques[2].numero_sesion
+	Motivo   string `json:"motivo"`
+	Esperado string `json:"esperado,omitempty"`
+	Recibido string `json:"recibido,omitempty"`
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// strictJSONRequested indica si el cliente pidió rechazar campos desconocidos.
+func strictJSONRequested(c *gin.Context) bool {
+	v := c.Query("strict")
+	if v == "" {
+		v = c.GetHeader("X-Strict-JSON")
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	return v == "1" || v == "true"
+}
+
+// decodeJSONBody decodifica el cuerpo en dst (puntero a struct).
+// Si falla responde 400 y regresa ok=false; si no, regresa las advertencias
+// (campos desconocidos ignorados) para incluirlas en la respuesta.
+func decodeJSONBody(c *gin.Context, dst any) (advertencias []JSONFieldError, ok bool) {
+	advertencias, _, ok = decodeJSONBodyCampos(c, dst)
+	return advertencias, ok
+}
+
+// decodeJSONBodyCampos es decodeJSONBody y además regresa las claves de
+// primer nivel presentes en el cuerpo (en minúsculas), aunque valgan null.
+// Los PATCH la usan para distinguir un campo omitido de uno que se borra.
+func decodeJSONBodyCampos(c *gin.Context, dst any) (advertencias []JSONFieldError, campos camposEnviados, ok bool) {
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer el cuerpo: " + err.Error()})
+		return nil, nil, false
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido", "msg": "cuerpo vacío"})
+		return nil, nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		resp := gin.H{"error": "JSON inválido", "msg": err.Error()}
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			resp["offset"] = se.Offset
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return nil, nil, false
+	}
+	if dec.More() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido", "msg": "datos adicionales después del objeto"})
+		return nil, nil, false
+	}
+
+	var tipos, desconocidos []JSONFieldError
+	checkJSONShape(generic, reflect.TypeOf(dst).Elem(), "", &tipos, &desconocidos)
+	sortFieldErrors(tipos)
+	sortFieldErrors(desconocidos)
+
+	strict := strictJSONRequested(c)
+	if len(tipos) > 0 || (strict && len(desconocidos) > 0) {
+		detalles := tipos
+		if strict {
+			detalles = append(detalles, desconocidos...)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido", "detalles": detalles})
+		return nil, nil, false
+	}
+
+	if err := json.Unmarshal(raw, dst); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido", "msg": err.Error()})
+		return nil, nil, false
+	}
+
+	campos = camposEnviados{}
+	if obj, ok := generic.(map[string]any); ok {
+		for k := range obj {
+			campos[strings.ToLower(k)] = true
+		}
+	}
+
+	return desconocidos, campos, true
+}
+
+// checkJSONShape recorre el valor genérico comparándolo con el tipo Go destino,
+// con la misma semántica que encoding/json (null siempre se acepta, nombres
+// sin distinguir mayúsculas como respaldo).
+func checkJSONShape(v any, t reflect.Type, path string, tipos, desconocidos *[]JSONFieldError) {
+	if v == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	mismatch := func(esperado string) {
+		*tipos = append(*tipos, JSONFieldError{
+			Campo:    rootPath(path),
+			Motivo:   motivoTipoInvalido,
+			Esperado: esperado,
+			Recibido: jsonKindName(v),
+		})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			mismatch("objeto")
+			return
+		}
+		fields := jsonStructFields(t)
+		for key, val := range obj {
+			f, found := fields[key]
+			if !found {
+				for name, ff := range fields {
+					if strings.EqualFold(name, key) {
+						f, found = ff, true
+						break
+					}
+				}
+			}
+			if !found {
+				*desconocidos = append(*desconocidos, JSONFieldError{
+					Campo:  joinPath(path, key),
+					Motivo: motivoCampoDesconocido,
+				})
+				continue
+			}
+			checkJSONShape(val, f, joinPath(path, key), tipos, desconocidos)
+		}
+
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			mismatch("objeto")
+			return
+		}
+		for key, val := range obj {
+			checkJSONShape(val, t.Elem(), joinPath(path, key), tipos, desconocidos)
+		}
+
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.([]any)
+		if !ok {
+			mismatch("arreglo de " + jsonTypeName(t.Elem()))
+			return
+		}
+		for i, val := range arr {
+			checkJSONShape(val, t.Elem(), path+"["+strconv.Itoa(i)+"]", tipos, desconocidos)
+		}
+
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			mismatch("string")
+		}
+
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			mismatch("booleano")
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(json.Number)
+		if !ok {
+			mismatch("entero")
+			return
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, t.Bits()); err != nil {
+			mismatch("entero")
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(json.Number)
+		if !ok {
+			mismatch("entero no negativo")
+			return
+		}
+		if _, err := strconv.ParseUint(n.String(), 10, t.Bits()); err != nil {
+			mismatch("entero no negativo")
+		}
+
+	case reflect.Float32, reflect.Float64:
+		n, ok := v.(json.Number)
+		if !ok {
+			mismatch("número")
+			return
+		}
+		if _, err := strconv.ParseFloat(n.String(), t.Bits()); err != nil {
+			mismatch("número")
+		}
+	}
+}
+
+// jsonStructFields mapea nombre JSON → tipo de campo (solo campos exportados).
+func jsonStructFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		tagName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tagName == "-" {
+			continue
+		}
+		// Struct embebido sin nombre JSON: sus campos se aplanan (como encoding/json)
+		if f.Anonymous && tagName == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for name, t := range jsonStructFields(ft) {
+					if _, dup := out[name]; !dup {
+						out[name] = t
+					}
+				}
+				continue
+			}
+		}
+		name := f.Name
+		if tagName != "" {
+			name = tagName
+		}
+		out[name] = f.Type
+	}
+	return out
+}
+
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "objeto"
+	case reflect.Slice, reflect.Array:
+		return "arreglo"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "booleano"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "entero"
+	case reflect.Float32, reflect.Float64:
+		return "número"
+	}
+	return "valor"
+}
+
+func jsonKindName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "booleano"
+	case json.Number:
+		return "número"
+	case []any:
+		return "arreglo"
+	case map[string]any:
+		return "objeto"
+	}
+	return "desconocido"
+}
+
+func sortFieldErrors(errs []JSONFieldError) {
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Campo < errs[j].Campo })
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
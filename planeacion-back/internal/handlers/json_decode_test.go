@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type formaBase struct {
+	Nombre string `json:"nombre"`
+	Activa *bool  `json:"activa"`
+}
+
+type formaSesion struct {
+	NumeroSesion int      `json:"numero_sesion"`
+	Valor        *float64 `json:"valor_porcentual"`
+}
+
+type formaPrueba struct {
+	formaBase
+	Anio     *int              `json:"anio"`
+	Sesiones []formaSesion     `json:"sesiones"`
+	Extras   map[string]string `json:"extras"`
+	Interno  string            `json:"-"`
+	SinTag   string
+}
+
+func TestCheckJSONShape(t *testing.T) {
+	casos := []struct {
+		nombre       string
+		cuerpo       string
+		tipos        []JSONFieldError
+		desconocidos []string
+	}{
+		{
+			nombre: "válido",
+			cuerpo: `{"nombre":"x","activa":true,"anio":2024,"sesiones":[{"numero_sesion":1,"valor_porcentual":12.5}],"extras":{"a":"b"}}`,
+		},
+		{
+			nombre: "null siempre se acepta",
+			cuerpo: `{"nombre":null,"anio":null,"sesiones":null,"extras":null}`,
+		},
+		{
+			nombre: "string en lugar de entero",
+			cuerpo: `{"anio":"2024"}`,
+			tipos:  []JSONFieldError{{Campo: "anio", Motivo: motivoTipoInvalido, Esperado: "entero", Recibido: "string"}},
+		},
+		{
+			nombre: "decimal en entero",
+			cuerpo: `{"anio":20.5}`,
+			tipos:  []JSONFieldError{{Campo: "anio", Motivo: motivoTipoInvalido, Esperado: "entero", Recibido: "número"}},
+		},
+		{
+			nombre: "ruta dentro de arreglo",
+			cuerpo: `{"sesiones":[{"numero_sesion":1},{"numero_sesion":"dos"}]}`,
+			tipos:  []JSONFieldError{{Campo: "sesiones[1].numero_sesion", Motivo: motivoTipoInvalido, Esperado: "entero", Recibido: "string"}},
+		},
+		{
+			nombre: "objeto en lugar de arreglo",
+			cuerpo: `{"sesiones":{"numero_sesion":1}}`,
+			tipos:  []JSONFieldError{{Campo: "sesiones", Motivo: motivoTipoInvalido, Esperado: "arreglo de objeto", Recibido: "objeto"}},
+		},
+		{
+			nombre: "valor de mapa",
+			cuerpo: `{"extras":{"a":1}}`,
+			tipos:  []JSONFieldError{{Campo: "extras.a", Motivo: motivoTipoInvalido, Esperado: "string", Recibido: "número"}},
+		},
+		{
+			nombre: "campo de struct embebido",
+			cuerpo: `{"activa":"si"}`,
+			tipos:  []JSONFieldError{{Campo: "activa", Motivo: motivoTipoInvalido, Esperado: "booleano", Recibido: "string"}},
+		},
+		{
+			nombre: "raíz que no es objeto",
+			cuerpo: `[1,2]`,
+			tipos:  []JSONFieldError{{Campo: "$", Motivo: motivoTipoInvalido, Esperado: "objeto", Recibido: "arreglo"}},
+		},
+		{
+			nombre: "nombre sin distinguir mayúsculas",
+			cuerpo: `{"Nombre":"x","ANIO":1,"Sesiones":[{"Numero_Sesion":1}]}`,
+		},
+		{
+			nombre: "tipo revisado también sin distinguir mayúsculas",
+			cuerpo: `{"ANIO":"x"}`,
+			tipos:  []JSONFieldError{{Campo: "ANIO", Motivo: motivoTipoInvalido, Esperado: "entero", Recibido: "string"}},
+		},
+		{
+			nombre: "campo sin tag usa el nombre Go",
+			cuerpo: `{"SinTag":"x"}`,
+		},
+		{
+			nombre:       "campos desconocidos",
+			cuerpo:       `{"otro":1,"sesiones":[{"numero_sesion":1,"tema":"x"}]}`,
+			desconocidos: []string{"otro", "sesiones[0].tema"},
+		},
+		{
+			nombre:       "json:\"-\" no se acepta",
+			cuerpo:       `{"Interno":"x"}`,
+			desconocidos: []string{"Interno"},
+		},
+	}
+
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			dec := json.NewDecoder(strings.NewReader(tc.cuerpo))
+			dec.UseNumber()
+			var generic any
+			if err := dec.Decode(&generic); err != nil {
+				t.Fatalf("cuerpo de prueba inválido: %v", err)
+			}
+
+			var tipos, desconocidos []JSONFieldError
+			checkJSONShape(generic, reflect.TypeOf(formaPrueba{}), "", &tipos, &desconocidos)
+			sortFieldErrors(tipos)
+			sortFieldErrors(desconocidos)
+
+			if len(tipos) != len(tc.tipos) || (len(tipos) > 0 && !reflect.DeepEqual(tipos, tc.tipos)) {
+				t.Errorf("tipos = %+v, se esperaba %+v", tipos, tc.tipos)
+			}
+			var campos []string
+			for _, d := range desconocidos {
+				if d.Motivo != motivoCampoDesconocido {
+					t.Errorf("motivo = %q, se esperaba %q", d.Motivo, motivoCampoDesconocido)
+				}
+				campos = append(campos, d.Campo)
+			}
+			if !reflect.DeepEqual(campos, tc.desconocidos) {
+				t.Errorf("desconocidos = %v, se esperaba %v", campos, tc.desconocidos)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	casos := []struct {
+		nombre       string
+		url          string
+		header       string
+		cuerpo       string
+		ok           bool
+		advertencias int
+		campos       []string
+	}{
+		{nombre: "válido", url: "/", cuerpo: `{"nombre":"x","anio":null}`, ok: true, campos: []string{"anio", "nombre"}},
+		{nombre: "desconocido es advertencia", url: "/", cuerpo: `{"nombre":"x","otro":1}`, ok: true, advertencias: 1, campos: []string{"nombre", "otro"}},
+		{nombre: "desconocido con ?strict=1", url: "/?strict=1", cuerpo: `{"nombre":"x","otro":1}`},
+		{nombre: "desconocido con X-Strict-JSON", url: "/", header: "true", cuerpo: `{"otro":1}`},
+		{nombre: "?strict=0 no es estricto", url: "/?strict=0", cuerpo: `{"otro":1}`, ok: true, advertencias: 1, campos: []string{"otro"}},
+		{nombre: "tipo inválido", url: "/", cuerpo: `{"anio":"x"}`},
+		{nombre: "cuerpo vacío", url: "/", cuerpo: "  "},
+		{nombre: "sintaxis", url: "/", cuerpo: `{"nombre":`},
+		{nombre: "datos adicionales", url: "/", cuerpo: `{"nombre":"x"} {}`},
+		{nombre: "claves en minúsculas", url: "/", cuerpo: `{"Nombre":"x"}`, ok: true, campos: []string{"nombre"}},
+	}
+
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, tc.url, bytes.NewBufferString(tc.cuerpo))
+			if tc.header != "" {
+				c.Request.Header.Set("X-Strict-JSON", tc.header)
+			}
+
+			var dst formaPrueba
+			advertencias, campos, ok := decodeJSONBodyCampos(c, &dst)
+			if ok != tc.ok {
+				t.Fatalf("ok = %v, se esperaba %v (respuesta %s)", ok, tc.ok, w.Body.String())
+			}
+			if !ok {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, se esperaba 400", w.Code)
+				}
+				return
+			}
+			if len(advertencias) != tc.advertencias {
+				t.Errorf("advertencias = %+v, se esperaban %d", advertencias, tc.advertencias)
+			}
+			var enviados []string
+			for k := range campos {
+				enviados = append(enviados, k)
+			}
+			sort.Strings(enviados)
+			if !reflect.DeepEqual(enviados, tc.campos) {
+				t.Errorf("campos = %v, se esperaba %v", enviados, tc.campos)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================
+// Papelera de planeaciones (borrado lógico con deleted_at)
+// DELETE /api/planeaciones/:id la manda aquí; se puede restaurar hasta que
+// la purga la elimina definitivamente (PAPELERA_RETENCION_DIAS, default 30;
+// 0 = no purgar). Mientras tanto los endpoints públicos responden 410.
+// =============================
+
+// Frecuencia con la que se revisa la papelera
+const intervaloPurgaPapelera = time.Hour
+
+// retencionPapelera: días que una planeación permanece en la papelera.
+func retencionPapelera() int {
+	return envEnteroNoNegativo("PAPELERA_RETENCION_DIAS", 30)
+}
+
+// sqlEliminadaPublicada: planeación en papelera que llegó a publicarse (alias p).
+// Un borrador eliminado nunca fue público: debe responder 404, no 410.
+const sqlEliminadaPublicada = "(p.deleted_at IS NOT NULL AND p.finalizada_at IS NOT NULL)"
+
+// responderPlaneacionEliminada: 410 para slugs/ids de planeaciones publicadas
+// que después se enviaron a la papelera.
+func responderPlaneacionEliminada(c *gin.Context) {
+	c.JSON(http.StatusGone, gin.H{"error": "La planeación fue eliminada"})
+}
+
+// =============================
+// GET /api/planeaciones/papelera
+// Planeaciones eliminadas del docente, la más reciente primero
+// =============================
+
+func (h *PlaneacionesHandler) Papelera(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	retencion := retencionPapelera()
+
+	rows, err := h.DB.Query(
+		c,
+		`
+		SELECT id, nombre_planeacion, status::text, slug, created_at, updated_at, deleted_at,
+		       CASE WHEN $2 > 0 THEN deleted_at + make_interval(days => $2) END
+		FROM planeaciones
+		WHERE docente_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		`,
+		claims.UserID,
+		retencion,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+
+	for rows.Next() {
+		var (
+			id                        int64
+			nombre, status            string
+			slug                      *string
+			created, updated, deleted time.Time
+			purgaAt                   *time.Time
+		)
+
+		if err := rows.Scan(&id, &nombre, &status, &slug, &created, &updated, &deleted, &purgaAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo filas: " + err.Error()})
+			return
+		}
+
+		list = append(list, gin.H{
+			"id":                id,
+			"nombre_planeacion": nombre,
+			"status":            status,
+			"slug":              slug,
+			"created_at":        created,
+			"updated_at":        updated,
+			"deleted_at":        deleted,
+			"purga_at":          purgaAt, // null = sin purga automática
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en cursor: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          list,
+		"retencion_dias": retencion,
+	})
+}
+
+// =============================
+// POST /api/planeaciones/:id/restaurar
+// Saca la planeación de la papelera con el status que tenía
+// (si estaba publicada, su slug vuelve a responder)
+// =============================
+
+func (h *PlaneacionesHandler) Restaurar(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var (
+		nombre, status string
+		eliminadaAt    time.Time
+	)
+	err = tx.QueryRow(
+		c,
+		`SELECT nombre_planeacion, status::text, deleted_at FROM planeaciones
+		 WHERE id = $1 AND docente_id = $2 AND deleted_at IS NOT NULL FOR UPDATE`,
+		id,
+		claims.UserID,
+	).Scan(&nombre, &status, &eliminadaAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada en la papelera"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	if _, err := tx.Exec(
+		c,
+		`UPDATE planeaciones SET deleted_at = NULL, deleted_by = NULL, updated_at = now() WHERE id = $1`,
+		id,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo restaurar: " + err.Error()})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionRestaurar, "planeacion", int64(id), gin.H{
+		"nombre_planeacion": nombre,
+		"status":            status,
+		"deleted_at":        eliminadaAt,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
+		return
+	}
+	if status == "finalizada" {
+		invalidarCachePublica()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "status": status})
+}
+
+// =============================
+// Purga automática
+// =============================
+
+// purgarPapelera borra definitivamente (con cascada a sus secciones) las
+// planeaciones con más de retencionPapelera días en la papelera y deja una
+// fila de bitácora por cada una. Regresa cuántas se purgaron.
+func purgarPapelera(ctx context.Context, db *pgxpool.Pool) (int64, error) {
+	dias := retencionPapelera()
+	if dias == 0 {
+		return 0, nil
+	}
+
+	tag, err := db.Exec(
+		ctx,
+		`
+WITH purgadas AS (
+  DELETE FROM planeaciones
+  WHERE deleted_at IS NOT NULL AND deleted_at < now() - make_interval(days => $1)
+  RETURNING id, docente_id, nombre_planeacion, status::text AS status, slug, deleted_at
+)
+INSERT INTO public.audit_log (accion, entidad, entidad_id, cambios)
+SELECT $2, 'planeacion', id, jsonb_build_object(
+  'docente_id', docente_id,
+  'nombre_planeacion', nombre_planeacion,
+  'status', status,
+  'slug', slug,
+  'deleted_at', deleted_at,
+  'retencion_dias', $1::int
+)
+FROM purgadas
+		`,
+		dias,
+		accionPlaneacionPurgar,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// IniciarPurgaPapelera revisa la papelera al arrancar y luego cada hora,
+// hasta que ctx se cancela. Una purga en curso no se corta al cancelar ctx
+// (solo por su propio timeout); el canal regresado se cierra cuando la
+// goroutine termina, para esperarla antes de cerrar el pool.
+func IniciarPurgaPapelera(ctx context.Context, db *pgxpool.Pool) <-chan struct{} {
+	terminada := make(chan struct{})
+	go func() {
+		defer close(terminada)
+		t := time.NewTicker(intervaloPurgaPapelera)
+		defer t.Stop()
+		for {
+			ctxPurga, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Minute)
+			n, err := purgarPapelera(ctxPurga, db)
+			cancel()
+			if err != nil {
+				log.Println("⚠️ No se pudo purgar la papelera:", err)
+			} else if n > 0 {
+				log.Printf("🗑️ Papelera: %d planeaciones purgadas", n)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	return terminada
+}
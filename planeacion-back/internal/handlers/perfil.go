@@ -0,0 +1,739 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// PerfilHandler
+// Perfil del usuario actual + solicitudes de cambio de unidad
+// =============================
+
+type PerfilHandler struct {
+	DB *pgxpool.Pool
+}
+
+// RegisterPerfilRoutes registra rutas del usuario actual (requiere AuthMiddleware).
+func RegisterPerfilRoutes(rg *gin.RouterGroup, h *PerfilHandler) {
+	rg.PATCH("/me", h.UpdateMe)                     // PATCH /api/me
+	rg.POST("/me/password", h.ChangePassword)       // POST /api/me/password
+	rg.GET("/me/cambio-unidad", h.ListCambios)      // GET /api/me/cambio-unidad
+	rg.POST("/me/cambio-unidad", h.SolicitarCambio) // POST /api/me/cambio-unidad
+	rg.DELETE("/me/cambio-unidad/:id", h.CancelarCambio)
+}
+
+// RegisterPerfilAdminRoutes registra la resolución de solicitudes (solo admin).
+func RegisterPerfilAdminRoutes(rg *gin.RouterGroup, h *PerfilHandler) {
+	g := rg.Group("/solicitudes-unidad")
+
+	g.GET("", h.AdminListCambios)            // GET /api/admin/solicitudes-unidad?status=pendiente
+	g.POST("/:id/aprobar", h.AdminAprobar)   // POST /api/admin/solicitudes-unidad/:id/aprobar
+	g.POST("/:id/rechazar", h.AdminRechazar) // POST /api/admin/solicitudes-unidad/:id/rechazar
+}
+
+// =============================
+// DTOs
+// =============================
+
+type updateMeRequest struct {
+	NombreCompleto *string `json:"nombre_completo"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type solicitarCambioRequest struct {
+	UnidadDestinoID int    `json:"unidad_destino_id"`
+	Motivo          string `json:"motivo"`
+}
+
+type resolverCambioRequest struct {
+	Comentario string `json:"comentario"`
+}
+
+const solicitudCols = `id, usuario_id, unidad_origen_id, unidad_destino_id, motivo, status,
+	resuelta_por, resuelta_at, comentario_resolucion, created_at, updated_at`
+
+func scanSolicitud(row pgx.Row, s *models.SolicitudCambioUnidad) error {
+	return row.Scan(
+		&s.ID,
+		&s.UsuarioID,
+		&s.UnidadOrigenID,
+		&s.UnidadDestinoID,
+		&s.Motivo,
+		&s.Status,
+		&s.ResueltaPor,
+		&s.ResueltaAt,
+		&s.ComentarioResolucion,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+}
+
+// =============================
+// Handler: PATCH /api/me
+// =============================
+
+// PATCH /api/me
+// Body: { "nombre_completo": "..." }
+// unidad_id NO se cambia aquí: usar POST /api/me/cambio-unidad.
+func (h *PerfilHandler) UpdateMe(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req updateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
+		return
+	}
+
+	var nombre *string
+	if req.NombreCompleto != nil {
+		n := strings.TrimSpace(*req.NombreCompleto)
+		if n == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "el nombre completo no puede estar vacío"})
+			return
+		}
+		if utf8.RuneCountInString(n) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "el nombre completo no debe exceder 255 caracteres"})
+			return
+		}
+		nombre = &n
+	}
+
+	const updateSQL = `
+		UPDATE public.usuarios
+		SET nombre_completo = COALESCE($2, nombre_completo), updated_at = now()
+		WHERE id = $1
+		RETURNING id, unidad_id, nombre_completo, email, role, is_active, created_at, updated_at;
+	`
+
+	ctx := c.Request.Context()
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var u models.Usuario
+	if err := tx.QueryRow(ctx, updateSQL, claims.UserID, strOrNil(nombre)).Scan(
+		&u.ID,
+		&u.UnidadID,
+		&u.NombreCompleto,
+		&u.Email,
+		&u.Role,
+		&u.IsActive,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "usuario no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al actualizar perfil",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPerfilEditar, "usuario", int64(claims.UserID), gin.H{
+		"nombre_completo": nombre,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	if nombre != nil {
+		invalidarCachePublica() // el nombre aparece en sus planeaciones publicadas
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// =============================
+// Handler: POST /api/me/password
+// =============================
+
+// POST /api/me/password
+// Body: { "current_password": "...", "new_password": "..." }
+// Cuentas solo-Google (sin contraseña propia) pueden definir la primera
+// contraseña sin enviar current_password.
+func (h *PerfilHandler) ChangePassword(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectIfAPIToken(c) {
+		return
+	}
+
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
+		return
+	}
+
+	if len(req.NewPassword) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "la contraseña debe tener al menos 8 caracteres"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var currentHash string
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT password_hash FROM public.usuarios WHERE id = $1`,
+		claims.UserID,
+	).Scan(&currentHash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "usuario no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar usuario",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	primeraVez := !hasUsablePassword(currentHash)
+	if !primeraVez {
+		if strings.TrimSpace(req.CurrentPassword) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "current_password es obligatorio"})
+			return
+		}
+		if err := hashMatchesPassword(currentHash, req.CurrentPassword); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "la contraseña actual no es correcta"})
+			return
+		}
+		if req.CurrentPassword == req.NewPassword {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "la nueva contraseña debe ser distinta de la actual"})
+			return
+		}
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo procesar la contraseña",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE public.usuarios SET password_hash = $2 WHERE id = $1`,
+		claims.UserID,
+		string(hashed),
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al actualizar contraseña",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionAuthPassword, "usuario", int64(claims.UserID), gin.H{
+		"primera_password": primeraVez,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":               true,
+		"primera_password": primeraVez,
+	})
+}
+
+// =============================
+// Solicitudes de cambio de unidad (usuario)
+// =============================
+
+// GET /api/me/cambio-unidad
+func (h *PerfilHandler) ListCambios(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := h.DB.Query(
+		c.Request.Context(),
+		`SELECT `+solicitudCols+`
+		 FROM public.solicitudes_cambio_unidad
+		 WHERE usuario_id = $1
+		 ORDER BY created_at DESC`,
+		claims.UserID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar solicitudes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.SolicitudCambioUnidad, 0)
+	for rows.Next() {
+		var s models.SolicitudCambioUnidad
+		if err := scanSolicitud(rows, &s); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer solicitudes",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, s)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar solicitudes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// POST /api/me/cambio-unidad
+// Body: { "unidad_destino_id": 3, "motivo": "..." }
+func (h *PerfilHandler) SolicitarCambio(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req solicitarCambioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload inválido"})
+		return
+	}
+	if req.UnidadDestinoID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_destino_id debe ser un entero positivo"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var unidadActual int
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT unidad_id FROM public.usuarios WHERE id = $1`,
+		claims.UserID,
+	).Scan(&unidadActual); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "usuario no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar usuario",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if unidadActual == req.UnidadDestinoID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ya perteneces a esa unidad académica"})
+		return
+	}
+
+	var dummy int
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT 1 FROM public.unidades_academicas WHERE id = $1 AND activa`,
+		req.UnidadDestinoID,
+	).Scan(&dummy); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "la unidad académica especificada no existe o está inactiva"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al validar unidad académica",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	var pendiente int
+	err = h.DB.QueryRow(
+		ctx,
+		`SELECT 1 FROM public.solicitudes_cambio_unidad WHERE usuario_id = $1 AND status = 'pendiente'`,
+		claims.UserID,
+	).Scan(&pendiente)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "ya tienes una solicitud de cambio pendiente"})
+		return
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al validar solicitudes pendientes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	var motivo *string
+	if m := strings.TrimSpace(req.Motivo); m != "" {
+		motivo = &m
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var s models.SolicitudCambioUnidad
+	if err := scanSolicitud(tx.QueryRow(
+		ctx,
+		`INSERT INTO public.solicitudes_cambio_unidad (usuario_id, unidad_origen_id, unidad_destino_id, motivo)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING `+solicitudCols,
+		claims.UserID,
+		unidadActual,
+		req.UnidadDestinoID,
+		strOrNil(motivo),
+	), &s); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al crear solicitud",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionSolicitudUnidadCrear, "solicitud_unidad", s.ID, gin.H{
+		"unidad_origen_id":  s.UnidadOrigenID,
+		"unidad_destino_id": s.UnidadDestinoID,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, s)
+}
+
+// DELETE /api/me/cambio-unidad/:id
+// Cancela una solicitud propia que siga pendiente.
+func (h *PerfilHandler) CancelarCambio(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(
+		ctx,
+		`UPDATE public.solicitudes_cambio_unidad
+		 SET status = 'cancelada'
+		 WHERE id = $1 AND usuario_id = $2 AND status = 'pendiente'`,
+		id,
+		claims.UserID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al cancelar solicitud",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	if res.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "solicitud pendiente no encontrada"})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionSolicitudUnidadCancelar, "solicitud_unidad", id, nil)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// =============================
+// Solicitudes de cambio de unidad (admin)
+// =============================
+
+// GET /api/admin/solicitudes-unidad?status=pendiente
+func (h *PerfilHandler) AdminListCambios(c *gin.Context) {
+	status := strings.TrimSpace(strings.ToLower(c.DefaultQuery("status", "pendiente")))
+
+	query := `SELECT ` + solicitudCols + ` FROM public.solicitudes_cambio_unidad`
+	args := []any{}
+	if status != "todas" {
+		query += ` WHERE status = $1`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at`
+
+	rows, err := h.DB.Query(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar solicitudes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.SolicitudCambioUnidad, 0)
+	for rows.Next() {
+		var s models.SolicitudCambioUnidad
+		if err := scanSolicitud(rows, &s); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer solicitudes",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, s)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar solicitudes",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// POST /api/admin/solicitudes-unidad/:id/aprobar
+// Mueve al usuario a la unidad destino. Las planeaciones ya creadas
+// conservan su unidad_academica_id. El usuario debe volver a iniciar sesión
+// para que su JWT refleje la nueva unidad.
+func (h *PerfilHandler) AdminAprobar(c *gin.Context) {
+	h.resolverCambio(c, "aprobada")
+}
+
+// POST /api/admin/solicitudes-unidad/:id/rechazar
+// Body opcional: { "comentario": "..." }
+func (h *PerfilHandler) AdminRechazar(c *gin.Context) {
+	h.resolverCambio(c, "rechazada")
+}
+
+func (h *PerfilHandler) resolverCambio(c *gin.Context, nuevoStatus string) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	// Body opcional
+	var req resolverCambioRequest
+	_ = c.ShouldBindJSON(&req)
+
+	var comentario *string
+	if s := strings.TrimSpace(req.Comentario); s != "" {
+		comentario = &s
+	}
+
+	ctx := c.Request.Context()
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo iniciar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var s models.SolicitudCambioUnidad
+	if err := scanSolicitud(tx.QueryRow(
+		ctx,
+		`SELECT `+solicitudCols+` FROM public.solicitudes_cambio_unidad WHERE id = $1 FOR UPDATE`,
+		id,
+	), &s); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "solicitud no encontrada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar solicitud",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if s.Status != "pendiente" {
+		c.JSON(http.StatusConflict, gin.H{"error": "la solicitud ya fue resuelta", "status": s.Status})
+		return
+	}
+
+	if nuevoStatus == "aprobada" {
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE public.usuarios SET unidad_id = $2 WHERE id = $1`,
+			s.UsuarioID,
+			s.UnidadDestinoID,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al cambiar unidad del usuario",
+				"msg":   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := scanSolicitud(tx.QueryRow(
+		ctx,
+		`UPDATE public.solicitudes_cambio_unidad
+		 SET status = $2, resuelta_por = $3, resuelta_at = now(), comentario_resolucion = $4
+		 WHERE id = $1
+		 RETURNING `+solicitudCols,
+		id,
+		nuevoStatus,
+		claims.UserID,
+		strOrNil(comentario),
+	), &s); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al resolver solicitud",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	accion := accionSolicitudUnidadRechazar
+	if nuevoStatus == "aprobada" {
+		accion = accionSolicitudUnidadAprobar
+	}
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accion, "solicitud_unidad", s.ID, gin.H{
+		"usuario_id":        s.UsuarioID,
+		"unidad_origen_id":  s.UnidadOrigenID,
+		"unidad_destino_id": s.UnidadDestinoID,
+		"comentario":        comentario,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo registrar auditoría",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "no se pudo confirmar transacción",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
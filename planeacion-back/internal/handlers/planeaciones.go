@@ -27,12 +27,43 @@ type PlaneacionesHandler struct {
 func RegisterPlaneacionesRoutes(rg *gin.RouterGroup, h *PlaneacionesHandler) {
 	g := rg.Group("/planeaciones")
 
-	g.GET("", h.List)       // GET /api/planeaciones
-	g.POST("", h.Create)    // POST /api/planeaciones
-	g.GET("/:id", h.GetOne) // GET /api/planeaciones/:id
-	g.PUT("/:id", h.Update) // PUT /api/planeaciones/:id
-	g.POST("/:id/reabrir", h.Reabrir) // ✅ NUEVO: POST /api/planeaciones/:id/reabrir
-	g.DELETE("/:id", h.Delete)
+	g.GET("", h.List)                                           // GET /api/planeaciones
+	g.POST("", h.Create)                                        // POST /api/planeaciones
+	g.GET("/papelera", h.Papelera)                              // GET /api/planeaciones/papelera
+	g.GET("/:id", h.GetOne)                                     // GET /api/planeaciones/:id
+	g.PUT("/:id", h.Update)                                     // PUT /api/planeaciones/:id
+	g.POST("/:id/reabrir", h.Reabrir)                           // ✅ NUEVO: POST /api/planeaciones/:id/reabrir
+	g.DELETE("/:id", h.Delete)                                  // DELETE /api/planeaciones/:id (a la papelera)
+	g.POST("/:id/restaurar", h.Restaurar)                       // POST /api/planeaciones/:id/restaurar
+	g.POST("/:id/archivar", h.Archivar)                         // POST /api/planeaciones/:id/archivar
+	g.POST("/:id/desarchivar", h.Desarchivar)                   // POST /api/planeaciones/:id/desarchivar
+	g.PUT("/:id/slug", h.PutSlug)                               // PUT /api/planeaciones/:id/slug
+	g.GET("/:id/consistencia", h.Consistencia)                  // GET /api/planeaciones/:id/consistencia
+	g.GET("/:id/calendario", h.Calendario)                      // GET /api/planeaciones/:id/calendario
+	g.GET("/:id/horario", h.GetHorario)                         // GET /api/planeaciones/:id/horario
+	g.PUT("/:id/horario", h.PutHorario)                         // PUT /api/planeaciones/:id/horario
+	g.POST("/:id/programar", h.Programar)                       // POST /api/planeaciones/:id/programar
+	g.GET("/:id/calendar.ics", h.CalendarICS)                   // GET /api/planeaciones/:id/calendar.ics
+	g.GET("/:id/diferencias-plantilla", h.DiferenciasPlantilla) // GET /api/planeaciones/:id/diferencias-plantilla
+
+	// PATCH por sección (combinan con lo guardado)
+	g.PATCH("/:id/datos-generales", h.PatchDatosGenerales)
+	g.PATCH("/:id/relaciones", h.PatchRelaciones)
+	g.PATCH("/:id/organizacion", h.PatchOrganizacion)
+	g.PATCH("/:id/plagio", h.PatchPlagio)
+	g.PATCH("/:id/referencias", h.PatchReferencias)
+
+	// Unidades temáticas y sesiones por número
+	g.GET("/:id/unidades", h.ListUnidades)
+	g.POST("/:id/unidades", h.CreateUnidad)
+	g.POST("/:id/unidades/reorden", h.ReordenarUnidades)
+	g.GET("/:id/unidades/:numero", h.GetUnidad)
+	g.PATCH("/:id/unidades/:numero", h.UpdateUnidad)
+	g.DELETE("/:id/unidades/:numero", h.DeleteUnidad)
+	g.POST("/:id/unidades/:numero/sesiones", h.CreateSesion)
+	g.POST("/:id/unidades/:numero/sesiones/reorden", h.ReordenarSesiones)
+	g.PATCH("/:id/unidades/:numero/sesiones/:n", h.UpdateSesion)
+	g.DELETE("/:id/unidades/:numero/sesiones/:n", h.DeleteSesion)
 }
 
 // Helpers para manejar punteros → NULL en SQL
@@ -64,11 +95,37 @@ func boolOrNil(p *bool) any {
 	return *p
 }
 
+func tipoUnidadOrNil(p *TipoUnidadPayload) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// Fechas "YYYY-MM-DD" (vacío → NULL)
+func dateOrNil(p *string) (any, error) {
+	if p == nil {
+		return nil, nil
+	}
+	s := strings.TrimSpace(*p)
+	if s == "" {
+		return nil, nil
+	}
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 // Payload para referencias
+// Con datos estructurados (titulo, autores, ...) cita_apa se genera en APA 7;
+// sin ellos se guarda la cita libre tal cual.
 type ReferenciaPayload struct {
 	CitaAPA        string  `json:"cita_apa"`
 	UnidadesAplica []int32 `json:"unidades_aplica"`
 	Tipo           string  `json:"tipo"`
+
+	ReferenciaBibliografica
 }
 
 // >>> Payloads para unidades temáticas y sesiones (bloques)
@@ -89,6 +146,20 @@ type SesionBloquePayload struct {
 	Evidencias      []string           `json:"evidencias"`
 	Instrumentos    []string           `json:"instrumentos"`
 	ValorPorcentual int                `json:"valor_porcentual"`
+
+	ProgramacionSesion // fecha/horario (opcional; ver /programar)
+}
+
+// Tipo de unidad de aprendizaje (casillas del formato oficial)
+type TipoUnidadPayload struct {
+	Teorica         bool `json:"teorica"`
+	Practica        bool `json:"practica"`
+	TeoricaPractica bool `json:"teorica_practica"`
+	Clinica         bool `json:"clinica"`
+	Otro            bool `json:"otro"`
+	Obligatoria     bool `json:"obligatoria"`
+	Optativa        bool `json:"optativa"`
+	TopicosSelectos bool `json:"topicos_selectos"`
 }
 
 // Periodo de desarrollo de la unidad
@@ -137,6 +208,13 @@ type UnidadTematicaPayload struct {
 // =============================
 
 func getClaimsFromHeader(c *gin.Context) (*PlaneacionClaims, error) {
+	// 0) Si AuthMiddleware ya resolvió la identidad (JWT o token personal), reutilizarla
+	if v, ok := c.Get("claims"); ok {
+		if claims, ok := v.(*PlaneacionClaims); ok && claims != nil {
+			return claims, nil
+		}
+	}
+
 	// 1) Preferir Authorization: Bearer
 	authHeader := c.GetHeader("Authorization")
 	tokenStr := ""
@@ -178,25 +256,43 @@ func getClaimsFromHeader(c *gin.Context) (*PlaneacionClaims, error) {
 	return claims, nil
 }
 
-
 // =============================
 // Slug helpers (para URL pública)
 // =============================
 
 var reSlug = regexp.MustCompile(`[^a-z0-9]+`)
 
+// slugify translitera acentos ("Programación" -> "programacion") y deja
+// solo [a-z0-9] separados por guiones.
 func slugify(s string) string {
-	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ToLower(quitarAcentos(strings.TrimSpace(s)))
 	s = reSlug.ReplaceAllString(s, "-")
 	s = strings.Trim(s, "-")
 	return s
 }
 
 // =============================
-// GET /api/planeaciones
-// Lista SOLO las planeaciones del docente autenticado
+// GET /api/planeaciones?status=&periodo=&asignatura=&q=&sort=recientes|creadas|nombre&limit=20&cursor=...
+// Lista SOLO las planeaciones del docente autenticado, con lo que necesita
+// cada tarjeta del tablero (progreso, conteos, slug) para no pedir GetOne.
+// - status: uno o varios separados por coma; sin status se omiten las
+//   archivadas (?status=archivada para verlas)
+// - periodo: clave exacta (sin distinguir mayúsculas); asignatura y q: texto parcial
+// - Paginación keyset: usar next_cursor de la respuesta anterior; sin limit ni
+//   cursor devuelve todas (el tablero aún no pagina) y "limit" sale null
 // =============================
 
+// statusPlaneacion: valores de public.planeacion_status
+var statusPlaneacion = map[string]bool{
+	"borrador":    true,
+	"en_progreso": true,
+	"finalizada":  true,
+	"archivada":   true,
+}
+
+// Secciones que cuentan para el progreso (llaves de secciones_completas)
+var seccionesPlaneacion = []string{"datos", "relaciones", "organizacion", "plagio", "referencias"}
+
 func (h *PlaneacionesHandler) List(c *gin.Context) {
 	claims, err := getClaimsFromHeader(c)
 	if err != nil {
@@ -204,15 +300,121 @@ func (h *PlaneacionesHandler) List(c *gin.Context) {
 		return
 	}
 
+	sort := strings.TrimSpace(strings.ToLower(c.DefaultQuery("sort", "recientes")))
+	if sort != "recientes" && sort != "creadas" && sort != "nombre" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort inválido (usa 'recientes', 'creadas' o 'nombre')"})
+		return
+	}
+
+	limit := 0 // 0 = sin límite
+	if v := strings.TrimSpace(c.Query("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 100"})
+			return
+		}
+		limit = n
+	}
+
+	var cursor *catalogoCursor
+	if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+		cur, err := decodeCatalogoCursor(v)
+		if err != nil || cur.Sort != sort || (sort != "nombre" && cur.Fecha == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor inválido"})
+			return
+		}
+		cursor = cur
+		if limit == 0 {
+			limit = 20
+		}
+	}
+
+	args := &sqlArgs{}
+	where := []string{"p.docente_id = " + args.add(claims.UserID), "p.deleted_at IS NULL"}
+
+	if estados := queryList(c, "status"); len(estados) > 0 {
+		for i, s := range estados {
+			estados[i] = strings.ToLower(s)
+			if !statusPlaneacion[estados[i]] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "status inválido: " + s})
+				return
+			}
+		}
+		where = append(where, "p.status::text = ANY("+args.add(estados)+")")
+	} else {
+		where = append(where, "p.status <> 'archivada'")
+	}
+	if v := strings.TrimSpace(c.Query("periodo")); v != "" {
+		where = append(where, "lower(btrim(p.periodo)) = lower("+args.add(v)+")")
+	}
+	if v := strings.TrimSpace(c.Query("asignatura")); v != "" {
+		where = append(where, "COALESCE(p.asignatura,'') ILIKE "+args.add("%"+v+"%"))
+	}
+	if v := strings.TrimSpace(c.Query("q")); v != "" {
+		n := args.add("%" + v + "%")
+		where = append(where, "(p.nombre_planeacion ILIKE "+n+
+			" OR COALESCE(p.asignatura,'') ILIKE "+n+
+			" OR COALESCE(p.grupo,'') ILIKE "+n+
+			" OR COALESCE(dg.grupos,'') ILIKE "+n+
+			" OR COALESCE(dg.programa_academico,'') ILIKE "+n+")")
+	}
+
+	const from = `
+FROM planeaciones p
+LEFT JOIN planeacion_datos_generales dg ON dg.planeacion_id = p.id
+`
+
+	// Total con los filtros (sin el cursor)
+	var total int64
+	if err := h.DB.QueryRow(
+		c,
+		`SELECT COUNT(*) `+from+` WHERE `+strings.Join(where, " AND "),
+		args.vals...,
+	).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	var fechaExpr, orderBy string
+	switch sort {
+	case "nombre":
+		orderBy = "lower(p.nombre_planeacion) ASC, p.id ASC"
+		if cursor != nil {
+			where = append(where, "(lower(p.nombre_planeacion), p.id) > ("+args.add(cursor.Nombre)+", "+args.add(cursor.ID)+")")
+		}
+	default:
+		fechaExpr = "p.updated_at"
+		if sort == "creadas" {
+			fechaExpr = "p.created_at"
+		}
+		orderBy = fechaExpr + " DESC, p.id DESC"
+		if cursor != nil {
+			where = append(where, "("+fechaExpr+", p.id) < ("+args.add(*cursor.Fecha)+", "+args.add(cursor.ID)+")")
+		}
+	}
+
+	// limit+1 para saber si hay otra página
+	limitSQL := ""
+	if limit > 0 {
+		limitSQL = "\nLIMIT " + args.add(limit+1)
+	}
 	rows, err := h.DB.Query(
 		c,
 		`
-		SELECT id, docente_id, unidad_academica_id, nombre_planeacion, status, created_at, updated_at
-		FROM planeaciones
-		WHERE docente_id = $1
-		ORDER BY created_at DESC
-		`,
-		claims.UserID,
+SELECT
+  p.id, p.docente_id, p.unidad_academica_id, p.nombre_planeacion, p.status::text,
+  p.asignatura, p.periodo, p.grupo, dg.grupos, p.slug,
+  p.created_at, p.updated_at, p.finalizada_at, p.archivada_at,
+  COALESCE(p.secciones_completas, '{}'::jsonb),
+  (SELECT COUNT(*) FROM unidades_tematicas ut WHERE ut.planeacion_id = p.id),
+  (SELECT COUNT(*) FROM sesiones_didacticas sd
+     JOIN unidades_tematicas ut ON ut.id = sd.unidad_tematica_id
+    WHERE ut.planeacion_id = p.id),
+  lower(p.nombre_planeacion) AS nombre_key
+`+from+`
+WHERE `+strings.Join(where, " AND ")+`
+ORDER BY `+orderBy+limitSQL,
+		args.vals...,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
@@ -221,28 +423,80 @@ func (h *PlaneacionesHandler) List(c *gin.Context) {
 	defer rows.Close()
 
 	list := []gin.H{}
+	var last catalogoCursor
+	hasMore := false
 
 	for rows.Next() {
 		var (
-			id, docenteID, unidadID int64
-			nombre, status          string
-			created, updated        time.Time
+			id, docenteID, unidadID    int64
+			nombre, status, nombreKey  string
+			asignatura, periodo, grupo *string
+			grupos, slug               *string
+			created, updated           time.Time
+			finalizadaAt, archivadaAt  *time.Time
+			secciones                  map[string]any
+			unidades, sesiones         int64
 		)
 
-		if err := rows.Scan(&id, &docenteID, &unidadID, &nombre, &status, &created, &updated); err != nil {
+		if err := rows.Scan(
+			&id, &docenteID, &unidadID, &nombre, &status,
+			&asignatura, &periodo, &grupo, &grupos, &slug,
+			&created, &updated, &finalizadaAt, &archivadaAt,
+			&secciones, &unidades, &sesiones, &nombreKey,
+		); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo filas: " + err.Error()})
 			return
 		}
 
+		if limit > 0 && len(list) == limit {
+			// fila extra: solo indica que hay otra página
+			hasMore = true
+			continue
+		}
+
+		completas := 0
+		for _, s := range seccionesPlaneacion {
+			if v, ok := secciones[s].(bool); ok && v {
+				completas++
+			}
+		}
+
 		list = append(list, gin.H{
 			"id":                  id,
 			"docente_id":          docenteID,
 			"unidad_academica_id": unidadID,
 			"nombre_planeacion":   nombre,
 			"status":              status,
+			"asignatura":          asignatura,
+			"periodo":             periodo,
+			"grupo":               grupo,
+			"grupos":              grupos,
+			"slug":                slug,
 			"created_at":          created,
 			"updated_at":          updated,
+			"finalizada_at":       finalizadaAt,
+			"archivada_at":        archivadaAt,
+			"secciones_completas": secciones,
+			"progreso": gin.H{
+				"completas":  completas,
+				"total":      len(seccionesPlaneacion),
+				"porcentaje": completas * 100 / len(seccionesPlaneacion),
+			},
+			"unidades_tematicas":  unidades,
+			"sesiones_didacticas": sesiones,
 		})
+
+		last = catalogoCursor{Sort: sort, ID: id}
+		switch sort {
+		case "nombre":
+			last.Nombre = nombreKey
+		case "creadas":
+			f := created
+			last.Fecha = &f
+		default:
+			f := updated
+			last.Fecha = &f
+		}
 	}
 
 	if err := rows.Err(); err != nil {
@@ -250,7 +504,23 @@ func (h *PlaneacionesHandler) List(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, list)
+	var nextCursor *string
+	if hasMore {
+		s := encodeCatalogoCursor(last)
+		nextCursor = &s
+	}
+
+	var limitResp *int
+	if limit > 0 {
+		limitResp = &limit
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":       list,
+		"total":       total,
+		"limit":       limitResp,
+		"next_cursor": nextCursor,
+	})
 }
 
 // =============================
@@ -261,6 +531,7 @@ func (h *PlaneacionesHandler) List(c *gin.Context) {
 
 type createPlaneacionRequest struct {
 	NombrePlaneacion string `json:"nombre_planeacion"`
+	PlantillaID      *int64 `json:"plantilla_id"` // opcional: siembra desde un programa sintético
 }
 
 func (h *PlaneacionesHandler) Create(c *gin.Context) {
@@ -271,8 +542,7 @@ func (h *PlaneacionesHandler) Create(c *gin.Context) {
 	}
 
 	var body createPlaneacionRequest
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido"})
+	if _, ok := decodeJSONBody(c, &body); !ok {
 		return
 	}
 
@@ -281,8 +551,15 @@ func (h *PlaneacionesHandler) Create(c *gin.Context) {
 		name = "Planeación sin título"
 	}
 
+	tx, err := h.DB.Begin(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
 	var newID int64
-	err = h.DB.QueryRow(
+	err = tx.QueryRow(
 		c,
 		`
 		INSERT INTO planeaciones (docente_id, unidad_academica_id, nombre_planeacion)
@@ -301,6 +578,36 @@ func (h *PlaneacionesHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if body.PlantillaID != nil {
+		nombrePlantilla, err := aplicarPlantilla(c, tx, int(newID), *body.PlantillaID, claims.UnidadID)
+		if err != nil {
+			if !respondValidacionError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo aplicar plantilla: " + err.Error()})
+			}
+			return
+		}
+		// Sin nombre explícito, la planeación toma el de la plantilla
+		if strings.TrimSpace(body.NombrePlaneacion) == "" {
+			if _, err := tx.Exec(c, `UPDATE planeaciones SET nombre_planeacion = $2 WHERE id = $1`, newID, nombrePlantilla); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+				return
+			}
+		}
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionCrear, "planeacion", newID, gin.H{
+		"nombre_planeacion": name,
+		"plantilla_id":      body.PlantillaID,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear planeación: " + err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"id": newID})
 }
 
@@ -390,7 +697,20 @@ SELECT json_build_object(
           'id', r.id,
           'cita_apa', r.cita_apa,
           'unidades_aplica', r.unidades_aplica,
-          'tipo', r.tipo
+          'tipo', r.tipo,
+          'tipo_fuente', r.tipo_fuente,
+          'autores', r.autores,
+          'anio', r.anio,
+          'titulo', r.titulo,
+          'fuente', r.fuente,
+          'editorial', r.editorial,
+          'edicion', r.edicion,
+          'volumen', r.volumen,
+          'numero', r.numero,
+          'paginas', r.paginas,
+          'doi', r.doi,
+          'isbn', r.isbn,
+          'url', r.url
         )
         ORDER BY r.id
       )
@@ -447,7 +767,11 @@ SELECT json_build_object(
                   'recursos', sd.recursos,
                   'evidencias', sd.evidencias,
                   'instrumentos', sd.instrumentos,
-                  'valor_porcentual', sd.valor_porcentual
+                  'valor_porcentual', sd.valor_porcentual,
+                  'fecha', sd.fecha,
+                  'hora_inicio', to_char(sd.hora_inicio, 'HH24:MI'),
+                  'hora_fin', to_char(sd.hora_fin, 'HH24:MI'),
+                  'espacio', sd.espacio
                 )
                 ORDER BY sd.numero_sesion
               )
@@ -464,13 +788,24 @@ SELECT json_build_object(
     ),
     '[]'::json
   )
+)::jsonb || jsonb_build_object(
+  -- Datos generales (json_build_object admite máx. 100 argumentos)
+  'fecha_elaboracion', dg.fecha_elaboracion,
+  'tipo_unidad', dg.tipo_unidad,
+  'semanas_por_semestre', dg.semanas_por_semestre,
+  'docente_autor', dg.docente_autor,
+  'programa_id', p.programa_id,
+  'academia_id', p.academia_id,
+  'unidad_aprendizaje_id', p.unidad_aprendizaje_id,
+  'plantilla_id', p.plantilla_id,
+  'plantilla_aplicada_at', p.plantilla_aplicada_at
 )
 FROM planeaciones p
 LEFT JOIN planeacion_datos_generales dg ON dg.planeacion_id = p.id
 LEFT JOIN planeacion_relaciones_ejes re ON re.planeacion_id = p.id
 LEFT JOIN planeacion_organizacion org ON org.planeacion_id = p.id
 LEFT JOIN planeacion_plagio pl ON pl.planeacion_id = p.id
-WHERE p.id = $1 AND p.docente_id = $2
+WHERE p.id = $1 AND p.docente_id = $2 AND p.deleted_at IS NULL
 		`,
 		id,
 		claims.UserID,
@@ -527,7 +862,7 @@ func (h *PlaneacionesHandler) Reabrir(c *gin.Context) {
 	var status string
 	err = tx.QueryRow(
 		c,
-		`SELECT status FROM planeaciones WHERE id = $1 AND docente_id = $2`,
+		`SELECT status FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL FOR UPDATE`,
 		id,
 		claims.UserID,
 	).Scan(&status)
@@ -547,7 +882,13 @@ func (h *PlaneacionesHandler) Reabrir(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"ok": true, "status": "borrador"})
 			return
 		}
-		// si existieran otros estados a futuro:
+		if status == "archivada" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "La planeación está archivada.",
+				"hint":  "POST /api/planeaciones/:id/desarchivar",
+			})
+			return
+		}
 		c.JSON(http.StatusConflict, gin.H{"error": "La planeación no está finalizada."})
 		return
 	}
@@ -570,10 +911,18 @@ WHERE id = $1 AND docente_id = $2
 		return
 	}
 
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionReabrir, "planeacion", int64(id), gin.H{
+		"status": gin.H{"de": "finalizada", "a": "borrador"},
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
 	if err := tx.Commit(c); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
 		return
 	}
+	invalidarCachePublica() // deja de ser pública
 
 	c.JSON(http.StatusOK, gin.H{"ok": true, "status": "borrador"})
 }
@@ -581,53 +930,17 @@ WHERE id = $1 AND docente_id = $2
 // =============================
 // PUT /api/planeaciones/:id
 // Actualiza campos de planeaciones + tablas por sección
+// Campos desconocidos → "advertencias" en la respuesta (?strict=1 los rechaza)
 // =============================
 
 type updatePlaneacionRequest struct {
-	NombrePlaneacion        *string `json:"nombre_planeacion"`
-	Status                  *string `json:"status"`
-	PeriodoEscolar          *string `json:"periodo_escolar"`
-	PlanEstudiosAnio        *int    `json:"plan_estudios_anio"`
-	SemestreNivel           *string `json:"semestre_nivel"`
-	Grupos                  *string `json:"grupos"`
-	ProgramaAcademico       *string `json:"programa_academico"`
-	Academia                *string `json:"academia"`
-	UnidadAprendizajeNombre *string `json:"unidad_aprendizaje_nombre"`
-	AreaFormacion           *string `json:"area_formacion"`
-	Modalidad               *string `json:"modalidad"`
-
-	SesionesPorSemestre *int `json:"sesiones_por_semestre"`
-	SesionesAula        *int `json:"sesiones_aula"`
-	SesionesLaboratorio *int `json:"sesiones_laboratorio"`
-	SesionesClinica     *int `json:"sesiones_clinica"`
-	SesionesOtro        *int `json:"sesiones_otro"`
-
-	HorasTeoria      *float64 `json:"horas_teoria"`
-	HorasPractica    *float64 `json:"horas_practica"`
-	HorasAula        *float64 `json:"horas_aula"`
-	HorasLaboratorio *float64 `json:"horas_laboratorio"`
-	HorasClinica     *float64 `json:"horas_clinica"`
-	HorasOtro        *float64 `json:"horas_otro"`
-	HorasTotal       *float64 `json:"horas_total"`
-
-	CreditosTepic *float64 `json:"creditos_tepic"`
-	CreditosSatca *float64 `json:"creditos_satca"`
-
-	Antecedentes *string `json:"antecedentes"`
-	Laterales    *string `json:"laterales"`
-	Subsecuentes *string `json:"subsecuentes"`
-
-	EjesCompromiso           *string `json:"ejes_compromiso_social_sustentabilidad"`
-	EjesPerspectivaGenero    *string `json:"ejes_perspectiva_genero"`
-	EjesInternacionalizacion *string `json:"ejes_internacionalizacion"`
-
-	OrgProposito  *string `json:"org_proposito"`
-	OrgEstrategia *string `json:"org_estrategia"`
-	OrgMetodos    *string `json:"org_metodos"`
-
-	PlagioIthenticate *bool   `json:"plagio_ithenticate"`
-	PlagioTurnitin    *bool   `json:"plagio_turnitin"`
-	PlagioOtro        *string `json:"plagio_otro"`
+	NombrePlaneacion *string `json:"nombre_planeacion"`
+	Status           *string `json:"status"`
+
+	DatosGeneralesPayload
+	RelacionesPayload
+	OrganizacionPayload
+	PlagioPayload
 
 	Referencias       *[]ReferenciaPayload     `json:"referencias"`
 	UnidadesTematicas *[]UnidadTematicaPayload `json:"unidades_tematicas"`
@@ -647,8 +960,20 @@ func (h *PlaneacionesHandler) Update(c *gin.Context) {
 	}
 
 	var body updatePlaneacionRequest
-	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido"})
+	advertencias, ok := decodeJSONBody(c, &body)
+	if !ok {
+		return
+	}
+
+	if err := body.DatosGeneralesPayload.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.Status != nil && strings.TrimSpace(*body.Status) == "archivada" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Para archivar usa el endpoint dedicado.",
+			"hint":  "POST /api/planeaciones/:id/archivar",
+		})
 		return
 	}
 
@@ -660,32 +985,7 @@ func (h *PlaneacionesHandler) Update(c *gin.Context) {
 	defer tx.Rollback(c)
 
 	// ✅ existencia + status actual (para bloquear edición si está finalizada)
-	var currentStatus string
-	err = tx.QueryRow(
-		c,
-		`SELECT status FROM planeaciones WHERE id = $1 AND docente_id = $2`,
-		id,
-		claims.UserID,
-	).Scan(&currentStatus)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando planeación: " + err.Error()})
-		return
-	}
-
-	cur := strings.TrimSpace(strings.ToLower(currentStatus))
-	if cur == "finalizada" {
-		// Si está finalizada, NO se permite editar vía PUT
-		// (reabrir primero con POST /:id/reabrir)
-		// Permitimos explícitamente que el cliente intente cambiar status a borrador,
-		// pero lo dejamos “limpio” forzando a usar /reabrir.
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "Planeación finalizada. Para editar debes reabrirla primero.",
-			"hint":  "POST /api/planeaciones/:id/reabrir",
-		})
+	if !lockPlaneacionEditable(c, tx, id, claims.UserID) {
 		return
 	}
 
@@ -696,17 +996,11 @@ UPDATE planeaciones
 SET
   nombre_planeacion = COALESCE($1, nombre_planeacion),
   status            = COALESCE($2, status),
-  asignatura        = COALESCE($3, asignatura),
-  periodo           = COALESCE($4, periodo),
-  grupo             = COALESCE($5, grupo),
   updated_at        = now()
-WHERE id = $6 AND docente_id = $7
+WHERE id = $3 AND docente_id = $4
 		`,
 		strOrNil(body.NombrePlaneacion),
 		strOrNil(body.Status),
-		strOrNil(body.UnidadAprendizajeNombre),
-		strOrNil(body.PeriodoEscolar),
-		strOrNil(body.Grupos),
 		id,
 		claims.UserID,
 	)
@@ -715,6 +1009,40 @@ WHERE id = $6 AND docente_id = $7
 		return
 	}
 
+	// Secciones 1:1 (PUT = documento completo: lo omitido queda en NULL)
+	if err := guardarDatosGenerales(c, tx, id, &body.DatosGeneralesPayload, nil); err != nil {
+		if respondValidacionError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar datos generales: " + err.Error()})
+		return
+	}
+	if err := guardarSeccion(c, tx, "planeacion_relaciones_ejes", id, body.RelacionesPayload.columnas(), nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar relaciones/ejes: " + err.Error()})
+		return
+	}
+	if err := guardarSeccion(c, tx, "planeacion_organizacion", id, body.OrganizacionPayload.columnas(), nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar organización: " + err.Error()})
+		return
+	}
+	if err := guardarSeccion(c, tx, "planeacion_plagio", id, body.PlagioPayload.columnas(), nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar plagio: " + err.Error()})
+		return
+	}
+
+	// ─────────────────────────────
+	// Referencias (reemplazar todas)
+	// ─────────────────────────────
+	if body.Referencias != nil {
+		if err := reemplazarReferencias(c, tx, id, *body.Referencias); err != nil {
+			if respondValidacionError(c, err) {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron guardar referencias: " + err.Error()})
+			return
+		}
+	}
+
 	// ==========================================================
 	// ✅ SLUG + finalizada_at (NUEVO, no rompe lo existente)
 	// - Solo si el cliente manda status="finalizada"
@@ -750,8 +1078,11 @@ WHERE id = $6 AND docente_id = $7
 			if asignatura != nil {
 				asig = strings.TrimSpace(*asignatura)
 			}
-			base := strings.TrimSpace(nombre)
-			newSlug := slugify(base + "-" + asig + "-" + strconv.Itoa(id))
+			newSlug, err := generarSlug(c, tx, id, nombre, asig)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar slug: " + err.Error()})
+				return
+			}
 
 			_, err = tx.Exec(
 				c,
@@ -791,321 +1122,6 @@ WHERE id = $6 AND docente_id = $7
 		}
 	}
 
-	cmd, err := tx.Exec(
-		c,
-		`
-UPDATE planeacion_datos_generales
-SET
-  periodo               = $2,
-  plan_estudios_anio    = $3,
-  semestre_nivel        = $4,
-  grupos                = $5,
-  programa_academico    = $6,
-  academia              = $7,
-  area_formacion        = $8,
-  modalidad             = $9,
-  sesiones_por_semestre = $10,
-  sesiones_aula         = $11,
-  sesiones_laboratorio  = $12,
-  sesiones_clinica      = $13,
-  sesiones_otro         = $14,
-  horas_teoria          = $15,
-  horas_practica        = $16,
-  horas_aula            = $17,
-  horas_laboratorio     = $18,
-  horas_clinica         = $19,
-  horas_otro            = $20,
-  horas_total           = $21,
-  creditos_tepic        = $22,
-  creditos_satca        = $23,
-  updated_at            = now()
-WHERE planeacion_id = $1
-		`,
-		id,
-		strOrNil(body.PeriodoEscolar),
-		intOrNil(body.PlanEstudiosAnio),
-		strOrNil(body.SemestreNivel),
-		strOrNil(body.Grupos),
-		strOrNil(body.ProgramaAcademico),
-		strOrNil(body.Academia),
-		strOrNil(body.AreaFormacion),
-		strOrNil(body.Modalidad),
-		intOrNil(body.SesionesPorSemestre),
-		intOrNil(body.SesionesAula),
-		intOrNil(body.SesionesLaboratorio),
-		intOrNil(body.SesionesClinica),
-		intOrNil(body.SesionesOtro),
-		floatOrNil(body.HorasTeoria),
-		floatOrNil(body.HorasPractica),
-		floatOrNil(body.HorasAula),
-		floatOrNil(body.HorasLaboratorio),
-		floatOrNil(body.HorasClinica),
-		floatOrNil(body.HorasOtro),
-		floatOrNil(body.HorasTotal),
-		floatOrNil(body.CreditosTepic),
-		floatOrNil(body.CreditosSatca),
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar datos generales: " + err.Error()})
-		return
-	}
-	if cmd.RowsAffected() == 0 {
-		_, err = tx.Exec(
-			c,
-			`
-INSERT INTO planeacion_datos_generales (
-  planeacion_id,
-  periodo,
-  plan_estudios_anio,
-  semestre_nivel,
-  grupos,
-  programa_academico,
-  academia,
-  area_formacion,
-  modalidad,
-  sesiones_por_semestre,
-  sesiones_aula,
-  sesiones_laboratorio,
-  sesiones_clinica,
-  sesiones_otro,
-  horas_teoria,
-  horas_practica,
-  horas_aula,
-  horas_laboratorio,
-  horas_clinica,
-  horas_otro,
-  horas_total,
-  creditos_tepic,
-  creditos_satca
-) VALUES (
-  $1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23
-)
-			`,
-			id,
-			strOrNil(body.PeriodoEscolar),
-			intOrNil(body.PlanEstudiosAnio),
-			strOrNil(body.SemestreNivel),
-			strOrNil(body.Grupos),
-			strOrNil(body.ProgramaAcademico),
-			strOrNil(body.Academia),
-			strOrNil(body.AreaFormacion),
-			strOrNil(body.Modalidad),
-			intOrNil(body.SesionesPorSemestre),
-			intOrNil(body.SesionesAula),
-			intOrNil(body.SesionesLaboratorio),
-			intOrNil(body.SesionesClinica),
-			intOrNil(body.SesionesOtro),
-			floatOrNil(body.HorasTeoria),
-			floatOrNil(body.HorasPractica),
-			floatOrNil(body.HorasAula),
-			floatOrNil(body.HorasLaboratorio),
-			floatOrNil(body.HorasClinica),
-			floatOrNil(body.HorasOtro),
-			floatOrNil(body.HorasTotal),
-			floatOrNil(body.CreditosTepic),
-			floatOrNil(body.CreditosSatca),
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo insertar datos generales: " + err.Error()})
-			return
-		}
-	}
-
-	cmd, err = tx.Exec(
-		c,
-		`
-UPDATE planeacion_relaciones_ejes
-SET
-  antecedentes                           = $2,
-  laterales                              = $3,
-  subsecuentes                           = $4,
-  ejes_compromiso_social_sustentabilidad = $5,
-  ejes_perspectiva_genero                = $6,
-  ejes_internacionalizacion              = $7,
-  updated_at                             = now()
-WHERE planeacion_id = $1
-		`,
-		id,
-		strOrNil(body.Antecedentes),
-		strOrNil(body.Laterales),
-		strOrNil(body.Subsecuentes),
-		strOrNil(body.EjesCompromiso),
-		strOrNil(body.EjesPerspectivaGenero),
-		strOrNil(body.EjesInternacionalizacion),
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar relaciones/ejes: " + err.Error()})
-		return
-	}
-	if cmd.RowsAffected() == 0 {
-		_, err = tx.Exec(
-			c,
-			`
-INSERT INTO planeacion_relaciones_ejes (
-  planeacion_id,
-  antecedentes,
-  laterales,
-  subsecuentes,
-  ejes_compromiso_social_sustentabilidad,
-  ejes_perspectiva_genero,
-  ejes_internacionalizacion
-) VALUES (
-  $1,$2,$3,$4,$5,$6,$7
-)
-			`,
-			id,
-			strOrNil(body.Antecedentes),
-			strOrNil(body.Laterales),
-			strOrNil(body.Subsecuentes),
-			strOrNil(body.EjesCompromiso),
-			strOrNil(body.EjesPerspectivaGenero),
-			strOrNil(body.EjesInternacionalizacion),
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo insertar relaciones/ejes: " + err.Error()})
-			return
-		}
-	}
-
-	cmd, err = tx.Exec(
-		c,
-		`
-UPDATE planeacion_organizacion
-SET
-  proposito = $2,
-  estrategia = $3,
-  metodos = $4,
-  updated_at = now()
-WHERE planeacion_id = $1
-		`,
-		id,
-		strOrNil(body.OrgProposito),
-		strOrNil(body.OrgEstrategia),
-		strOrNil(body.OrgMetodos),
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar organización: " + err.Error()})
-		return
-	}
-	if cmd.RowsAffected() == 0 {
-		_, err = tx.Exec(
-			c,
-			`
-INSERT INTO planeacion_organizacion (
-  planeacion_id,
-  proposito,
-  estrategia,
-  metodos
-) VALUES (
-  $1,$2,$3,$4
-)
-			`,
-			id,
-			strOrNil(body.OrgProposito),
-			strOrNil(body.OrgEstrategia),
-			strOrNil(body.OrgMetodos),
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo insertar organización: " + err.Error()})
-			return
-		}
-	}
-
-	cmd, err = tx.Exec(
-		c,
-		`
-UPDATE planeacion_plagio
-SET
-  ithenticate = COALESCE($2, ithenticate),
-  turnitin    = COALESCE($3, turnitin),
-  otro        = $4,
-  updated_at  = now()
-WHERE planeacion_id = $1
-		`,
-		id,
-		boolOrNil(body.PlagioIthenticate),
-		boolOrNil(body.PlagioTurnitin),
-		strOrNil(body.PlagioOtro),
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar plagio: " + err.Error()})
-		return
-	}
-	if cmd.RowsAffected() == 0 {
-		_, err = tx.Exec(
-			c,
-			`
-INSERT INTO planeacion_plagio (
-  planeacion_id,
-  ithenticate,
-  turnitin,
-  otro
-) VALUES (
-  $1, COALESCE($2,false), COALESCE($3,false), $4
-)
-			`,
-			id,
-			boolOrNil(body.PlagioIthenticate),
-			boolOrNil(body.PlagioTurnitin),
-			strOrNil(body.PlagioOtro),
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo insertar plagio: " + err.Error()})
-			return
-		}
-	}
-
-	// ─────────────────────────────
-	// Referencias (reemplazar todas)
-	// ─────────────────────────────
-	if body.Referencias != nil {
-		_, err = tx.Exec(
-			c,
-			`DELETE FROM planeacion_referencias WHERE planeacion_id = $1`,
-			id,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "No se pudieron limpiar referencias: " + err.Error(),
-			})
-			return
-		}
-
-		for _, ref := range *body.Referencias {
-			cita := strings.TrimSpace(ref.CitaAPA)
-			if cita == "" {
-				continue
-			}
-
-			tipo := strings.TrimSpace(ref.Tipo)
-			if tipo == "" {
-				tipo = "Básica"
-			}
-
-			_, err = tx.Exec(
-				c,
-				`
-INSERT INTO planeacion_referencias (
-  planeacion_id,
-  cita_apa,
-  unidades_aplica,
-  tipo
-) VALUES ($1, $2, $3, $4)
-				`,
-				id,
-				cita,
-				ref.UnidadesAplica,
-				tipo,
-			)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "No se pudo insertar referencia: " + err.Error(),
-				})
-				return
-			}
-		}
-	}
-
 	// ─────────────────────────────
 	// >>> Unidades temáticas + sesiones didácticas
 	// ─────────────────────────────
@@ -1119,23 +1135,9 @@ INSERT INTO planeacion_referencias (
 			return
 		}
 
-		for _, ut := range uts {
-			sumPct := 0
-			for _, b := range ut.Bloques {
-				if b.ValorPorcentual < 0 {
-					c.JSON(http.StatusBadRequest, gin.H{
-						"error": "El valor porcentual de una sesión no puede ser negativo.",
-					})
-					return
-				}
-				sumPct += b.ValorPorcentual
-			}
-			if sumPct > 100 {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "La suma de valores porcentuales de las sesiones de una unidad no debe exceder 100.",
-				})
-				return
-			}
+		if err := validarUnidadesTematicas(uts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
 		_, err = tx.Exec(
@@ -1151,154 +1153,96 @@ INSERT INTO planeacion_referencias (
 		}
 
 		for _, ut := range uts {
-			var perDel, perAl *string
-			if ut.PeriodoDesarrollo.Del != nil {
-				s := strings.TrimSpace(*ut.PeriodoDesarrollo.Del)
-				if s != "" {
-					perDel = &s
-				}
-			}
-			if ut.PeriodoDesarrollo.Al != nil {
-				s := strings.TrimSpace(*ut.PeriodoDesarrollo.Al)
-				if s != "" {
-					perAl = &s
-				}
-			}
-
-			sumPct := 0
-			for _, b := range ut.Bloques {
-				sumPct += b.ValorPorcentual
-			}
-			var porc *int
-			if ut.Porcentaje != nil {
-				porc = ut.Porcentaje
-			} else {
-				porc = &sumPct
-			}
-
-			var unidadID int64
-			err = tx.QueryRow(
-				c,
-				`
-INSERT INTO unidades_tematicas (
-  planeacion_id,
-  numero,
-  nombre_unidad_tematica,
-  unidad_competencia,
-  periodo_del,
-  periodo_al,
-  horas_aula,
-  horas_laboratorio,
-  horas_taller,
-  horas_clinica,
-  horas_otro,
-  sesiones_aula,
-  sesiones_laboratorio,
-  sesiones_taller,
-  sesiones_clinica,
-  sesiones_otro,
-  sesiones_totales,
-  porcentaje,
-  periodo_registro_eval,
-  aprendizajes_esperados,
-  precisiones
-) VALUES (
-  $1,
-  $2,
-  $3,
-  $4,
-  $5,
-  $6,
-  $7,$8,$9,$10,$11,
-  $12,$13,$14,$15,$16,
-  $17,
-  $18,
-  $19,
-  $20,
-  $21
-)
-RETURNING id
-				`,
-				id,
-				ut.Numero,
-				strings.TrimSpace(ut.NombreUnidadTematica),
-				strings.TrimSpace(ut.UnidadCompetencia),
-				perDel,
-				perAl,
-				floatOrNil(ut.Horas.Aula),
-				floatOrNil(ut.Horas.Laboratorio),
-				floatOrNil(ut.Horas.Taller),
-				floatOrNil(ut.Horas.Clinica),
-				floatOrNil(ut.Horas.Otro),
-				intOrNil(ut.SesionesPorEspacio.Aula),
-				intOrNil(ut.SesionesPorEspacio.Laboratorio),
-				intOrNil(ut.SesionesPorEspacio.Taller),
-				intOrNil(ut.SesionesPorEspacio.Clinica),
-				intOrNil(ut.SesionesPorEspacio.Otro),
-				intOrNil(ut.SesionesTotales),
-				intOrNil(porc),
-				strOrNil(ut.PeriodoRegistroEval),
-				ut.AprendizajesEsperados,
-				strOrNil(ut.Precisiones),
-			).Scan(&unidadID)
-			if err != nil {
+			if _, err := insertarUnidadTematica(c, tx, id, ut); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error": "No se pudo insertar unidad temática: " + err.Error(),
 				})
 				return
 			}
+		}
+	}
 
-			for _, b := range ut.Bloques {
-				_, err = tx.Exec(
-					c,
-					`
-INSERT INTO sesiones_didacticas (
-  unidad_tematica_id,
-  numero_sesion,
-  temas_subtemas,
-  actividades_inicio,
-  actividades_desarrollo,
-  actividades_cierre,
-  recursos,
-  evidencias,
-  instrumentos,
-  valor_porcentual
-) VALUES (
-  $1,$2,$3,$4,$5,$6,$7,$8,$9,$10
-)
-					`,
-					unidadID,
-					b.NumeroSesion,
-					strings.TrimSpace(b.TemasSubtemas),
-					strings.TrimSpace(b.Actividades.Inicio),
-					strings.TrimSpace(b.Actividades.Desarrollo),
-					strings.TrimSpace(b.Actividades.Cierre),
-					b.Recursos,
-					b.Evidencias,
-					b.Instrumentos,
-					b.ValorPorcentual,
-				)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error": "No se pudo insertar sesión didáctica: " + err.Error(),
-					})
-					return
-				}
+	// ─────────────────────────────
+	// Referencias ↔ unidades (ya con ambas listas guardadas)
+	// ─────────────────────────────
+	if err := verificarReferenciasUnidades(c, tx, id, body.Referencias != nil); err != nil {
+		if respondValidacionError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron verificar referencias: " + err.Error()})
+		return
+	}
+
+	// Periodos de las unidades dentro del periodo escolar (calendario académico)
+	if err := verificarPeriodosUnidades(c, tx, id); err != nil {
+		if respondValidacionError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar calendario: " + err.Error()})
+		return
+	}
+
+	// ─────────────────────────────
+	// Publicar: consistencia e índice de búsqueda pública
+	// (ya con las secciones guardadas)
+	// ─────────────────────────────
+	if body.Status != nil && strings.TrimSpace(*body.Status) == "finalizada" {
+		if err := prepararPublicacion(c, tx, id); err != nil {
+			if respondValidacionError(c, err) {
+				return
 			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo publicar: " + err.Error()})
+			return
 		}
 	}
 
+	// ─────────────────────────────
+	// Auditoría (resumen: sin contenido de las secciones)
+	// ─────────────────────────────
+	accion := accionPlaneacionEditar
+	resumen := gin.H{}
+	if body.NombrePlaneacion != nil {
+		resumen["nombre_planeacion"] = strings.TrimSpace(*body.NombrePlaneacion)
+	}
+	if body.Status != nil {
+		resumen["status"] = strings.TrimSpace(*body.Status)
+		if resumen["status"] == "finalizada" {
+			accion = accionPlaneacionFinalizar
+		}
+	}
+	if body.UnidadesTematicas != nil {
+		resumen["unidades_tematicas"] = len(*body.UnidadesTematicas)
+	}
+	if body.Referencias != nil {
+		resumen["referencias"] = len(*body.Referencias)
+	}
+	if len(advertencias) > 0 {
+		resumen["campos_ignorados"] = len(advertencias)
+	}
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accion, "planeacion", int64(id), resumen)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
 	if err := tx.Commit(c); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
 		return
 	}
+	if body.Status != nil && strings.TrimSpace(*body.Status) == "finalizada" {
+		invalidarCachePublica() // nueva versión pública
+	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	resp := gin.H{"ok": true}
+	if len(advertencias) > 0 {
+		resp["advertencias"] = advertencias
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // =============================
 // DELETE /api/planeaciones/:id
-// Solo borra si la planeación es del docente del token
+// Manda a la papelera (deleted_at) si la planeación es del docente del token;
+// la purga automática la borra definitivamente (ver papelera.go)
 // =============================
 
 func (h *PlaneacionesHandler) Delete(c *gin.Context) {
@@ -1314,21 +1258,55 @@ func (h *PlaneacionesHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	res, err := h.DB.Exec(
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	// Lo eliminado queda descrito en la bitácora
+	var (
+		nombre, status string
+		slug           *string
+		eliminadaAt    time.Time
+	)
+	err = tx.QueryRow(
 		c,
-		`DELETE FROM planeaciones WHERE id = $1 AND docente_id = $2`,
+		`UPDATE planeaciones SET deleted_at = now(), deleted_by = $2
+		 WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL
+		 RETURNING nombre_planeacion, status::text, slug, deleted_at`,
 		id,
 		claims.UserID,
-	)
+	).Scan(&nombre, &status, &slug, &eliminadaAt)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar: " + err.Error()})
 		return
 	}
 
-	if res.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionEliminar, "planeacion", int64(id), gin.H{
+		"nombre_planeacion": nombre,
+		"status":            status,
+		"slug":              slug,
+		"papelera":          true,
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
 		return
 	}
+	invalidarCachePublica()
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	resp := gin.H{"ok": true, "deleted_at": eliminadaAt}
+	if dias := retencionPapelera(); dias > 0 {
+		resp["purga_at"] = eliminadaAt.AddDate(0, 0, dias)
+	}
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,515 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Secciones de la planeación (tablas 1:1 con planeaciones)
+// PUT /api/planeaciones/:id reemplaza todas; los PATCH por sección
+// combinan: lo omitido conserva el valor guardado y un campo enviado
+// (incluso null o "") lo reemplaza, así el cliente puede borrarlo.
+// =============================
+
+// Datos generales (planeacion_datos_generales + asignatura/periodo/grupo en planeaciones)
+type DatosGeneralesPayload struct {
+	PeriodoEscolar          *string `json:"periodo_escolar"`
+	PlanEstudiosAnio        *int    `json:"plan_estudios_anio"`
+	SemestreNivel           *string `json:"semestre_nivel"`
+	Grupos                  *string `json:"grupos"`
+	ProgramaAcademico       *string `json:"programa_academico"`
+	Academia                *string `json:"academia"`
+	UnidadAprendizajeNombre *string `json:"unidad_aprendizaje_nombre"`
+	AreaFormacion           *string `json:"area_formacion"`
+	Modalidad               *string `json:"modalidad"`
+	FechaElaboracion        *string `json:"fecha_elaboracion"`
+	DocenteAutor            *string `json:"docente_autor"`
+
+	// Referencias al catálogo académico (ver catalogos.go); el nombre del
+	// catálogo reemplaza el texto libre correspondiente
+	ProgramaID          *int64 `json:"programa_id"`
+	AcademiaID          *int64 `json:"academia_id"`
+	UnidadAprendizajeID *int64 `json:"unidad_aprendizaje_id"`
+
+	TipoUnidad *TipoUnidadPayload `json:"tipo_unidad"`
+
+	SemanasPorSemestre  *int `json:"semanas_por_semestre"`
+	SesionesPorSemestre *int `json:"sesiones_por_semestre"`
+	SesionesAula        *int `json:"sesiones_aula"`
+	SesionesLaboratorio *int `json:"sesiones_laboratorio"`
+	SesionesClinica     *int `json:"sesiones_clinica"`
+	SesionesOtro        *int `json:"sesiones_otro"`
+
+	HorasTeoria      *float64 `json:"horas_teoria"`
+	HorasPractica    *float64 `json:"horas_practica"`
+	HorasAula        *float64 `json:"horas_aula"`
+	HorasLaboratorio *float64 `json:"horas_laboratorio"`
+	HorasClinica     *float64 `json:"horas_clinica"`
+	HorasOtro        *float64 `json:"horas_otro"`
+	HorasTotal       *float64 `json:"horas_total"`
+
+	CreditosTepic *float64 `json:"creditos_tepic"`
+	CreditosSatca *float64 `json:"creditos_satca"`
+}
+
+// Relaciones con otras unidades + ejes transversales
+type RelacionesPayload struct {
+	Antecedentes *string `json:"antecedentes"`
+	Laterales    *string `json:"laterales"`
+	Subsecuentes *string `json:"subsecuentes"`
+
+	EjesCompromiso           *string `json:"ejes_compromiso_social_sustentabilidad"`
+	EjesPerspectivaGenero    *string `json:"ejes_perspectiva_genero"`
+	EjesInternacionalizacion *string `json:"ejes_internacionalizacion"`
+}
+
+// Organización didáctica
+type OrganizacionPayload struct {
+	OrgProposito  *string `json:"org_proposito"`
+	OrgEstrategia *string `json:"org_estrategia"`
+	OrgMetodos    *string `json:"org_metodos"`
+}
+
+// Herramientas antiplagio
+type PlagioPayload struct {
+	PlagioIthenticate *bool   `json:"plagio_ithenticate"`
+	PlagioTurnitin    *bool   `json:"plagio_turnitin"`
+	PlagioOtro        *string `json:"plagio_otro"`
+}
+
+// Referencias (la lista completa se reemplaza)
+type referenciasPatchRequest struct {
+	Referencias *[]ReferenciaPayload `json:"referencias"`
+}
+
+func (p *DatosGeneralesPayload) validar() error {
+	if _, err := dateOrNil(p.FechaElaboracion); err != nil {
+		return errors.New("fecha_elaboracion inválida (formato YYYY-MM-DD)")
+	}
+	return nil
+}
+
+func (p *referenciasPatchRequest) validar() error {
+	if p.Referencias == nil {
+		return errors.New("referencias es obligatorio")
+	}
+	return nil
+}
+
+// Columna de una sección
+type seccionCol struct {
+	nombre   string
+	campo    string // clave JSON si no es igual a nombre (para los PATCH)
+	valor    any
+	conserva bool // NULL conserva el valor actual también en PUT/PATCH
+}
+
+// camposEnviados: claves JSON presentes en el cuerpo de un PATCH (ver
+// decodeJSONBodyCampos). nil = PUT, todas las columnas se reemplazan.
+type camposEnviados map[string]bool
+
+// enviado indica si el PATCH trae la columna (aunque sea null).
+func (e camposEnviados) enviado(col seccionCol) bool {
+	if col.campo != "" {
+		return e[col.campo]
+	}
+	return e[col.nombre]
+}
+
+func (p *DatosGeneralesPayload) columnas() []seccionCol {
+	fecha, _ := dateOrNil(p.FechaElaboracion) // ya validada
+	return []seccionCol{
+		{nombre: "periodo", campo: "periodo_escolar", valor: strOrNil(p.PeriodoEscolar)},
+		{nombre: "plan_estudios_anio", valor: intOrNil(p.PlanEstudiosAnio)},
+		{nombre: "semestre_nivel", valor: strOrNil(p.SemestreNivel)},
+		{nombre: "grupos", valor: strOrNil(p.Grupos)},
+		{nombre: "programa_academico", valor: strOrNil(p.ProgramaAcademico)},
+		{nombre: "academia", valor: strOrNil(p.Academia)},
+		{nombre: "area_formacion", valor: strOrNil(p.AreaFormacion)},
+		{nombre: "modalidad", valor: strOrNil(p.Modalidad)},
+		{nombre: "sesiones_por_semestre", valor: intOrNil(p.SesionesPorSemestre)},
+		{nombre: "sesiones_aula", valor: intOrNil(p.SesionesAula)},
+		{nombre: "sesiones_laboratorio", valor: intOrNil(p.SesionesLaboratorio)},
+		{nombre: "sesiones_clinica", valor: intOrNil(p.SesionesClinica)},
+		{nombre: "sesiones_otro", valor: intOrNil(p.SesionesOtro)},
+		{nombre: "horas_teoria", valor: floatOrNil(p.HorasTeoria)},
+		{nombre: "horas_practica", valor: floatOrNil(p.HorasPractica)},
+		{nombre: "horas_aula", valor: floatOrNil(p.HorasAula)},
+		{nombre: "horas_laboratorio", valor: floatOrNil(p.HorasLaboratorio)},
+		{nombre: "horas_clinica", valor: floatOrNil(p.HorasClinica)},
+		{nombre: "horas_otro", valor: floatOrNil(p.HorasOtro)},
+		{nombre: "horas_total", valor: floatOrNil(p.HorasTotal)},
+		{nombre: "creditos_tepic", valor: floatOrNil(p.CreditosTepic)},
+		{nombre: "creditos_satca", valor: floatOrNil(p.CreditosSatca)},
+		{nombre: "fecha_elaboracion", valor: fecha},
+		{nombre: "tipo_unidad", valor: tipoUnidadOrNil(p.TipoUnidad)},
+		{nombre: "semanas_por_semestre", valor: intOrNil(p.SemanasPorSemestre)},
+		{nombre: "docente_autor", valor: strOrNil(p.DocenteAutor)},
+	}
+}
+
+func (p *RelacionesPayload) columnas() []seccionCol {
+	return []seccionCol{
+		{nombre: "antecedentes", valor: strOrNil(p.Antecedentes)},
+		{nombre: "laterales", valor: strOrNil(p.Laterales)},
+		{nombre: "subsecuentes", valor: strOrNil(p.Subsecuentes)},
+		{nombre: "ejes_compromiso_social_sustentabilidad", valor: strOrNil(p.EjesCompromiso)},
+		{nombre: "ejes_perspectiva_genero", valor: strOrNil(p.EjesPerspectivaGenero)},
+		{nombre: "ejes_internacionalizacion", valor: strOrNil(p.EjesInternacionalizacion)},
+	}
+}
+
+func (p *OrganizacionPayload) columnas() []seccionCol {
+	return []seccionCol{
+		{nombre: "proposito", campo: "org_proposito", valor: strOrNil(p.OrgProposito)},
+		{nombre: "estrategia", campo: "org_estrategia", valor: strOrNil(p.OrgEstrategia)},
+		{nombre: "metodos", campo: "org_metodos", valor: strOrNil(p.OrgMetodos)},
+	}
+}
+
+func (p *PlagioPayload) columnas() []seccionCol {
+	return []seccionCol{
+		{nombre: "ithenticate", campo: "plagio_ithenticate", valor: boolOrNil(p.PlagioIthenticate), conserva: true},
+		{nombre: "turnitin", campo: "plagio_turnitin", valor: boolOrNil(p.PlagioTurnitin), conserva: true},
+		{nombre: "otro", campo: "plagio_otro", valor: strOrNil(p.PlagioOtro)},
+	}
+}
+
+// guardarSeccion actualiza (o crea) la fila de la sección de la planeación.
+// enviados=nil (PUT) reemplaza todas las columnas; en un PATCH las que no
+// llegaron conservan su valor (COALESCE) y las enviadas como null se borran.
+func guardarSeccion(ctx context.Context, tx pgx.Tx, tabla string, planeacionID int, cols []seccionCol, enviados camposEnviados) error {
+	args := []any{planeacionID}
+	set := make([]string, 0, len(cols)+1)
+	for _, col := range cols {
+		args = append(args, col.valor)
+		ph := "$" + strconv.Itoa(len(args))
+		if col.conserva || (enviados != nil && !enviados.enviado(col)) {
+			set = append(set, col.nombre+" = COALESCE("+ph+", "+col.nombre+")")
+		} else {
+			set = append(set, col.nombre+" = "+ph)
+		}
+	}
+	set = append(set, "updated_at = now()")
+
+	cmd, err := tx.Exec(
+		ctx,
+		`UPDATE `+tabla+` SET `+strings.Join(set, ", ")+` WHERE planeacion_id = $1`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() > 0 {
+		return nil
+	}
+
+	// No existía: se insertan solo las columnas con valor (el resto toma su DEFAULT)
+	args = []any{planeacionID}
+	names := []string{"planeacion_id"}
+	phs := []string{"$1"}
+	for _, col := range cols {
+		if col.valor == nil {
+			continue
+		}
+		args = append(args, col.valor)
+		names = append(names, col.nombre)
+		phs = append(phs, "$"+strconv.Itoa(len(args)))
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO `+tabla+` (`+strings.Join(names, ", ")+`) VALUES (`+strings.Join(phs, ", ")+`)`,
+		args...,
+	)
+	return err
+}
+
+// guardarDatosGenerales guarda la sección y los campos que viven en planeaciones.
+// En un PATCH, asignatura/periodo/grupo enviados como null se borran.
+func guardarDatosGenerales(ctx context.Context, tx pgx.Tx, planeacionID int, p *DatosGeneralesPayload, enviados camposEnviados) error {
+	_, err := tx.Exec(
+		ctx,
+		`
+UPDATE planeaciones
+SET
+  asignatura = CASE WHEN $5 THEN $2 ELSE COALESCE($2, asignatura) END,
+  periodo    = CASE WHEN $6 THEN $3 ELSE COALESCE($3, periodo) END,
+  grupo      = CASE WHEN $7 THEN $4 ELSE COALESCE($4, grupo) END,
+  updated_at = now()
+WHERE id = $1
+		`,
+		planeacionID,
+		strOrNil(p.UnidadAprendizajeNombre),
+		strOrNil(p.PeriodoEscolar),
+		strOrNil(p.Grupos),
+		enviados["unidad_aprendizaje_nombre"],
+		enviados["periodo_escolar"],
+		enviados["grupos"],
+	)
+	if err != nil {
+		return err
+	}
+	if err := guardarSeccion(ctx, tx, "planeacion_datos_generales", planeacionID, p.columnas(), enviados); err != nil {
+		return err
+	}
+	return ligarCatalogos(ctx, tx, planeacionID, p, enviados)
+}
+
+// reemplazarReferencias borra e inserta todas las referencias de la planeación.
+func reemplazarReferencias(ctx context.Context, tx pgx.Tx, planeacionID int, refs []ReferenciaPayload) error {
+	if _, err := tx.Exec(
+		ctx,
+		`DELETE FROM planeacion_referencias WHERE planeacion_id = $1`,
+		planeacionID,
+	); err != nil {
+		return err
+	}
+
+	for i, ref := range refs {
+		ref.normalizar()
+		if err := ref.validar(); err != nil {
+			return errValidacion{errors.New("referencias[" + strconv.Itoa(i) + "]: " + err.Error())}
+		}
+		cita := strings.TrimSpace(ref.CitaAPA)
+		if apa := formatAPA(ref.ReferenciaBibliografica); apa != "" {
+			cita = apa
+		}
+		if cita == "" {
+			continue
+		}
+
+		tipo := strings.TrimSpace(ref.Tipo)
+		if tipo == "" {
+			tipo = "Básica"
+		}
+		if utf8.RuneCountInString(tipo) > 30 {
+			return errValidacion{errors.New("referencias[" + strconv.Itoa(i) + "]: tipo excede 30 caracteres")}
+		}
+
+		// Sin título no hay datos estructurados: solo cita libre
+		var b ReferenciaBibliografica
+		if ref.tieneDatos() {
+			b = ref.ReferenciaBibliografica
+		}
+		var autores any
+		if len(b.Autores) > 0 {
+			autores = b.Autores
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			`
+INSERT INTO planeacion_referencias (
+  planeacion_id,
+  cita_apa,
+  unidades_aplica,
+  tipo,
+  tipo_fuente,
+  autores,
+  anio,
+  titulo,
+  fuente,
+  editorial,
+  edicion,
+  volumen,
+  numero,
+  paginas,
+  doi,
+  isbn,
+  url
+) VALUES (
+  $1, $2, $3, $4,
+  NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''),
+  NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''), NULLIF($16, ''), NULLIF($17, '')
+)
+			`,
+			planeacionID,
+			cita,
+			normalizarUnidadesAplica(ref.UnidadesAplica),
+			tipo,
+			b.TipoFuente,
+			autores,
+			b.Anio,
+			b.Titulo,
+			b.Fuente,
+			b.Editorial,
+			b.Edicion,
+			b.Volumen,
+			b.Numero,
+			b.Paginas,
+			b.DOI,
+			b.ISBN,
+			b.URL,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockPlaneacionEditable bloquea la planeación (FOR UPDATE) y verifica que sea
+// del docente y no esté finalizada ni archivada. Si no, responde y regresa false.
+func lockPlaneacionEditable(c *gin.Context, tx pgx.Tx, id, docenteID int) bool {
+	var currentStatus string
+	err := tx.QueryRow(
+		c,
+		`SELECT status FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		id,
+		docenteID,
+	).Scan(&currentStatus)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando planeación: " + err.Error()})
+		return false
+	}
+
+	if strings.TrimSpace(strings.ToLower(currentStatus)) == "finalizada" {
+		// Reabrir primero con POST /:id/reabrir
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Planeación finalizada. Para editar debes reabrirla primero.",
+			"hint":  "POST /api/planeaciones/:id/reabrir",
+		})
+		return false
+	}
+	if strings.TrimSpace(strings.ToLower(currentStatus)) == "archivada" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Planeación archivada. Para editar debes desarchivarla primero.",
+			"hint":  "POST /api/planeaciones/:id/desarchivar",
+		})
+		return false
+	}
+	return true
+}
+
+// patchSeccion: flujo común de los PATCH (auth, decodificación, bloqueo, guardado).
+func (h *PlaneacionesHandler) patchSeccion(
+	c *gin.Context,
+	body any,
+	seccion string,
+	guardar func(ctx context.Context, tx pgx.Tx, id int, enviados camposEnviados) error,
+) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	advertencias, enviados, ok := decodeJSONBodyCampos(c, body)
+	if !ok {
+		return
+	}
+	if v, ok := body.(interface{ validar() error }); ok {
+		if err := v.validar(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	if !lockPlaneacionEditable(c, tx, id, claims.UserID) {
+		return
+	}
+
+	if err := guardar(c, tx, id, enviados); err != nil {
+		if respondValidacionError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar " + seccion + ": " + err.Error()})
+		return
+	}
+
+	if _, err := tx.Exec(c, `UPDATE planeaciones SET updated_at = now() WHERE id = $1`, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar planeación: " + err.Error()})
+		return
+	}
+
+	resumen := resumenSolicitud(c, body)
+	resumen["seccion"] = seccion
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionEditar, "planeacion", int64(id), resumen)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
+		return
+	}
+
+	resp := gin.H{"ok": true}
+	if len(advertencias) > 0 {
+		resp["advertencias"] = advertencias
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PATCH /api/planeaciones/:id/datos-generales
+func (h *PlaneacionesHandler) PatchDatosGenerales(c *gin.Context) {
+	var body DatosGeneralesPayload
+	h.patchSeccion(c, &body, "datos generales", func(ctx context.Context, tx pgx.Tx, id int, enviados camposEnviados) error {
+		if err := guardarDatosGenerales(ctx, tx, id, &body, enviados); err != nil {
+			return err
+		}
+		// Cambiar el periodo escolar puede dejar unidades fuera de él
+		return verificarPeriodosUnidades(ctx, tx, id)
+	})
+}
+
+// PATCH /api/planeaciones/:id/relaciones
+func (h *PlaneacionesHandler) PatchRelaciones(c *gin.Context) {
+	var body RelacionesPayload
+	h.patchSeccion(c, &body, "relaciones/ejes", func(ctx context.Context, tx pgx.Tx, id int, enviados camposEnviados) error {
+		return guardarSeccion(ctx, tx, "planeacion_relaciones_ejes", id, body.columnas(), enviados)
+	})
+}
+
+// PATCH /api/planeaciones/:id/organizacion
+func (h *PlaneacionesHandler) PatchOrganizacion(c *gin.Context) {
+	var body OrganizacionPayload
+	h.patchSeccion(c, &body, "organización", func(ctx context.Context, tx pgx.Tx, id int, enviados camposEnviados) error {
+		return guardarSeccion(ctx, tx, "planeacion_organizacion", id, body.columnas(), enviados)
+	})
+}
+
+// PATCH /api/planeaciones/:id/plagio
+func (h *PlaneacionesHandler) PatchPlagio(c *gin.Context) {
+	var body PlagioPayload
+	h.patchSeccion(c, &body, "plagio", func(ctx context.Context, tx pgx.Tx, id int, enviados camposEnviados) error {
+		return guardarSeccion(ctx, tx, "planeacion_plagio", id, body.columnas(), enviados)
+	})
+}
+
+// PATCH /api/planeaciones/:id/referencias
+// Body: { "referencias": [ ... ] } (reemplaza la lista completa)
+func (h *PlaneacionesHandler) PatchReferencias(c *gin.Context) {
+	var body referenciasPatchRequest
+	h.patchSeccion(c, &body, "referencias", func(ctx context.Context, tx pgx.Tx, id int, enviados camposEnviados) error {
+		if err := reemplazarReferencias(ctx, tx, id, *body.Referencias); err != nil {
+			return err
+		}
+		return verificarReferenciasUnidades(ctx, tx, id, true)
+	})
+}
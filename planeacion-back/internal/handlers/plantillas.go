@@ -0,0 +1,946 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// PlantillasHandler
+// Plantillas de programa sintético: contenido oficial de una unidad de
+// aprendizaje (datos generales, unidades temáticas y bibliografía) que
+// siembra una planeación nueva. Consulta para docentes; altas, cambios y
+// bajas solo coordinación (rol admin).
+// =============================
+
+type PlantillasHandler struct {
+	DB *pgxpool.Pool
+}
+
+// RegisterPlantillasRoutes registra la consulta de plantillas (requiere sesión).
+func RegisterPlantillasRoutes(rg *gin.RouterGroup, h *PlantillasHandler) {
+	g := rg.Group("/plantillas")
+
+	g.GET("", h.List)       // GET /api/plantillas?unidad_aprendizaje_id=&q=&incluir_no_vigentes=1
+	g.GET("/:id", h.GetOne) // GET /api/plantillas/:id
+}
+
+// RegisterPlantillasAdminRoutes registra la administración de plantillas (solo admin).
+func RegisterPlantillasAdminRoutes(rg *gin.RouterGroup, h *PlantillasHandler) {
+	g := rg.Group("/plantillas")
+
+	g.POST("", h.Create)       // POST /api/admin/plantillas
+	g.PUT("/:id", h.Update)    // PUT /api/admin/plantillas/:id
+	g.DELETE("/:id", h.Delete) // DELETE /api/admin/plantillas/:id
+
+	rg.GET("/planeaciones/:id/diferencias-plantilla", h.Diferencias) // GET /api/admin/planeaciones/:id/diferencias-plantilla
+}
+
+// =============================
+// DTOs
+// =============================
+
+type plantillaRequest struct {
+	UnidadAcademicaID   int             `json:"unidad_academica_id"`
+	UnidadAprendizajeID *int64          `json:"unidad_aprendizaje_id"`
+	Nombre              string          `json:"nombre"`
+	Version             *string         `json:"version"`
+	Vigente             *bool           `json:"vigente"` // omitido = true
+	DatosGenerales      json.RawMessage `json:"datos_generales"`
+	Unidades            json.RawMessage `json:"unidades"`
+	Referencias         json.RawMessage `json:"referencias"`
+}
+
+// Resumen para listados (sin el contenido)
+type plantillaResumen struct {
+	ID                  int64     `json:"id"`
+	UnidadAcademicaID   int       `json:"unidad_academica_id"`
+	UnidadAprendizajeID *int64    `json:"unidad_aprendizaje_id"`
+	Nombre              string    `json:"nombre"`
+	Version             *string   `json:"version"`
+	Vigente             bool      `json:"vigente"`
+	TotalUnidades       int       `json:"total_unidades"`
+	TotalReferencias    int       `json:"total_referencias"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Contenido decodificado de una plantilla
+type contenidoPlantilla struct {
+	DatosGenerales DatosGeneralesPayload
+	Unidades       []UnidadTematicaPayload
+	Referencias    []ReferenciaPayload
+}
+
+// decodificarEstricto rechaza campos desconocidos (erratas en la plantilla).
+func decodificarEstricto(raw json.RawMessage, dst any, campo string) error {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%s inválido: %v", campo, err)
+	}
+	return nil
+}
+
+// decodificarPlantilla valida el contenido con las mismas reglas que PUT /api/planeaciones/:id.
+func decodificarPlantilla(dg, unidades, refs json.RawMessage) (*contenidoPlantilla, error) {
+	var out contenidoPlantilla
+	if err := decodificarEstricto(dg, &out.DatosGenerales, "datos_generales"); err != nil {
+		return nil, err
+	}
+	if err := decodificarEstricto(unidades, &out.Unidades, "unidades"); err != nil {
+		return nil, err
+	}
+	if err := decodificarEstricto(refs, &out.Referencias, "referencias"); err != nil {
+		return nil, err
+	}
+
+	if err := out.DatosGenerales.validar(); err != nil {
+		return nil, err
+	}
+	for _, ut := range out.Unidades {
+		if ut.Numero <= 0 {
+			return nil, errors.New("cada unidad temática requiere numero positivo")
+		}
+		if strings.TrimSpace(ut.NombreUnidadTematica) == "" {
+			return nil, fmt.Errorf("la unidad temática %d requiere nombre_unidad_tematica", ut.Numero)
+		}
+	}
+	if err := validarUnidadesTematicas(out.Unidades); err != nil {
+		return nil, err
+	}
+
+	numeros := make(map[int32]bool, len(out.Unidades))
+	for _, ut := range out.Unidades {
+		numeros[int32(ut.Numero)] = true
+	}
+	for i, ref := range out.Referencias {
+		if strings.TrimSpace(ref.CitaAPA) == "" && !ref.tieneDatos() {
+			return nil, fmt.Errorf("la referencia %d no tiene cita_apa ni datos bibliográficos", i+1)
+		}
+		b := ref.ReferenciaBibliografica
+		b.normalizar()
+		if err := b.validar(); err != nil {
+			return nil, fmt.Errorf("la referencia %d: %w", i+1, err)
+		}
+		for _, n := range ref.UnidadesAplica {
+			if !numeros[n] {
+				return nil, fmt.Errorf("la referencia %d aplica a la unidad %d, que no existe en la plantilla", i+1, n)
+			}
+		}
+	}
+	return &out, nil
+}
+
+func (r *plantillaRequest) validar() (*contenidoPlantilla, error) {
+	r.Nombre = strings.TrimSpace(r.Nombre)
+	if r.Nombre == "" {
+		return nil, errors.New("nombre es obligatorio")
+	}
+	if r.UnidadAcademicaID <= 0 {
+		return nil, errors.New("unidad_academica_id es obligatorio")
+	}
+	if len(bytes.TrimSpace(r.DatosGenerales)) == 0 {
+		r.DatosGenerales = json.RawMessage(`{}`)
+	}
+	if len(bytes.TrimSpace(r.Unidades)) == 0 {
+		r.Unidades = json.RawMessage(`[]`)
+	}
+	if len(bytes.TrimSpace(r.Referencias)) == 0 {
+		r.Referencias = json.RawMessage(`[]`)
+	}
+	return decodificarPlantilla(r.DatosGenerales, r.Unidades, r.Referencias)
+}
+
+const plantillaCols = `id, unidad_academica_id, unidad_aprendizaje_id, nombre, version, vigente,
+	datos_generales, unidades, referencias, created_by, created_at, updated_at`
+
+func scanPlantilla(row pgx.Row, p *models.PlantillaPrograma) error {
+	return row.Scan(
+		&p.ID,
+		&p.UnidadAcademicaID,
+		&p.UnidadAprendizajeID,
+		&p.Nombre,
+		&p.Version,
+		&p.Vigente,
+		&p.DatosGenerales,
+		&p.Unidades,
+		&p.Referencias,
+		&p.CreatedBy,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+}
+
+func (h *PlantillasHandler) responderErrorPlantilla(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "plantilla no encontrada"})
+	case esViolacionFK(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_academica_id o unidad_aprendizaje_id no existe"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+			"msg":   err.Error(),
+		})
+	}
+}
+
+// verificarUnidadAprendizaje: la unidad de aprendizaje debe ser del catálogo de la unidad académica.
+func (h *PlantillasHandler) verificarUnidadAprendizaje(ctx context.Context, r *plantillaRequest) error {
+	if r.UnidadAprendizajeID == nil {
+		return nil
+	}
+	var ok bool
+	err := h.DB.QueryRow(
+		ctx,
+		`SELECT EXISTS (
+		   SELECT 1 FROM public.unidades_aprendizaje ua
+		   JOIN public.academias a ON a.id = ua.academia_id
+		   JOIN public.programas_academicos pa ON pa.id = a.programa_id
+		   WHERE ua.id = $1 AND pa.unidad_academica_id = $2)`,
+		*r.UnidadAprendizajeID,
+		r.UnidadAcademicaID,
+	).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errValidacion{errors.New("unidad_aprendizaje_id no pertenece al catálogo de la unidad académica")}
+	}
+	return nil
+}
+
+// =============================
+// Consulta
+// =============================
+
+// GET /api/plantillas?unidad_academica_id=&unidad_aprendizaje_id=&q=&incluir_no_vigentes=1
+// Por defecto, las plantillas vigentes de la unidad académica del usuario.
+func (h *PlantillasHandler) List(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ua := claims.UnidadID
+	if s := strings.TrimSpace(c.Query("unidad_academica_id")); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_academica_id inválido"})
+			return
+		}
+		ua = n
+	}
+
+	where := []string{"unidad_academica_id = $1"}
+	args := []any{ua}
+	if c.Query("incluir_no_vigentes") != "1" {
+		where = append(where, "vigente")
+	}
+	if s := strings.TrimSpace(c.Query("unidad_aprendizaje_id")); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_aprendizaje_id inválido"})
+			return
+		}
+		args = append(args, n)
+		where = append(where, "unidad_aprendizaje_id = $"+strconv.Itoa(len(args)))
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		args = append(args, q)
+		ph := "$" + strconv.Itoa(len(args))
+		where = append(where, "(public.normalizar_catalogo(nombre) % public.normalizar_catalogo("+ph+")"+
+			" OR public.normalizar_catalogo(nombre) LIKE '%' || public.normalizar_catalogo("+ph+") || '%')")
+	}
+
+	rows, err := h.DB.Query(
+		c.Request.Context(),
+		`SELECT id, unidad_academica_id, unidad_aprendizaje_id, nombre, version, vigente,
+		        jsonb_array_length(unidades), jsonb_array_length(referencias), updated_at
+		 FROM public.plantillas_programa
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY nombre, updated_at DESC`,
+		args...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar plantillas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]plantillaResumen, 0)
+	for rows.Next() {
+		var p plantillaResumen
+		if err := rows.Scan(
+			&p.ID, &p.UnidadAcademicaID, &p.UnidadAprendizajeID, &p.Nombre, &p.Version, &p.Vigente,
+			&p.TotalUnidades, &p.TotalReferencias, &p.UpdatedAt,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer plantillas",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, p)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar plantillas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// GET /api/plantillas/:id
+func (h *PlantillasHandler) GetOne(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	var p models.PlantillaPrograma
+	err := scanPlantilla(h.DB.QueryRow(
+		c.Request.Context(),
+		`SELECT `+plantillaCols+` FROM public.plantillas_programa WHERE id = $1`,
+		id,
+	), &p)
+	if err != nil {
+		h.responderErrorPlantilla(c, "error al consultar plantilla", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// =============================
+// Administración
+// =============================
+
+// POST /api/admin/plantillas
+// Body: { "unidad_academica_id": 1, "unidad_aprendizaje_id": 10, "nombre": "Cálculo (plan 2020)",
+//
+//	"version": "2020", "datos_generales": {...}, "unidades": [...], "referencias": [...] }
+func (h *PlantillasHandler) Create(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req plantillaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if _, err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.verificarUnidadAprendizaje(c.Request.Context(), &req); err != nil {
+		if !respondValidacionError(c, err) {
+			h.responderErrorPlantilla(c, "error al validar plantilla", err)
+		}
+		return
+	}
+
+	vigente := req.Vigente == nil || *req.Vigente
+	var p models.PlantillaPrograma
+	err = conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanPlantilla(tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.plantillas_programa
+			   (unidad_academica_id, unidad_aprendizaje_id, nombre, version, vigente,
+			    datos_generales, unidades, referencias, created_by)
+			 VALUES ($1, $2, $3, NULLIF(btrim($4), ''), $5, $6::jsonb, $7::jsonb, $8::jsonb, $9)
+			 RETURNING `+plantillaCols,
+			req.UnidadAcademicaID,
+			req.UnidadAprendizajeID,
+			req.Nombre,
+			strOrNil(req.Version),
+			vigente,
+			string(req.DatosGenerales),
+			string(req.Unidades),
+			string(req.Referencias),
+			claims.UserID,
+		), &p)
+		return eventoDeClaims(claims, accionPlantillaCrear, "plantilla", p.ID, gin.H{"nombre": p.Nombre, "version": p.Version, "vigente": p.Vigente, "unidad_academica_id": p.UnidadAcademicaID, "unidad_aprendizaje_id": p.UnidadAprendizajeID}), err
+	})
+	if err != nil {
+		h.responderErrorPlantilla(c, "error al crear plantilla", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// PUT /api/admin/plantillas/:id
+// Reemplaza la plantilla; las planeaciones ya sembradas no cambian
+// (sus diferencias se calculan contra el contenido actual).
+func (h *PlantillasHandler) Update(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	var req plantillaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if _, err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.verificarUnidadAprendizaje(c.Request.Context(), &req); err != nil {
+		if !respondValidacionError(c, err) {
+			h.responderErrorPlantilla(c, "error al validar plantilla", err)
+		}
+		return
+	}
+
+	vigente := req.Vigente == nil || *req.Vigente
+	var p models.PlantillaPrograma
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanPlantilla(tx.QueryRow(
+			c.Request.Context(),
+			`UPDATE public.plantillas_programa
+			 SET unidad_academica_id = $2, unidad_aprendizaje_id = $3, nombre = $4,
+			     version = NULLIF(btrim($5), ''), vigente = $6,
+			     datos_generales = $7::jsonb, unidades = $8::jsonb, referencias = $9::jsonb
+			 WHERE id = $1
+			 RETURNING `+plantillaCols,
+			id,
+			req.UnidadAcademicaID,
+			req.UnidadAprendizajeID,
+			req.Nombre,
+			strOrNil(req.Version),
+			vigente,
+			string(req.DatosGenerales),
+			string(req.Unidades),
+			string(req.Referencias),
+		), &p)
+		return eventoDeSesion(c, accionPlantillaEditar, "plantilla", p.ID, gin.H{"nombre": p.Nombre, "version": p.Version, "vigente": p.Vigente, "unidad_academica_id": p.UnidadAcademicaID, "unidad_aprendizaje_id": p.UnidadAprendizajeID}), err
+	})
+	if err != nil {
+		h.responderErrorPlantilla(c, "error al actualizar plantilla", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// DELETE /api/admin/plantillas/:id
+// Las planeaciones sembradas se conservan (plantilla_id queda en NULL).
+func (h *PlantillasHandler) Delete(c *gin.Context) {
+	id, ok := paramID64(c)
+	if !ok {
+		return
+	}
+
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		var (
+			nombre  string
+			version *string
+		)
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`DELETE FROM public.plantillas_programa WHERE id = $1 RETURNING nombre, version`,
+			id,
+		).Scan(&nombre, &version)
+		return eventoDeSesion(c, accionPlantillaEliminar, "plantilla", id, gin.H{"nombre": nombre, "version": version}), err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "plantilla no encontrada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al eliminar plantilla",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GET /api/admin/planeaciones/:id/diferencias-plantilla
+// Revisión: cambios de la planeación respecto a lo que sembró su plantilla.
+func (h *PlantillasHandler) Diferencias(c *gin.Context) {
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+	responderDiferencias(c, h.DB, id)
+}
+
+// =============================
+// Sembrado de una planeación
+// =============================
+
+// aplicarPlantilla siembra datos generales, unidades temáticas y referencias
+// de la plantilla en una planeación recién creada y guarda una copia de lo
+// sembrado (plantilla_contenido) para las diferencias. Regresa el nombre de la plantilla.
+func aplicarPlantilla(ctx context.Context, tx pgx.Tx, planeacionID int, plantillaID int64, unidadAcademicaID int) (string, error) {
+	var (
+		nombre              string
+		unidadAprendizajeID *int64
+		dg, uts, refs       []byte
+	)
+	err := tx.QueryRow(
+		ctx,
+		`SELECT nombre, unidad_aprendizaje_id, datos_generales, unidades, referencias
+		 FROM public.plantillas_programa
+		 WHERE id = $1 AND vigente AND unidad_academica_id = $2`,
+		plantillaID,
+		unidadAcademicaID,
+	).Scan(&nombre, &unidadAprendizajeID, &dg, &uts, &refs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", errValidacion{errors.New("La plantilla no existe, no está vigente o es de otra unidad académica.")}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	contenido, err := decodificarPlantilla(dg, uts, refs)
+	if err != nil {
+		return "", fmt.Errorf("plantilla %d: %w", plantillaID, err)
+	}
+
+	if contenido.DatosGenerales.UnidadAprendizajeID == nil {
+		contenido.DatosGenerales.UnidadAprendizajeID = unidadAprendizajeID
+	}
+	if err := guardarDatosGenerales(ctx, tx, planeacionID, &contenido.DatosGenerales, nil); err != nil {
+		return "", err
+	}
+	for _, ut := range contenido.Unidades {
+		if _, err := insertarUnidadTematica(ctx, tx, planeacionID, ut); err != nil {
+			return "", fmt.Errorf("unidad %d: %w", ut.Numero, err)
+		}
+	}
+	if err := reemplazarReferencias(ctx, tx, planeacionID, contenido.Referencias); err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE planeaciones p
+		 SET plantilla_id = pp.id, plantilla_aplicada_at = now(), plantilla_version = pp.version,
+		     plantilla_contenido = jsonb_build_object(
+		       'datos_generales', pp.datos_generales,
+		       'unidades', pp.unidades,
+		       'referencias', pp.referencias)
+		 FROM public.plantillas_programa pp
+		 WHERE p.id = $1 AND pp.id = $2`,
+		planeacionID,
+		plantillaID,
+	)
+	return nombre, err
+}
+
+// =============================
+// Diferencias contra la plantilla
+// =============================
+
+// Campo distinto a la plantilla
+type cambioCampo struct {
+	Campo     string `json:"campo"`
+	Plantilla any    `json:"plantilla"`
+	Actual    any    `json:"actual"`
+}
+
+// Unidad temática distinta a la plantilla
+type cambioUnidad struct {
+	Numero int           `json:"numero"`
+	Estado string        `json:"estado"` // faltante | agregada | modificada
+	Nombre string        `json:"nombre_unidad_tematica"`
+	Campos []cambioCampo `json:"campos,omitempty"`
+}
+
+type referenciaDiferencia struct {
+	CitaAPA string `json:"cita_apa"`
+	Tipo    string `json:"tipo"`
+}
+
+type reporteDiferencias struct {
+	Plantilla            plantillaResumen       `json:"plantilla"`
+	AplicadaAt           *time.Time             `json:"aplicada_at"`
+	VersionAplicada      *string                `json:"version_aplicada"`
+	PlantillaModificada  bool                   `json:"plantilla_modificada"` // cambió después de sembrar la planeación
+	TotalCambios         int                    `json:"total_cambios"`
+	DatosGenerales       []cambioCampo          `json:"datos_generales"`
+	Unidades             []cambioUnidad         `json:"unidades"`
+	ReferenciasFaltantes []referenciaDiferencia `json:"referencias_faltantes"`
+	ReferenciasAgregadas []referenciaDiferencia `json:"referencias_agregadas"`
+}
+
+// Datos generales guardados, con las claves de DatosGeneralesPayload
+const datosGeneralesPayloadSQL = `
+SELECT json_build_object(
+  'periodo_escolar', dg.periodo,
+  'plan_estudios_anio', dg.plan_estudios_anio,
+  'semestre_nivel', dg.semestre_nivel,
+  'grupos', dg.grupos,
+  'programa_academico', dg.programa_academico,
+  'academia', dg.academia,
+  'unidad_aprendizaje_nombre', p.asignatura,
+  'area_formacion', dg.area_formacion,
+  'modalidad', dg.modalidad,
+  'fecha_elaboracion', dg.fecha_elaboracion,
+  'docente_autor', dg.docente_autor,
+  'programa_id', p.programa_id,
+  'academia_id', p.academia_id,
+  'unidad_aprendizaje_id', p.unidad_aprendizaje_id,
+  'tipo_unidad', dg.tipo_unidad,
+  'semanas_por_semestre', dg.semanas_por_semestre,
+  'sesiones_por_semestre', dg.sesiones_por_semestre,
+  'sesiones_aula', dg.sesiones_aula,
+  'sesiones_laboratorio', dg.sesiones_laboratorio,
+  'sesiones_clinica', dg.sesiones_clinica,
+  'sesiones_otro', dg.sesiones_otro,
+  'horas_teoria', dg.horas_teoria,
+  'horas_practica', dg.horas_practica,
+  'horas_aula', dg.horas_aula,
+  'horas_laboratorio', dg.horas_laboratorio,
+  'horas_clinica', dg.horas_clinica,
+  'horas_otro', dg.horas_otro,
+  'horas_total', dg.horas_total,
+  'creditos_tepic', dg.creditos_tepic,
+  'creditos_satca', dg.creditos_satca
+)
+FROM planeaciones p
+LEFT JOIN planeacion_datos_generales dg ON dg.planeacion_id = p.id
+WHERE p.id = $1`
+
+// aMapa: representación JSON comparable (sin nulos).
+func aMapa(v any) map[string]any {
+	raw, _ := json.Marshal(v)
+	m := map[string]any{}
+	_ = json.Unmarshal(raw, &m)
+	for k, x := range m {
+		if x == nil {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+func valoresIguales(a, b any) bool {
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if okA && okB {
+		return strings.Join(strings.Fields(sa), " ") == strings.Join(strings.Fields(sb), " ")
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compararMapas lista los campos con valor en la plantilla que difieren de lo actual.
+func compararMapas(plantilla, actual map[string]any) []cambioCampo {
+	campos := make([]string, 0, len(plantilla))
+	for k := range plantilla {
+		campos = append(campos, k)
+	}
+	sort.Strings(campos)
+
+	out := []cambioCampo{}
+	for _, k := range campos {
+		tv := plantilla[k]
+		if s, ok := tv.(string); ok && strings.TrimSpace(s) == "" {
+			continue
+		}
+		if l, ok := tv.([]any); ok && len(l) == 0 {
+			continue
+		}
+		if !valoresIguales(tv, actual[k]) {
+			out = append(out, cambioCampo{Campo: k, Plantilla: tv, Actual: actual[k]})
+		}
+	}
+	return out
+}
+
+// camposUnidad: campos de contenido oficial de una unidad (sin periodo ni sesiones).
+func camposUnidad(ut UnidadTematicaPayload) map[string]any {
+	m := aMapa(struct {
+		Nombre                string                    `json:"nombre_unidad_tematica"`
+		UnidadCompetencia     string                    `json:"unidad_competencia"`
+		Horas                 HorasPayload              `json:"horas"`
+		SesionesPorEspacio    SesionesPorEspacioPayload `json:"sesiones_por_espacio"`
+		SesionesTotales       *int                      `json:"sesiones_totales"`
+		AprendizajesEsperados []string                  `json:"aprendizajes_esperados"`
+		Precisiones           *string                   `json:"precisiones"`
+	}{
+		ut.NombreUnidadTematica, ut.UnidadCompetencia, ut.Horas, ut.SesionesPorEspacio,
+		ut.SesionesTotales, ut.AprendizajesEsperados, ut.Precisiones,
+	})
+	// Horas y sesiones por espacio como campos planos (horas.aula, ...)
+	for _, grupo := range []string{"horas", "sesiones_por_espacio"} {
+		if sub, ok := m[grupo].(map[string]any); ok {
+			for k, v := range sub {
+				if v != nil {
+					m[grupo+"."+k] = v
+				}
+			}
+		}
+		delete(m, grupo)
+	}
+	return m
+}
+
+func cargarUnidadesPayload(ctx context.Context, db consultaDB, planeacionID int) ([]UnidadTematicaPayload, error) {
+	rows, err := db.Query(
+		ctx,
+		`SELECT numero, nombre_unidad_tematica, COALESCE(unidad_competencia, ''),
+		        horas_aula::float8, horas_laboratorio::float8, horas_taller::float8, horas_clinica::float8, horas_otro::float8,
+		        sesiones_aula, sesiones_laboratorio, sesiones_taller, sesiones_clinica, sesiones_otro,
+		        sesiones_totales, aprendizajes_esperados, precisiones
+		 FROM unidades_tematicas WHERE planeacion_id = $1 ORDER BY numero`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UnidadTematicaPayload
+	for rows.Next() {
+		var ut UnidadTematicaPayload
+		if err := rows.Scan(
+			&ut.Numero, &ut.NombreUnidadTematica, &ut.UnidadCompetencia,
+			&ut.Horas.Aula, &ut.Horas.Laboratorio, &ut.Horas.Taller, &ut.Horas.Clinica, &ut.Horas.Otro,
+			&ut.SesionesPorEspacio.Aula, &ut.SesionesPorEspacio.Laboratorio, &ut.SesionesPorEspacio.Taller,
+			&ut.SesionesPorEspacio.Clinica, &ut.SesionesPorEspacio.Otro,
+			&ut.SesionesTotales, &ut.AprendizajesEsperados, &ut.Precisiones,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, ut)
+	}
+	return out, rows.Err()
+}
+
+// clavesReferenciasPlaneacion: clave de obra → referencia guardada.
+func clavesReferenciasPlaneacion(ctx context.Context, db consultaDB, planeacionID int) (map[string]referenciaDiferencia, []string, error) {
+	rows, err := db.Query(
+		ctx,
+		`SELECT cita_apa, COALESCE(tipo, ''), tipo_fuente, autores, anio, titulo, doi, isbn
+		 FROM planeacion_referencias WHERE planeacion_id = $1 ORDER BY id`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]referenciaDiferencia{}
+	var orden []string
+	for rows.Next() {
+		var (
+			r                             referenciaDiferencia
+			tipoFuente, titulo, doi, isbn *string
+			autoresRaw                    []byte
+			anio                          *int
+		)
+		if err := rows.Scan(&r.CitaAPA, &r.Tipo, &tipoFuente, &autoresRaw, &anio, &titulo, &doi, &isbn); err != nil {
+			return nil, nil, err
+		}
+		datos := ReferenciaBibliografica{
+			TipoFuente: deref(tipoFuente),
+			Anio:       anio,
+			Titulo:     deref(titulo),
+			DOI:        deref(doi),
+			ISBN:       deref(isbn),
+		}
+		if len(autoresRaw) > 0 {
+			_ = json.Unmarshal(autoresRaw, &datos.Autores)
+		}
+		clave := claveReferencia(datos, r.CitaAPA)
+		if _, ok := out[clave]; !ok {
+			orden = append(orden, clave)
+		}
+		out[clave] = r
+	}
+	return out, orden, rows.Err()
+}
+
+// diferenciasPlantilla compara la planeación con el contenido con que se sembró
+// (no con la plantilla actual, que pudo editarse después). nil si no tiene plantilla.
+func diferenciasPlantilla(ctx context.Context, db consultaDB, planeacionID int) (*reporteDiferencias, error) {
+	var (
+		rep           reporteDiferencias
+		dg, uts, refs []byte
+	)
+	err := db.QueryRow(
+		ctx,
+		`SELECT pp.id, pp.unidad_academica_id, pp.unidad_aprendizaje_id, pp.nombre, pp.version, pp.vigente,
+		        jsonb_array_length(pp.unidades), jsonb_array_length(pp.referencias), pp.updated_at,
+		        p.plantilla_aplicada_at, p.plantilla_version,
+		        COALESCE(p.plantilla_contenido->'datos_generales', pp.datos_generales),
+		        COALESCE(p.plantilla_contenido->'unidades', pp.unidades),
+		        COALESCE(p.plantilla_contenido->'referencias', pp.referencias)
+		 FROM planeaciones p
+		 JOIN public.plantillas_programa pp ON pp.id = p.plantilla_id
+		 WHERE p.id = $1`,
+		planeacionID,
+	).Scan(
+		&rep.Plantilla.ID, &rep.Plantilla.UnidadAcademicaID, &rep.Plantilla.UnidadAprendizajeID,
+		&rep.Plantilla.Nombre, &rep.Plantilla.Version, &rep.Plantilla.Vigente,
+		&rep.Plantilla.TotalUnidades, &rep.Plantilla.TotalReferencias, &rep.Plantilla.UpdatedAt,
+		&rep.AplicadaAt, &rep.VersionAplicada, &dg, &uts, &refs,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rep.PlantillaModificada = rep.AplicadaAt != nil && rep.Plantilla.UpdatedAt.After(*rep.AplicadaAt)
+
+	contenido, err := decodificarPlantilla(dg, uts, refs)
+	if err != nil {
+		return nil, fmt.Errorf("plantilla %d: %w", rep.Plantilla.ID, err)
+	}
+
+	// Datos generales
+	var actualRaw []byte
+	if err := db.QueryRow(ctx, datosGeneralesPayloadSQL, planeacionID).Scan(&actualRaw); err != nil {
+		return nil, err
+	}
+	var actual DatosGeneralesPayload
+	if err := json.Unmarshal(actualRaw, &actual); err != nil {
+		return nil, err
+	}
+	rep.DatosGenerales = compararMapas(aMapa(contenido.DatosGenerales), aMapa(actual))
+
+	// Unidades temáticas (por número)
+	unidades, err := cargarUnidadesPayload(ctx, db, planeacionID)
+	if err != nil {
+		return nil, err
+	}
+	porNumero := make(map[int]UnidadTematicaPayload, len(unidades))
+	for _, ut := range unidades {
+		porNumero[ut.Numero] = ut
+	}
+	rep.Unidades = []cambioUnidad{}
+	enPlantilla := map[int]bool{}
+	for _, tu := range contenido.Unidades {
+		enPlantilla[tu.Numero] = true
+		ut, ok := porNumero[tu.Numero]
+		if !ok {
+			rep.Unidades = append(rep.Unidades, cambioUnidad{Numero: tu.Numero, Estado: "faltante", Nombre: tu.NombreUnidadTematica})
+			continue
+		}
+		if campos := compararMapas(camposUnidad(tu), camposUnidad(ut)); len(campos) > 0 {
+			rep.Unidades = append(rep.Unidades, cambioUnidad{Numero: tu.Numero, Estado: "modificada", Nombre: ut.NombreUnidadTematica, Campos: campos})
+		}
+	}
+	for _, ut := range unidades {
+		if !enPlantilla[ut.Numero] {
+			rep.Unidades = append(rep.Unidades, cambioUnidad{Numero: ut.Numero, Estado: "agregada", Nombre: ut.NombreUnidadTematica})
+		}
+	}
+
+	// Referencias (por obra: DOI, ISBN, título o cita)
+	guardadas, orden, err := clavesReferenciasPlaneacion(ctx, db, planeacionID)
+	if err != nil {
+		return nil, err
+	}
+	rep.ReferenciasFaltantes = []referenciaDiferencia{}
+	rep.ReferenciasAgregadas = []referenciaDiferencia{}
+	deLaPlantilla := map[string]bool{}
+	for _, ref := range contenido.Referencias {
+		ref.normalizar()
+		cita := strings.TrimSpace(ref.CitaAPA)
+		if apa := formatAPA(ref.ReferenciaBibliografica); apa != "" {
+			cita = apa
+		}
+		clave := claveReferencia(ref.ReferenciaBibliografica, cita)
+		deLaPlantilla[clave] = true
+		if _, ok := guardadas[clave]; !ok {
+			tipo := strings.TrimSpace(ref.Tipo)
+			if tipo == "" {
+				tipo = "Básica"
+			}
+			rep.ReferenciasFaltantes = append(rep.ReferenciasFaltantes, referenciaDiferencia{CitaAPA: cita, Tipo: tipo})
+		}
+	}
+	for _, clave := range orden {
+		if !deLaPlantilla[clave] {
+			rep.ReferenciasAgregadas = append(rep.ReferenciasAgregadas, guardadas[clave])
+		}
+	}
+
+	rep.TotalCambios = len(rep.DatosGenerales) + len(rep.Unidades) + len(rep.ReferenciasFaltantes) + len(rep.ReferenciasAgregadas)
+	return &rep, nil
+}
+
+func responderDiferencias(c *gin.Context, db consultaDB, planeacionID int) {
+	rep, err := diferenciasPlantilla(c, db, planeacionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron calcular diferencias: " + err.Error()})
+		return
+	}
+	if rep == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "La planeación no existe o no se creó desde una plantilla"})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+// =============================
+// GET /api/planeaciones/:id/diferencias-plantilla
+// Cambios del docente respecto a la plantilla con la que creó la planeación.
+// =============================
+func (h *PlaneacionesHandler) DiferenciasPlantilla(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var existe bool
+	if err := h.DB.QueryRow(
+		c,
+		`SELECT EXISTS (SELECT 1 FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL)`,
+		id,
+		claims.UserID,
+	).Scan(&existe); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if !existe {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+		return
+	}
+
+	responderDiferencias(c, h.DB, id)
+}
@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Programación de sesiones
+// Horario semanal del grupo (/horario) y asignación automática de fecha a
+// cada sesión didáctica dentro del periodo de desarrollo de su unidad,
+// respetando el calendario académico (/programar).
+// =============================
+
+const formatoHora = "15:04"
+
+var espaciosValidos = []string{"aula", "laboratorio", "taller", "clinica", "otro"}
+
+func esEspacioValido(s string) bool {
+	for _, e := range espaciosValidos {
+		if s == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ProgramacionSesion: fecha y horario de una sesión (null conserva; "" limpia en PATCH).
+type ProgramacionSesion struct {
+	Fecha      *string `json:"fecha"`
+	HoraInicio *string `json:"hora_inicio"`
+	HoraFin    *string `json:"hora_fin"`
+	Espacio    *string `json:"espacio"`
+}
+
+func (p *ProgramacionSesion) validar() error {
+	if _, err := dateOrNil(p.Fecha); err != nil {
+		return errors.New("fecha inválida (formato YYYY-MM-DD)")
+	}
+	var ini, fin time.Time
+	var err error
+	if s := strings.TrimSpace(deref(p.HoraInicio)); s != "" {
+		if ini, err = time.Parse(formatoHora, s); err != nil {
+			return errors.New("hora_inicio inválida (formato HH:MM)")
+		}
+	}
+	if s := strings.TrimSpace(deref(p.HoraFin)); s != "" {
+		if fin, err = time.Parse(formatoHora, s); err != nil {
+			return errors.New("hora_fin inválida (formato HH:MM)")
+		}
+	}
+	if !ini.IsZero() && !fin.IsZero() && !fin.After(ini) {
+		return errors.New("hora_fin debe ser posterior a hora_inicio")
+	}
+	if s := strings.TrimSpace(deref(p.Espacio)); s != "" && !esEspacioValido(s) {
+		return errors.New("espacio inválido (" + strings.Join(espaciosValidos, " | ") + ")")
+	}
+	return nil
+}
+
+// verificarHorarioSesion revisa, ya con el PATCH aplicado, que hora_fin sea
+// posterior a hora_inicio aunque solo una de las dos venga en el body.
+func verificarHorarioSesion(ctx context.Context, tx pgx.Tx, sesionID int64) error {
+	var invalido bool
+	if err := tx.QueryRow(
+		ctx,
+		`SELECT COALESCE(hora_fin <= hora_inicio, false) FROM sesiones_didacticas WHERE id = $1`,
+		sesionID,
+	).Scan(&invalido); err != nil {
+		return err
+	}
+	if invalido {
+		return errValidacion{errors.New("hora_fin debe ser posterior a hora_inicio")}
+	}
+	return nil
+}
+
+// Bloque del horario semanal
+type HorarioBloque struct {
+	ID         int64  `json:"id,omitempty"`
+	DiaSemana  int    `json:"dia_semana"` // 1 = lunes ... 7 = domingo
+	HoraInicio string `json:"hora_inicio"`
+	HoraFin    string `json:"hora_fin"`
+	Espacio    string `json:"espacio"`
+}
+
+type horarioRequest struct {
+	Bloques []HorarioBloque `json:"bloques"`
+}
+
+func (r *horarioRequest) validar() error {
+	type intervalo struct{ ini, fin time.Time }
+	porDia := map[int][]intervalo{}
+	for i := range r.Bloques {
+		b := &r.Bloques[i]
+		b.Espacio = strings.ToLower(strings.TrimSpace(b.Espacio))
+		if b.Espacio == "" {
+			b.Espacio = "aula"
+		}
+		if b.DiaSemana < 1 || b.DiaSemana > 7 {
+			return fmt.Errorf("bloques[%d].dia_semana debe estar entre 1 (lunes) y 7 (domingo)", i)
+		}
+		ini, err := time.Parse(formatoHora, strings.TrimSpace(b.HoraInicio))
+		if err != nil {
+			return fmt.Errorf("bloques[%d].hora_inicio inválida (formato HH:MM)", i)
+		}
+		fin, err := time.Parse(formatoHora, strings.TrimSpace(b.HoraFin))
+		if err != nil {
+			return fmt.Errorf("bloques[%d].hora_fin inválida (formato HH:MM)", i)
+		}
+		if !fin.After(ini) {
+			return fmt.Errorf("bloques[%d]: hora_fin debe ser posterior a hora_inicio", i)
+		}
+		if !esEspacioValido(b.Espacio) {
+			return fmt.Errorf("bloques[%d].espacio inválido (%s)", i, strings.Join(espaciosValidos, " | "))
+		}
+		for _, o := range porDia[b.DiaSemana] {
+			if ini.Before(o.fin) && o.ini.Before(fin) {
+				return fmt.Errorf("bloques[%d] se empalma con otro bloque del mismo día", i)
+			}
+		}
+		porDia[b.DiaSemana] = append(porDia[b.DiaSemana], intervalo{ini, fin})
+		b.HoraInicio, b.HoraFin = ini.Format(formatoHora), fin.Format(formatoHora)
+	}
+	return nil
+}
+
+func cargarHorario(ctx context.Context, db consultaDB, planeacionID int) ([]HorarioBloque, error) {
+	rows, err := db.Query(
+		ctx,
+		`SELECT id, dia_semana, to_char(hora_inicio, 'HH24:MI'), to_char(hora_fin, 'HH24:MI'), espacio
+		 FROM planeacion_horario WHERE planeacion_id = $1
+		 ORDER BY dia_semana, hora_inicio`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []HorarioBloque{}
+	for rows.Next() {
+		var b HorarioBloque
+		if err := rows.Scan(&b.ID, &b.DiaSemana, &b.HoraInicio, &b.HoraFin, &b.Espacio); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// =============================
+// GET /api/planeaciones/:id/horario
+// =============================
+func (h *PlaneacionesHandler) GetHorario(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var existe bool
+	if err := h.DB.QueryRow(
+		c,
+		`SELECT EXISTS (SELECT 1 FROM planeaciones WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL)`,
+		id,
+		claims.UserID,
+	).Scan(&existe); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if !existe {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+		return
+	}
+
+	bloques, err := cargarHorario(c, h.DB, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bloques": bloques})
+}
+
+// =============================
+// PUT /api/planeaciones/:id/horario
+// Body: { "bloques": [ { "dia_semana": 1, "hora_inicio": "07:00", "hora_fin": "08:30", "espacio": "aula" } ] }
+// Reemplaza el horario completo (no cambia fechas ya asignadas).
+// =============================
+func (h *PlaneacionesHandler) PutHorario(c *gin.Context) {
+	var body horarioRequest
+	h.editarUnidades(c, &body, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		if err := body.validar(); err != nil {
+			return nil, errValidacion{err}
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM planeacion_horario WHERE planeacion_id = $1`, id); err != nil {
+			return nil, err
+		}
+		for _, b := range body.Bloques {
+			if _, err := tx.Exec(
+				ctx,
+				`INSERT INTO planeacion_horario (planeacion_id, dia_semana, hora_inicio, hora_fin, espacio)
+				 VALUES ($1, $2, $3::time, $4::time, $5)`,
+				id,
+				b.DiaSemana,
+				b.HoraInicio,
+				b.HoraFin,
+				b.Espacio,
+			); err != nil {
+				return nil, err
+			}
+		}
+
+		bloques, err := cargarHorario(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(gin.H{"bloques": bloques})
+	})
+}
+
+// Espacio de clase concreto (fecha + bloque del horario)
+type espacioClase struct {
+	fecha  time.Time
+	bloque HorarioBloque
+}
+
+// espaciosDeClase: ocurrencias del horario entre del y al, en orden cronológico.
+// Con calendario se omiten días fuera del periodo e inhábiles.
+func espaciosDeClase(horario []HorarioBloque, cal *calendarioPeriodo, del, al time.Time) []espacioClase {
+	var out []espacioClase
+	for t := del; !t.After(al); t = t.AddDate(0, 0, 1) {
+		if cal != nil && !cal.esHabil(t) {
+			continue
+		}
+		dia := int(t.Weekday())
+		if dia == 0 {
+			dia = 7
+		}
+		for _, b := range horario { // ya ordenado por día y hora
+			if b.DiaSemana == dia {
+				out = append(out, espacioClase{fecha: t, bloque: b})
+			}
+		}
+	}
+	return out
+}
+
+// Resultado de la programación de una unidad
+type programacionUnidad struct {
+	Numero       int            `json:"numero"`
+	PeriodoDel   *string        `json:"periodo_del"`
+	PeriodoAl    *string        `json:"periodo_al"`
+	Sesiones     int            `json:"sesiones"`
+	Disponibles  int            `json:"espacios_disponibles"`
+	Programadas  int            `json:"programadas"`
+	Conservadas  int            `json:"conservadas"` // ya tenían fecha (sin ?reemplazar=1)
+	Excedentes   []int          `json:"excedentes"`  // numero_sesion sin fecha
+	PorEspacio   map[string]int `json:"disponibles_por_espacio"`
+	Advertencias []string       `json:"advertencias"`
+}
+
+// unidadProgramable: unidad con lo planeado por espacio y sus sesiones en orden
+type unidadProgramable struct {
+	id       int64
+	numero   int
+	del, al  *string
+	planeado map[string]int
+	sesiones []sesionProgramable
+}
+
+type sesionProgramable struct {
+	id         int64
+	numero     int
+	fecha      *string // ya asignada (a mano o en una programación anterior)
+	horaInicio *string
+}
+
+func cargarUnidadesProgramables(ctx context.Context, tx pgx.Tx, planeacionID int) ([]*unidadProgramable, error) {
+	rows, err := tx.Query(
+		ctx,
+		`
+SELECT ut.id, ut.numero,
+       to_char(ut.periodo_del, 'YYYY-MM-DD'), to_char(ut.periodo_al, 'YYYY-MM-DD'),
+       COALESCE(ut.sesiones_aula, 0), COALESCE(ut.sesiones_laboratorio, 0), COALESCE(ut.sesiones_taller, 0),
+       COALESCE(ut.sesiones_clinica, 0), COALESCE(ut.sesiones_otro, 0),
+       sd.id, sd.numero_sesion, to_char(sd.fecha, 'YYYY-MM-DD'), to_char(sd.hora_inicio, 'HH24:MI')
+FROM unidades_tematicas ut
+LEFT JOIN sesiones_didacticas sd ON sd.unidad_tematica_id = ut.id
+WHERE ut.planeacion_id = $1
+ORDER BY ut.numero, sd.numero_sesion
+		`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*unidadProgramable
+	for rows.Next() {
+		var (
+			u                          unidadProgramable
+			aula, lab, taller, cl, otr int
+			sesionID                   *int64
+			sesionNum                  *int
+			sesionFecha, sesionHora    *string
+		)
+		if err := rows.Scan(&u.id, &u.numero, &u.del, &u.al, &aula, &lab, &taller, &cl, &otr, &sesionID, &sesionNum, &sesionFecha, &sesionHora); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].id != u.id {
+			u.planeado = map[string]int{"aula": aula, "laboratorio": lab, "taller": taller, "clinica": cl, "otro": otr}
+			out = append(out, &u)
+		}
+		if sesionID != nil {
+			ult := out[len(out)-1]
+			ult.sesiones = append(ult.sesiones, sesionProgramable{id: *sesionID, numero: *sesionNum, fecha: sesionFecha, horaInicio: sesionHora})
+		}
+	}
+	return out, rows.Err()
+}
+
+// =============================
+// POST /api/planeaciones/:id/programar[?reemplazar=1]
+// Asigna fecha, horario y espacio a cada sesión sin fecha, en orden, con los
+// espacios del horario semanal dentro del periodo de desarrollo de su unidad.
+// Las sesiones que ya tienen fecha se conservan y ocupan su espacio; con
+// ?reemplazar=1 se reprograman todas. Las que no caben quedan sin fecha y se
+// reportan como excedentes.
+// =============================
+func (h *PlaneacionesHandler) Programar(c *gin.Context) {
+	reemplazar := c.Query("reemplazar") == "1" || c.Query("reemplazar") == "true"
+
+	h.editarUnidades(c, nil, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		horario, err := cargarHorario(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(horario) == 0 {
+			return nil, errValidacion{errors.New("Registra el horario semanal del grupo antes de programar las sesiones.")}
+		}
+
+		cal, err := calendarioDePlaneacion(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		unidades, err := cargarUnidadesProgramables(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Un mismo espacio no se usa dos veces si los periodos de las unidades se traslapan
+		usados := map[string]bool{}
+		// Sin ?reemplazar=1, los espacios de las sesiones con fecha ya están
+		// ocupados (clave → unidad dueña)
+		fijos := map[string]int64{}
+		if !reemplazar {
+			for _, u := range unidades {
+				for _, s := range u.sesiones {
+					if s.fecha != nil && s.horaInicio != nil {
+						fijos[*s.fecha+" "+*s.horaInicio] = u.id
+					}
+				}
+			}
+		}
+		resultados := make([]programacionUnidad, 0, len(unidades))
+		desborde := false
+
+		for _, u := range unidades {
+			r := programacionUnidad{
+				Numero:       u.numero,
+				PeriodoDel:   u.del,
+				PeriodoAl:    u.al,
+				Sesiones:     len(u.sesiones),
+				Excedentes:   []int{},
+				PorEspacio:   map[string]int{},
+				Advertencias: []string{},
+			}
+
+			var espacios []espacioClase
+			propios := 0 // espacios del periodo que ya ocupan sesiones de esta unidad
+			if u.del == nil || u.al == nil {
+				r.Advertencias = append(r.Advertencias, "La unidad no tiene periodo de desarrollo: sus sesiones quedan sin fecha.")
+			} else {
+				del, err1 := parseFecha(*u.del)
+				al, err2 := parseFecha(*u.al)
+				if err := errors.Join(err1, err2); err != nil {
+					return nil, err
+				}
+				for _, e := range espaciosDeClase(horario, cal, del, al) {
+					clave := e.fecha.Format(formatoFecha) + " " + e.bloque.HoraInicio
+					if dueno, ok := fijos[clave]; ok {
+						if dueno == u.id {
+							propios++
+							r.PorEspacio[e.bloque.Espacio]++
+						}
+						continue
+					}
+					if usados[clave] {
+						continue
+					}
+					espacios = append(espacios, e)
+				}
+			}
+			r.Disponibles = len(espacios) + propios
+			for _, e := range espacios {
+				r.PorEspacio[e.bloque.Espacio]++
+			}
+
+			i := 0
+			for _, s := range u.sesiones {
+				if !reemplazar && s.fecha != nil {
+					r.Conservadas++
+					continue
+				}
+				if i >= len(espacios) {
+					r.Excedentes = append(r.Excedentes, s.numero)
+					if !reemplazar {
+						continue // ya no tiene fecha; se conserva lo demás
+					}
+					if _, err := tx.Exec(
+						ctx,
+						`UPDATE sesiones_didacticas SET fecha = NULL, hora_inicio = NULL, hora_fin = NULL, espacio = NULL WHERE id = $1`,
+						s.id,
+					); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				e := espacios[i]
+				i++
+				usados[e.fecha.Format(formatoFecha)+" "+e.bloque.HoraInicio] = true
+				if _, err := tx.Exec(
+					ctx,
+					`UPDATE sesiones_didacticas
+					 SET fecha = $2::date, hora_inicio = $3::time, hora_fin = $4::time, espacio = $5
+					 WHERE id = $1`,
+					s.id,
+					e.fecha.Format(formatoFecha),
+					e.bloque.HoraInicio,
+					e.bloque.HoraFin,
+					e.bloque.Espacio,
+				); err != nil {
+					return nil, err
+				}
+				r.Programadas++
+			}
+
+			if len(r.Excedentes) > 0 && u.del != nil && u.al != nil {
+				desborde = true
+				r.Advertencias = append(r.Advertencias, fmt.Sprintf(
+					"Hay %d sesiones planeadas y solo %d espacios de clase en el periodo de la unidad.",
+					r.Sesiones, r.Disponibles,
+				))
+			}
+
+			// Lo planeado por espacio (sesiones_por_espacio) contra lo que ofrece el horario
+			nombres := make([]string, 0, len(u.planeado))
+			for esp := range u.planeado {
+				nombres = append(nombres, esp)
+			}
+			sort.Strings(nombres)
+			for _, esp := range nombres {
+				if plan := u.planeado[esp]; plan > r.PorEspacio[esp] {
+					r.Advertencias = append(r.Advertencias, fmt.Sprintf(
+						"Se planearon %d sesiones de %s y el horario solo ofrece %d en el periodo.",
+						plan, esp, r.PorEspacio[esp],
+					))
+				}
+			}
+
+			resultados = append(resultados, r)
+		}
+
+		resp := gin.H{
+			"calendario": cal != nil,
+			"desborde":   desborde,
+			"unidades":   resultados,
+		}
+		if cal != nil {
+			resp["periodo"] = cal.periodo.Clave
+		}
+		return json.Marshal(resp)
+	})
+}
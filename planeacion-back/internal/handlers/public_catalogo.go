@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================
+// Catálogo público con facetas
+// GET /api/public/catalogo
+// =============================
+
+// Facetas de texto (planeacion_datos_generales). La clave es el nombre del
+// query param y de la faceta en la respuesta.
+var catalogoFacetas = []struct {
+	Key  string
+	Expr string
+}{
+	{"programa", "dg.programa_academico"},
+	{"academia", "dg.academia"},
+	{"semestre", "dg.semestre_nivel"},
+	{"modalidad", "dg.modalidad"},
+	{"area", "dg.area_formacion"},
+	{"periodo", "dg.periodo"},
+}
+
+// Cursor de paginación keyset (opaco para el cliente)
+type catalogoCursor struct {
+	Sort   string     `json:"s"`
+	Fecha  *time.Time `json:"f,omitempty"`
+	Nombre string     `json:"n,omitempty"`
+	ID     int64      `json:"id"`
+}
+
+func encodeCatalogoCursor(cur catalogoCursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCatalogoCursor(s string) (*catalogoCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur catalogoCursor
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// Acumulador de argumentos posicionales ($1, $2, ...)
+type sqlArgs struct {
+	vals []any
+}
+
+func (a *sqlArgs) add(v any) string {
+	a.vals = append(a.vals, v)
+	return "$" + strconv.Itoa(len(a.vals))
+}
+
+// Valores de un filtro: admite ?programa=a&programa=b y ?programa=a,b
+func queryList(c *gin.Context, key string) []string {
+	out := []string{}
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// GET /api/public/catalogo?ua=1&programa=...&semestre=...&q=...&sort=recientes|nombre&limit=20&cursor=...
+// Filtros: ua, programa, academia, semestre, modalidad, area, periodo (repetibles o separados por coma) y q.
+//   - Sin filtros lista todo lo publicado (status='finalizada', o archivada si se publicó).
+//   - Cada faceta cuenta con todos los filtros aplicados excepto el suyo,
+//     así el cliente puede mostrar alternativas dentro de la misma faceta.
+//   - Paginación keyset: usar next_cursor de la respuesta anterior.
+func (h *PublicPlaneacionesHandler) Catalog(c *gin.Context) {
+	sort := strings.TrimSpace(strings.ToLower(c.DefaultQuery("sort", "recientes")))
+	if sort != "recientes" && sort != "nombre" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort inválido (usa 'recientes' o 'nombre')"})
+		return
+	}
+
+	limit := 20
+	if v := strings.TrimSpace(c.Query("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 100"})
+			return
+		}
+		limit = n
+	}
+
+	var cursor *catalogoCursor
+	if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+		cur, err := decodeCatalogoCursor(v)
+		if err != nil || cur.Sort != sort || (sort == "recientes" && cur.Fecha == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor inválido"})
+			return
+		}
+		cursor = cur
+	}
+
+	uaIDs := []int32{}
+	for _, v := range queryList(c, "ua") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ua debe ser un id de unidad académica"})
+			return
+		}
+		uaIDs = append(uaIDs, int32(n))
+	}
+
+	filtros := map[string][]string{}
+	for _, f := range catalogoFacetas {
+		if vals := queryList(c, f.Key); len(vals) > 0 {
+			filtros[f.Key] = vals
+		}
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+
+	// WHERE con todos los filtros, salvo el de la faceta "exclude"
+	buildWhere := func(args *sqlArgs, exclude string) string {
+		where := []string{sqlPublicada, "p.deleted_at IS NULL"}
+		if q != "" {
+			where = append(where, "p.search_tsv @@ websearch_to_tsquery('public.es_unaccent', "+args.add(q)+")")
+		}
+		if exclude != "ua" && len(uaIDs) > 0 {
+			where = append(where, "p.unidad_academica_id = ANY("+args.add(uaIDs)+")")
+		}
+		for _, f := range catalogoFacetas {
+			if f.Key == exclude {
+				continue
+			}
+			if vals, ok := filtros[f.Key]; ok {
+				where = append(where, f.Expr+" = ANY("+args.add(vals)+")")
+			}
+		}
+		return strings.Join(where, " AND ")
+	}
+
+	const from = `
+FROM planeaciones p
+JOIN usuarios u ON u.id = p.docente_id
+JOIN unidades_academicas ua ON ua.id = p.unidad_academica_id
+LEFT JOIN planeacion_datos_generales dg ON dg.planeacion_id = p.id
+`
+
+	ctx := c.Request.Context()
+
+	// ─────────────────────────────
+	// Items (keyset)
+	// ─────────────────────────────
+	args := &sqlArgs{}
+	where := buildWhere(args, "")
+
+	fechaExpr := "COALESCE(p.finalizada_at, p.updated_at)"
+	var orderBy string
+	switch sort {
+	case "nombre":
+		orderBy = "lower(p.nombre_planeacion) ASC, p.id ASC"
+		if cursor != nil {
+			where += " AND (lower(p.nombre_planeacion), p.id) > (" + args.add(cursor.Nombre) + ", " + args.add(cursor.ID) + ")"
+		}
+	default:
+		orderBy = fechaExpr + " DESC, p.id DESC"
+		if cursor != nil {
+			where += " AND (" + fechaExpr + ", p.id) < (" + args.add(*cursor.Fecha) + ", " + args.add(cursor.ID) + ")"
+		}
+	}
+
+	// limit+1 para saber si hay otra página
+	itemsSQL := `
+SELECT
+  p.id,
+  p.nombre_planeacion,
+  COALESCE(p.asignatura,'') AS unidad_aprendizaje,
+  u.nombre_completo AS profesor,
+  ua.id,
+  ua.nombre,
+  COALESCE(ua.abreviatura,''),
+  dg.programa_academico,
+  dg.academia,
+  dg.semestre_nivel,
+  dg.modalidad,
+  dg.area_formacion,
+  dg.periodo,
+  ` + fechaExpr + ` AS fecha,
+  lower(p.nombre_planeacion) AS nombre_key,
+  COALESCE(p.slug,'') AS slug,
+  p.status = 'archivada' AS historica
+` + from + `
+WHERE ` + where + `
+ORDER BY ` + orderBy + `
+LIMIT ` + args.add(limit+1)
+
+	rows, err := h.DB.Query(ctx, itemsSQL, args.vals...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	items := []gin.H{}
+	var last catalogoCursor
+	hasMore := false
+	for rows.Next() {
+		var (
+			id                                            int64
+			nombre, unidadApr, prof                       string
+			uaID                                          int
+			uaNombre, uaAbrev                             string
+			programa, academia, semestre, modalidad, area *string
+			periodo                                       *string
+			fecha                                         time.Time
+			nombreKey, slug                               string
+			historica                                     bool
+		)
+		if err := rows.Scan(
+			&id, &nombre, &unidadApr, &prof,
+			&uaID, &uaNombre, &uaAbrev,
+			&programa, &academia, &semestre, &modalidad, &area, &periodo,
+			&fecha, &nombreKey, &slug, &historica,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo filas: " + err.Error()})
+			return
+		}
+
+		if len(items) == limit {
+			// fila extra: solo indica que hay otra página
+			hasMore = true
+			continue
+		}
+
+		items = append(items, gin.H{
+			"id":                           id,
+			"nombre_planeacion":            nombre,
+			"unidad_aprendizaje":           unidadApr,
+			"profesor":                     prof,
+			"unidad_academica_id":          uaID,
+			"unidad_academica":             uaNombre,
+			"unidad_academica_abreviatura": uaAbrev,
+			"programa_academico":           programa,
+			"academia":                     academia,
+			"semestre_nivel":               semestre,
+			"modalidad":                    modalidad,
+			"area_formacion":               area,
+			"periodo_escolar":              periodo,
+			"publicada_at":                 fecha,
+			"slug":                         slug,
+			"historica":                    historica,
+		})
+
+		f := fecha
+		last = catalogoCursor{Sort: sort, Fecha: &f, Nombre: nombreKey, ID: id}
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en cursor: " + err.Error()})
+		return
+	}
+
+	var nextCursor *string
+	if hasMore {
+		if sort == "nombre" {
+			last.Fecha = nil
+		} else {
+			last.Nombre = ""
+		}
+		s := encodeCatalogoCursor(last)
+		nextCursor = &s
+	}
+
+	// ─────────────────────────────
+	// Total con todos los filtros
+	// ─────────────────────────────
+	totalArgs := &sqlArgs{}
+	var total int64
+	if err := h.DB.QueryRow(
+		ctx,
+		`SELECT COUNT(*) `+from+` WHERE `+buildWhere(totalArgs, ""),
+		totalArgs.vals...,
+	).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	// ─────────────────────────────
+	// Facetas
+	// ─────────────────────────────
+	facets := gin.H{}
+
+	uaArgs := &sqlArgs{}
+	uaRows, err := h.DB.Query(
+		ctx,
+		`SELECT ua.id, ua.nombre, COALESCE(ua.abreviatura,''), COUNT(*) `+from+`
+		 WHERE `+buildWhere(uaArgs, "ua")+`
+		 GROUP BY ua.id, ua.nombre, ua.abreviatura
+		 ORDER BY COUNT(*) DESC, ua.nombre`,
+		uaArgs.vals...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	uaFacet := []gin.H{}
+	for uaRows.Next() {
+		var (
+			id            int
+			nombre, abrev string
+			n             int64
+		)
+		if err := uaRows.Scan(&id, &nombre, &abrev, &n); err != nil {
+			uaRows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo facetas: " + err.Error()})
+			return
+		}
+		uaFacet = append(uaFacet, gin.H{"id": id, "nombre": nombre, "abreviatura": abrev, "total": n})
+	}
+	uaRows.Close()
+	if err := uaRows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en facetas: " + err.Error()})
+		return
+	}
+	facets["ua"] = uaFacet
+
+	for _, f := range catalogoFacetas {
+		fArgs := &sqlArgs{}
+		fRows, err := h.DB.Query(
+			ctx,
+			`SELECT `+f.Expr+`, COUNT(*) `+from+`
+			 WHERE `+buildWhere(fArgs, f.Key)+` AND `+f.Expr+` IS NOT NULL AND `+f.Expr+` <> ''
+			 GROUP BY 1
+			 ORDER BY COUNT(*) DESC, 1`,
+			fArgs.vals...,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+			return
+		}
+
+		vals := []gin.H{}
+		for fRows.Next() {
+			var (
+				valor string
+				n     int64
+			)
+			if err := fRows.Scan(&valor, &n); err != nil {
+				fRows.Close()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo facetas: " + err.Error()})
+				return
+			}
+			vals = append(vals, gin.H{"valor": valor, "total": n})
+		}
+		fRows.Close()
+		if err := fRows.Err(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en facetas: " + err.Error()})
+			return
+		}
+		facets[f.Key] = vals
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":       items,
+		"facets":      facets,
+		"total":       total,
+		"sort":        sort,
+		"limit":       limit,
+		"next_cursor": nextCursor,
+	})
+}
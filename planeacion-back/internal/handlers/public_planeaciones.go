@@ -3,9 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"html"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
@@ -16,20 +18,48 @@ type PublicPlaneacionesHandler struct {
 	DB *pgxpool.Pool
 }
 
-// GET /api/public/planeaciones?profesor=...&unidad=...&ua=...&limit=20&offset=0
-// - profesor: usuarios.nombre_completo ILIKE
-// - unidad: planeaciones.asignatura ILIKE
-// - ua: unidades_academicas.nombre o abreviatura ILIKE (opcional)
-// - SOLO status='finalizada'
+// GET /api/public/planeaciones?q=...&profesor=...&unidad=...&ua=...&programa=...&periodo=...&limit=20&offset=0
+//   - q: texto completo (español, sin acentos) sobre nombre, asignatura, unidades temáticas,
+//     unidad de competencia, aprendizajes esperados, temas/subtemas y referencias
+//     (sintaxis websearch: "frase exacta", -excluir, or)
+//   - profesor: usuarios.nombre_completo ILIKE
+//   - unidad: planeaciones.asignatura ILIKE
+//   - ua: unidades_academicas.nombre o abreviatura ILIKE (opcional)
+//   - programa / academia / periodo: planeacion_datos_generales ILIKE (programa, academia y unidad
+//     también coinciden por el nombre del catálogo ligado, así que encuentran variantes de escritura)
+//   - programa_id / academia_id / unidad_aprendizaje_id: referencia exacta al catálogo académico
+//   - los filtros se combinan con AND
+//   - SOLO publicadas (finalizada, o archivada si se publicó; "historica": true)
+//
+// Con q: orden por relevancia e incluye "rank" y "snippet" (coincidencias entre <mark></mark>).
 func (h *PublicPlaneacionesHandler) Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
 	profesor := strings.TrimSpace(c.Query("profesor"))
 	unidad := strings.TrimSpace(c.Query("unidad"))
 	ua := strings.TrimSpace(c.Query("ua"))
+	programa := strings.TrimSpace(c.Query("programa"))
+	academia := strings.TrimSpace(c.Query("academia"))
+	periodo := strings.TrimSpace(c.Query("periodo"))
+
+	// Filtros por catálogo
+	catalogo := map[string]int64{}
+	for _, f := range []string{"programa_id", "academia_id", "unidad_aprendizaje_id"} {
+		v := strings.TrimSpace(c.Query(f))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f + " inválido"})
+			return
+		}
+		catalogo[f] = n
+	}
 
 	// Evita listar TODO sin filtro (público)
-	if profesor == "" && unidad == "" && ua == "" {
+	if q == "" && profesor == "" && unidad == "" && ua == "" && programa == "" && academia == "" && periodo == "" && len(catalogo) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Debes enviar al menos un filtro: profesor, unidad o ua",
+			"error": "Debes enviar al menos un filtro: q, profesor, unidad, ua, programa, academia, periodo o un id de catálogo",
 		})
 		return
 	}
@@ -47,17 +77,33 @@ func (h *PublicPlaneacionesHandler) Search(c *gin.Context) {
 		}
 	}
 
-	where := []string{"p.status = 'finalizada'"}
+	where := []string{sqlPublicada, "p.deleted_at IS NULL"}
 	args := []any{}
 	argN := 1
 
+	// rank / snippet solo tienen sentido con q
+	rankExpr := "0::real"
+	snippetExpr := "''"
+	orderBy := "p.updated_at DESC"
+
+	if q != "" {
+		tsq := "websearch_to_tsquery('public.es_unaccent', $" + strconv.Itoa(argN) + ")"
+		where = append(where, "p.search_tsv @@ "+tsq)
+		rankExpr = "ts_rank_cd(p.search_tsv, " + tsq + ")"
+		snippetExpr = "ts_headline('public.es_unaccent', COALESCE(p.search_text,''), " + tsq +
+			", 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter= … ')"
+		orderBy = "rank DESC, p.updated_at DESC"
+		args = append(args, q)
+		argN++
+	}
 	if profesor != "" {
 		where = append(where, "u.nombre_completo ILIKE $"+strconv.Itoa(argN))
 		args = append(args, "%"+profesor+"%")
 		argN++
 	}
 	if unidad != "" {
-		where = append(where, "COALESCE(p.asignatura,'') ILIKE $"+strconv.Itoa(argN))
+		where = append(where, "(COALESCE(p.asignatura,'') ILIKE $"+strconv.Itoa(argN)+
+			" OR EXISTS (SELECT 1 FROM unidades_aprendizaje cu WHERE cu.id = p.unidad_aprendizaje_id AND cu.nombre ILIKE $"+strconv.Itoa(argN)+"))")
 		args = append(args, "%"+unidad+"%")
 		argN++
 	}
@@ -66,6 +112,30 @@ func (h *PublicPlaneacionesHandler) Search(c *gin.Context) {
 		args = append(args, "%"+ua+"%")
 		argN++
 	}
+	if programa != "" {
+		where = append(where, "(COALESCE(dg.programa_academico,'') ILIKE $"+strconv.Itoa(argN)+
+			" OR EXISTS (SELECT 1 FROM programas_academicos cp WHERE cp.id = p.programa_id AND cp.nombre ILIKE $"+strconv.Itoa(argN)+"))")
+		args = append(args, "%"+programa+"%")
+		argN++
+	}
+	if academia != "" {
+		where = append(where, "(COALESCE(dg.academia,'') ILIKE $"+strconv.Itoa(argN)+
+			" OR EXISTS (SELECT 1 FROM academias ca WHERE ca.id = p.academia_id AND ca.nombre ILIKE $"+strconv.Itoa(argN)+"))")
+		args = append(args, "%"+academia+"%")
+		argN++
+	}
+	if periodo != "" {
+		where = append(where, "COALESCE(dg.periodo, p.periodo, '') ILIKE $"+strconv.Itoa(argN))
+		args = append(args, "%"+periodo+"%")
+		argN++
+	}
+	for _, f := range []string{"programa_id", "academia_id", "unidad_aprendizaje_id"} {
+		if v, ok := catalogo[f]; ok {
+			where = append(where, "p."+f+" = $"+strconv.Itoa(argN))
+			args = append(args, v)
+			argN++
+		}
+	}
 
 	// limit/offset
 	args = append(args, limit, offset)
@@ -79,12 +149,16 @@ SELECT
   ua.nombre AS unidad_academica,
   ua.abreviatura AS unidad_academica_abreviatura,
   p.updated_at,
-  COALESCE(p.slug,'') AS slug
+  COALESCE(p.slug,'') AS slug,
+  p.status = 'archivada' AS historica,
+  ` + rankExpr + ` AS rank,
+  ` + snippetExpr + ` AS snippet
 FROM planeaciones p
 JOIN usuarios u ON u.id = p.docente_id
 JOIN unidades_academicas ua ON ua.id = p.unidad_academica_id
+LEFT JOIN planeacion_datos_generales dg ON dg.planeacion_id = p.id
 WHERE ` + strings.Join(where, " AND ") + `
-ORDER BY p.updated_at DESC
+ORDER BY ` + orderBy + `
 LIMIT $` + strconv.Itoa(argN) + ` OFFSET $` + strconv.Itoa(argN+1)
 
 	rows, err := h.DB.Query(c, sql, args...)
@@ -105,23 +179,37 @@ LIMIT $` + strconv.Itoa(argN) + ` OFFSET $` + strconv.Itoa(argN+1)
 			uaAbrev      *string
 			updatedAtAny any
 			slug         string
+			historica    bool
+			rank         float32
+			snippet      string
 		)
 
-		if err := rows.Scan(&id, &nombre, &unidadApr, &prof, &uaNombre, &uaAbrev, &updatedAtAny, &slug); err != nil {
+		if err := rows.Scan(&id, &nombre, &unidadApr, &prof, &uaNombre, &uaAbrev, &updatedAtAny, &slug, &historica, &rank, &snippet); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo filas: " + err.Error()})
 			return
 		}
 
-		items = append(items, gin.H{
+		uaAbreviatura := ""
+		if uaAbrev != nil {
+			uaAbreviatura = *uaAbrev
+		}
+
+		item := gin.H{
 			"id":                           id,
 			"nombre_planeacion":            nombre,
 			"unidad_aprendizaje":           unidadApr,
 			"profesor":                     prof,
 			"unidad_academica":             uaNombre,
-			"unidad_academica_abreviatura": func() string { if uaAbrev == nil { return "" }; return *uaAbrev }(),
+			"unidad_academica_abreviatura": uaAbreviatura,
 			"updated_at":                   updatedAtAny,
 			"slug":                         slug,
-		})
+			"historica":                    historica, // archivada: de un periodo anterior
+		}
+		if q != "" {
+			item["rank"] = rank
+			item["snippet"] = escapeSnippet(snippet)
+		}
+		items = append(items, item)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -136,6 +224,15 @@ LIMIT $` + strconv.Itoa(argN) + ` OFFSET $` + strconv.Itoa(argN+1)
 	})
 }
 
+// escapeSnippet escapa el HTML del contenido del docente y conserva solo
+// las marcas <mark></mark> que agrega ts_headline.
+func escapeSnippet(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "&lt;mark&gt;", "<mark>")
+	s = strings.ReplaceAll(s, "&lt;/mark&gt;", "</mark>")
+	return s
+}
+
 // GET /api/public/planeaciones/:id
 // Detalle público completo:
 // - sin JWT
@@ -147,6 +244,33 @@ func (h *PublicPlaneacionesHandler) GetOne(c *gin.Context) {
 		return
 	}
 
+	// Versión publicada (ETag / Last-Modified) antes de armar el documento
+	var (
+		updatedAt time.Time
+		eliminada bool
+	)
+	err = h.DB.QueryRow(
+		c,
+		`SELECT COALESCE(`+sqlVersionPublica+`, p.updated_at), p.deleted_at IS NOT NULL FROM planeaciones p
+		 WHERE p.id = $1 AND (`+sqlPublicada+` OR `+sqlEliminadaPublicada+`)`,
+		id,
+	).Scan(&updatedAt, &eliminada)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada (o no publicada)"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if eliminada {
+		responderPlaneacionEliminada(c)
+		return
+	}
+	if responderNoModificado(c, etagVersion("planeacion", int64(id), updatedAt), updatedAt) {
+		return
+	}
+
 	row := h.DB.QueryRow(
 		c,
 		`
@@ -217,7 +341,20 @@ SELECT json_build_object(
           'id', r.id,
           'cita_apa', r.cita_apa,
           'unidades_aplica', r.unidades_aplica,
-          'tipo', r.tipo
+          'tipo', r.tipo,
+          'tipo_fuente', r.tipo_fuente,
+          'autores', r.autores,
+          'anio', r.anio,
+          'titulo', r.titulo,
+          'fuente', r.fuente,
+          'editorial', r.editorial,
+          'edicion', r.edicion,
+          'volumen', r.volumen,
+          'numero', r.numero,
+          'paginas', r.paginas,
+          'doi', r.doi,
+          'isbn', r.isbn,
+          'url', r.url
         )
         ORDER BY r.id
       )
@@ -274,7 +411,11 @@ SELECT json_build_object(
                   'recursos', sd.recursos,
                   'evidencias', sd.evidencias,
                   'instrumentos', sd.instrumentos,
-                  'valor_porcentual', sd.valor_porcentual
+                  'valor_porcentual', sd.valor_porcentual,
+                  'fecha', sd.fecha,
+                  'hora_inicio', to_char(sd.hora_inicio, 'HH24:MI'),
+                  'hora_fin', to_char(sd.hora_fin, 'HH24:MI'),
+                  'espacio', sd.espacio
                 )
                 ORDER BY sd.numero_sesion
               )
@@ -291,6 +432,18 @@ SELECT json_build_object(
     ),
     '[]'::json
   )
+)::jsonb || jsonb_build_object(
+  -- Datos generales (json_build_object admite máx. 100 argumentos)
+  'fecha_elaboracion', dg.fecha_elaboracion,
+  'tipo_unidad', dg.tipo_unidad,
+  'semanas_por_semestre', dg.semanas_por_semestre,
+  'docente_autor', dg.docente_autor,
+  'programa_id', p.programa_id,
+  'academia_id', p.academia_id,
+  'unidad_aprendizaje_id', p.unidad_aprendizaje_id,
+  -- Archivada: se publicó en un periodo anterior
+  'historica', p.status = 'archivada',
+  'archivada_at', p.archivada_at
 )
 FROM planeaciones p
 JOIN usuarios u ON u.id = p.docente_id
@@ -300,7 +453,8 @@ LEFT JOIN planeacion_relaciones_ejes re ON re.planeacion_id = p.id
 LEFT JOIN planeacion_organizacion org ON org.planeacion_id = p.id
 LEFT JOIN planeacion_plagio pl ON pl.planeacion_id = p.id
 WHERE p.id = $1
-  AND p.status = 'finalizada'
+  AND `+sqlPublicada+`
+  AND p.deleted_at IS NULL
 		`,
 		id,
 	)
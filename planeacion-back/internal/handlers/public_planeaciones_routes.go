@@ -5,7 +5,10 @@ import "github.com/gin-gonic/gin"
 // Registro de rutas públicas: /api/public/planeaciones
 func RegisterPublicPlaneacionesRoutes(rg *gin.RouterGroup, h *PublicPlaneacionesHandler) {
 	g := rg.Group("/public/planeaciones")
-	g.GET("", h.Search)              // GET /api/public/planeaciones?profesor=&unidad=&ua=
-	g.GET("/:id", h.GetOne)          // GET /api/public/planeaciones/:id
-	g.GET("/slug/:slug", h.GetBySlug) // GET /api/public/planeaciones/slug/:slug
+	g.GET("", h.Search)                                    // GET /api/public/planeaciones?q=&profesor=&unidad=&ua=&programa=&academia=&periodo=
+	g.GET("/:id", h.GetOne)                                // GET /api/public/planeaciones/:id
+	g.GET("/slug/:slug", h.GetBySlug)                      // GET /api/public/planeaciones/slug/:slug
+	g.GET("/slug/:slug/calendar.ics", h.CalendarICSBySlug) // GET /api/public/planeaciones/slug/:slug/calendar.ics
+
+	rg.GET("/public/catalogo", h.Catalog) // GET /api/public/catalogo?ua=&programa=&sort=&cursor=
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
@@ -19,6 +20,41 @@ func (h *PublicPlaneacionesHandler) GetBySlug(c *gin.Context) {
 		return
 	}
 
+	// Versión publicada (ETag / Last-Modified) antes de armar el documento
+	var (
+		id        int64
+		updatedAt time.Time
+		eliminada bool
+	)
+	err := h.DB.QueryRow(
+		c,
+		`SELECT p.id, COALESCE(`+sqlVersionPublica+`, p.updated_at), p.deleted_at IS NOT NULL FROM planeaciones p
+		 WHERE p.slug = $1 AND (`+sqlPublicada+` OR `+sqlEliminadaPublicada+`) LIMIT 1`,
+		slug,
+	).Scan(&id, &updatedAt, &eliminada)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Slug anterior de una planeación renombrada: 301 al actual
+			if ok, err := h.redirigirSlugAnterior(c, slug); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+				return
+			} else if ok {
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada (o no publicada)"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if eliminada {
+		responderPlaneacionEliminada(c)
+		return
+	}
+	if responderNoModificado(c, etagVersion("planeacion", id, updatedAt), updatedAt) {
+		return
+	}
+
 	row := h.DB.QueryRow(
 		c,
 		`
@@ -89,7 +125,20 @@ SELECT json_build_object(
           'id', r.id,
           'cita_apa', r.cita_apa,
           'unidades_aplica', r.unidades_aplica,
-          'tipo', r.tipo
+          'tipo', r.tipo,
+          'tipo_fuente', r.tipo_fuente,
+          'autores', r.autores,
+          'anio', r.anio,
+          'titulo', r.titulo,
+          'fuente', r.fuente,
+          'editorial', r.editorial,
+          'edicion', r.edicion,
+          'volumen', r.volumen,
+          'numero', r.numero,
+          'paginas', r.paginas,
+          'doi', r.doi,
+          'isbn', r.isbn,
+          'url', r.url
         )
         ORDER BY r.id
       )
@@ -146,7 +195,11 @@ SELECT json_build_object(
                   'recursos', sd.recursos,
                   'evidencias', sd.evidencias,
                   'instrumentos', sd.instrumentos,
-                  'valor_porcentual', sd.valor_porcentual
+                  'valor_porcentual', sd.valor_porcentual,
+                  'fecha', sd.fecha,
+                  'hora_inicio', to_char(sd.hora_inicio, 'HH24:MI'),
+                  'hora_fin', to_char(sd.hora_fin, 'HH24:MI'),
+                  'espacio', sd.espacio
                 )
                 ORDER BY sd.numero_sesion
               )
@@ -163,6 +216,18 @@ SELECT json_build_object(
     ),
     '[]'::json
   )
+)::jsonb || jsonb_build_object(
+  -- Datos generales (json_build_object admite máx. 100 argumentos)
+  'fecha_elaboracion', dg.fecha_elaboracion,
+  'tipo_unidad', dg.tipo_unidad,
+  'semanas_por_semestre', dg.semanas_por_semestre,
+  'docente_autor', dg.docente_autor,
+  'programa_id', p.programa_id,
+  'academia_id', p.academia_id,
+  'unidad_aprendizaje_id', p.unidad_aprendizaje_id,
+  -- Archivada: se publicó en un periodo anterior
+  'historica', p.status = 'archivada',
+  'archivada_at', p.archivada_at
 )
 FROM planeaciones p
 JOIN usuarios u ON u.id = p.docente_id
@@ -172,7 +237,8 @@ LEFT JOIN planeacion_relaciones_ejes re ON re.planeacion_id = p.id
 LEFT JOIN planeacion_organizacion org ON org.planeacion_id = p.id
 LEFT JOIN planeacion_plagio pl ON pl.planeacion_id = p.id
 WHERE p.slug = $1
-  AND p.status = 'finalizada'
+  AND `+sqlPublicada+`
+  AND p.deleted_at IS NULL
 LIMIT 1
 		`,
 		slug,
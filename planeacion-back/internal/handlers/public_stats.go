@@ -2,10 +2,17 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,6 +28,136 @@ type PublicStatsResponse struct {
 	SesionesDidacticasTotal int64      `json:"sesiones_didacticas_total"`
 	UltimaActualizacion     *time.Time `json:"ultima_actualizacion,omitempty"`
 	UltimaPublicacion       *time.Time `json:"ultima_publicacion,omitempty"`
+
+	// Promedio de días entre created_at y finalizada_at (solo finalizadas)
+	PromedioDiasFinalizacion *float64 `json:"promedio_dias_finalizacion"`
+	// Fracción de planeaciones con cada sección completa (0..1)
+	TasaSecciones map[string]float64 `json:"tasa_secciones"`
+	// Momento en que se calcularon las vistas materializadas
+	GeneradoAt *time.Time `json:"generado_at,omitempty"`
+}
+
+// Fila del desglose: llaves de la dimensión + indicadores
+type estadisticasGrupo struct {
+	UnidadAcademicaID *int    `json:"unidad_academica_id,omitempty"`
+	UnidadAcademica   *string `json:"unidad_academica,omitempty"`
+	ProgramaID        *int64  `json:"programa_id,omitempty"`
+	Programa          *string `json:"programa,omitempty"`
+	AcademiaID        *int64  `json:"academia_id,omitempty"`
+	Academia          *string `json:"academia,omitempty"`
+	Periodo           *string `json:"periodo,omitempty"`
+	PublicStatsResponse
+}
+
+// Dimensiones de mv_estadisticas_planeaciones
+var dimensionesEstadisticas = map[string]bool{
+	"unidad":         true,
+	"programa":       true,
+	"academia":       true,
+	"periodo":        true,
+	"unidad_periodo": true,
+}
+
+// Indicadores comunes de mv_estadisticas_planeaciones (alias e)
+const indicadoresEstadisticasSQL = `
+  e.planeaciones_total,
+  e.planeaciones_finalizadas,
+  e.docentes_participantes,
+  e.unidades_tematicas_total,
+  e.sesiones_didacticas_total,
+  e.ultima_actualizacion,
+  e.ultima_publicacion,
+  e.promedio_dias_finalizacion::float8,
+  e.secciones_datos,
+  e.secciones_relaciones,
+  e.secciones_organizacion,
+  e.secciones_plagio,
+  e.secciones_referencias,
+  e.generado_at`
+
+func scanIndicadores(dest []any, r *PublicStatsResponse) []any {
+	return append(dest,
+		&r.PlaneacionesTotal,
+		&r.PlaneacionesFinalizadas,
+		&r.DocentesParticipantes,
+		&r.UnidadesTematicasTotal,
+		&r.SesionesDidacticasTotal,
+		&r.UltimaActualizacion,
+		&r.UltimaPublicacion,
+		&r.PromedioDiasFinalizacion,
+	)
+}
+
+// seccionesCompletas recibe los conteos por sección y calcula las tasas.
+type seccionesCompletas struct {
+	Datos, Relaciones, Organizacion, Plagio, Referencias int64
+}
+
+func (s seccionesCompletas) tasas(total int64) map[string]float64 {
+	tasa := func(n int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n) / float64(total)
+	}
+	return map[string]float64{
+		"datos":        tasa(s.Datos),
+		"relaciones":   tasa(s.Relaciones),
+		"organizacion": tasa(s.Organizacion),
+		"plagio":       tasa(s.Plagio),
+		"referencias":  tasa(s.Referencias),
+	}
+}
+
+func (s *seccionesCompletas) destinos() []any {
+	return []any{&s.Datos, &s.Relaciones, &s.Organizacion, &s.Plagio, &s.Referencias}
+}
+
+// =============================
+// Refresco de las vistas materializadas
+// =============================
+
+var refrescandoEstadisticas atomic.Bool
+
+// statsTTL: vigencia de las vistas (env STATS_REFRESH_SECONDS, default 300).
+func statsTTL() time.Duration {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("STATS_REFRESH_SECONDS"))); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// refrescarSiVencido lanza un refresco en segundo plano si las vistas son más
+// viejas que statsTTL; mientras tanto se responde con los datos anteriores.
+func (h *PublicStatsHandler) refrescarSiVencido(generado *time.Time) {
+	if generado != nil && time.Since(*generado) < statsTTL() {
+		return
+	}
+	if !refrescandoEstadisticas.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer refrescandoEstadisticas.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if _, err := h.DB.Exec(ctx, `SELECT public.refrescar_estadisticas_publicas()`); err != nil {
+			log.Println("⚠️ No se pudieron refrescar las estadísticas públicas:", err)
+			return
+		}
+		invalidarCachePublica()
+	}()
+}
+
+func (h *PublicStatsHandler) generadoAt(ctx context.Context) (*time.Time, error) {
+	var t *time.Time
+	err := h.DB.QueryRow(
+		ctx,
+		`SELECT generado_at FROM public.mv_estadisticas_planeaciones WHERE dimension = 'global'`,
+	).Scan(&t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
 }
 
 // GET /api/public/stats
@@ -29,31 +166,255 @@ func (h *PublicStatsHandler) Get(c *gin.Context) {
 	defer cancel()
 
 	const q = `
-SELECT
-  (SELECT COUNT(*)::bigint FROM planeaciones) AS planeaciones_total,
-  (SELECT COUNT(*)::bigint FROM planeaciones WHERE status='finalizada') AS planeaciones_finalizadas,
-  (SELECT COUNT(DISTINCT docente_id)::bigint FROM planeaciones) AS docentes_participantes,
-  (SELECT COUNT(*)::bigint FROM unidades_tematicas) AS unidades_tematicas_total,
-  (SELECT COUNT(*)::bigint FROM sesiones_didacticas) AS sesiones_didacticas_total,
-  (SELECT MAX(updated_at) FROM planeaciones) AS ultima_actualizacion,
-  (SELECT MAX(finalizada_at) FROM planeaciones WHERE status='finalizada') AS ultima_publicacion;
+SELECT` + indicadoresEstadisticasSQL + `
+FROM public.mv_estadisticas_planeaciones e
+WHERE e.dimension = 'global';
 `
 
-	var resp PublicStatsResponse
-	if err := h.DB.QueryRow(ctx, q).Scan(
-		&resp.PlaneacionesTotal,
-		&resp.PlaneacionesFinalizadas,
-		&resp.DocentesParticipantes,
-		&resp.UnidadesTematicasTotal,
-		&resp.SesionesDidacticasTotal,
-		&resp.UltimaActualizacion,
-		&resp.UltimaPublicacion,
-	); err != nil {
+	var (
+		resp      PublicStatsResponse
+		secciones seccionesCompletas
+	)
+	dest := scanIndicadores(nil, &resp)
+	dest = append(dest, secciones.destinos()...)
+	dest = append(dest, &resp.GeneradoAt)
+	if err := h.DB.QueryRow(ctx, q).Scan(dest...); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "DB error: " + err.Error(),
 		})
 		return
 	}
+	resp.TasaSecciones = secciones.tasas(resp.PlaneacionesTotal)
+	h.refrescarSiVencido(resp.GeneradoAt)
+	if resp.GeneradoAt != nil && responderNoModificado(c, etagVersion("stats", 0, *resp.GeneradoAt), *resp.GeneradoAt) {
+		return
+	}
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// GET /api/public/stats/desglose?por=unidad|programa|academia|periodo|unidad_periodo
+// Filtros opcionales: unidad_academica_id, programa_id, academia_id, periodo
+func (h *PublicStatsHandler) Desglose(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	por := strings.TrimSpace(c.DefaultQuery("por", "unidad"))
+	if !dimensionesEstadisticas[por] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "por inválido (unidad, programa, academia, periodo o unidad_periodo)"})
+		return
+	}
+
+	where := []string{"e.dimension = $1"}
+	args := []any{por}
+	for _, f := range []string{"unidad_academica_id", "programa_id", "academia_id"} {
+		s := strings.TrimSpace(c.Query(f))
+		if s == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": f + " inválido"})
+			return
+		}
+		args = append(args, n)
+		where = append(where, "e."+f+" = $"+strconv.Itoa(len(args)))
+	}
+	if s := strings.TrimSpace(c.Query("periodo")); s != "" {
+		args = append(args, s)
+		where = append(where, "e.periodo = $"+strconv.Itoa(len(args)))
+	}
+
+	rows, err := h.DB.Query(
+		ctx,
+		`SELECT e.unidad_academica_id, ua.nombre, e.programa_id, pa.nombre, e.academia_id, a.nombre, e.periodo,`+
+			indicadoresEstadisticasSQL+`
+		 FROM public.mv_estadisticas_planeaciones e
+		 LEFT JOIN public.unidades_academicas ua ON ua.id = e.unidad_academica_id
+		 LEFT JOIN public.programas_academicos pa ON pa.id = e.programa_id
+		 LEFT JOIN public.academias a ON a.id = e.academia_id
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY e.planeaciones_total DESC, ua.nombre, pa.nombre, a.nombre, e.periodo`,
+		args...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	conUnidad := por != "periodo"
+	conPrograma := por == "programa" || por == "academia"
+	conAcademia := por == "academia"
+	conPeriodo := por == "periodo" || por == "unidad_periodo"
+
+	items := make([]estadisticasGrupo, 0)
+	var generado *time.Time
+	for rows.Next() {
+		var (
+			g                           estadisticasGrupo
+			uaID                        int
+			programaID, academiaID      int64
+			uaNombre, paNombre, aNombre *string
+			periodo                     string
+			secciones                   seccionesCompletas
+		)
+		dest := []any{&uaID, &uaNombre, &programaID, &paNombre, &academiaID, &aNombre, &periodo}
+		dest = scanIndicadores(dest, &g.PublicStatsResponse)
+		dest = append(dest, secciones.destinos()...)
+		dest = append(dest, &generado)
+		if err := rows.Scan(dest...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+			return
+		}
+		g.TasaSecciones = secciones.tasas(g.PlaneacionesTotal)
+
+		// Llave 0 / '' = planeaciones sin ese dato
+		if conUnidad {
+			g.UnidadAcademicaID, g.UnidadAcademica = &uaID, uaNombre
+		}
+		if conPrograma {
+			g.ProgramaID, g.Programa = &programaID, etiquetaEstadistica(paNombre, "Sin programa")
+		}
+		if conAcademia {
+			g.AcademiaID, g.Academia = &academiaID, etiquetaEstadistica(aNombre, "Sin academia")
+		}
+		if conPeriodo {
+			if periodo == "" {
+				periodo = "Sin periodo"
+			}
+			g.Periodo = &periodo
+		}
+		items = append(items, g)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	if generado == nil {
+		if generado, err = h.generadoAt(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+			return
+		}
+	}
+	h.refrescarSiVencido(generado)
+
+	c.JSON(http.StatusOK, gin.H{
+		"por":         por,
+		"items":       items,
+		"total":       len(items),
+		"generado_at": generado,
+	})
+}
+
+func etiquetaEstadistica(nombre *string, vacio string) *string {
+	if nombre == nil {
+		return &vacio
+	}
+	return nombre
+}
+
+// GET /api/public/stats/serie?unidad_academica_id=&semanas=26
+// Planeaciones finalizadas por semana (lunes), incluidas las semanas en cero.
+func (h *PublicStatsHandler) Serie(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	ua := 0 // 0 = todas las unidades
+	if s := strings.TrimSpace(c.Query("unidad_academica_id")); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unidad_academica_id inválido"})
+			return
+		}
+		ua = n
+	}
+	semanas := 26
+	if s := strings.TrimSpace(c.Query("semanas")); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 260 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "semanas debe estar entre 1 y 260"})
+			return
+		}
+		semanas = n
+	}
+
+	rows, err := h.DB.Query(
+		ctx,
+		`SELECT s.semana::date, COALESCE(m.finalizadas, 0)
+		 FROM generate_series(
+		        date_trunc('week', now()) - ($2::int - 1) * interval '1 week',
+		        date_trunc('week', now()),
+		        interval '1 week') AS s(semana)
+		 LEFT JOIN public.mv_finalizadas_por_semana m
+		        ON m.semana = s.semana::date AND m.unidad_academica_id = $1
+		 ORDER BY s.semana`,
+		ua,
+		semanas,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type punto struct {
+		Semana      string `json:"semana"`
+		Finalizadas int64  `json:"finalizadas"`
+	}
+	items := make([]punto, 0, semanas)
+	for rows.Next() {
+		var (
+			p      punto
+			semana time.Time
+		)
+		if err := rows.Scan(&semana, &p.Finalizadas); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+			return
+		}
+		p.Semana = semana.Format("2006-01-02")
+		items = append(items, p)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	generado, err := h.generadoAt(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	h.refrescarSiVencido(generado)
+
+	c.JSON(http.StatusOK, gin.H{
+		"unidad_academica_id": ua,
+		"semanas":             semanas,
+		"items":               items,
+		"generado_at":         generado,
+	})
+}
+
+// POST /api/admin/estadisticas/refrescar
+// Recalcula las vistas de inmediato (p. ej. tras una carga masiva).
+func (h *PublicStatsHandler) Refrescar(c *gin.Context) {
+	if _, err := h.DB.Exec(c.Request.Context(), `SELECT public.refrescar_estadisticas_publicas()`); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al refrescar estadísticas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	invalidarCachePublica()
+
+	generado, err := h.generadoAt(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar estadísticas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "generado_at": generado})
+}
@@ -5,5 +5,12 @@ import "github.com/gin-gonic/gin"
 // Registro de rutas públicas: /api/public/stats
 func RegisterPublicStatsRoutes(rg *gin.RouterGroup, h *PublicStatsHandler) {
 	g := rg.Group("/public")
-	g.GET("/stats", h.Get) // GET /api/public/stats
+	g.GET("/stats", h.Get)               // GET /api/public/stats
+	g.GET("/stats/desglose", h.Desglose) // GET /api/public/stats/desglose?por=unidad|programa|academia|periodo|unidad_periodo
+	g.GET("/stats/serie", h.Serie)       // GET /api/public/stats/serie?unidad_academica_id=&semanas=26
+}
+
+// Registro de rutas admin: /api/admin/estadisticas
+func RegisterPublicStatsAdminRoutes(rg *gin.RouterGroup, h *PublicStatsHandler) {
+	rg.POST("/estadisticas/refrescar", h.Refrescar) // POST /api/admin/estadisticas/refrescar
 }
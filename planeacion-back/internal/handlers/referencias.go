@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================
+// Catálogo de referencias del docente (todas sus planeaciones),
+// formato APA 7 e importación BibTeX/RIS.
+// =============================
+
+const maxImportBytes = 1 << 20 // 1 MiB
+
+type ReferenciasHandler struct {
+	DB *pgxpool.Pool
+}
+
+// RegisterReferenciasRoutes registra /api/referencias (requiere AuthMiddleware).
+func RegisterReferenciasRoutes(rg *gin.RouterGroup, h *ReferenciasHandler) {
+	g := rg.Group("/referencias")
+
+	g.GET("", h.Catalogo)             // GET /api/referencias?q=&duplicadas=1
+	g.POST("/formatear", h.Formatear) // POST /api/referencias/formatear
+	g.POST("/importar", h.Importar)   // POST /api/referencias/importar
+}
+
+// Uso de una obra en una planeación
+type usoReferencia struct {
+	PlaneacionID     int64   `json:"planeacion_id"`
+	NombrePlaneacion string  `json:"nombre_planeacion"`
+	ReferenciaID     int64   `json:"referencia_id"`
+	Tipo             *string `json:"tipo"`
+}
+
+// Obra del catálogo (agrupada por claveReferencia)
+type grupoReferencia struct {
+	Clave   string                   `json:"clave"`
+	CitaAPA string                   `json:"cita_apa"`
+	Datos   *ReferenciaBibliografica `json:"datos"`
+	Usos    []usoReferencia          `json:"usos"`
+}
+
+type importarReferenciasRequest struct {
+	Formato   string `json:"formato"` // bibtex | ris | "" (detectar)
+	Contenido string `json:"contenido"`
+}
+
+type referenciaImportada struct {
+	ReferenciaBibliografica
+	CitaAPA           string          `json:"cita_apa"`
+	Clave             string          `json:"clave"`
+	Existentes        []usoReferencia `json:"existentes"`          // ya usada en tus planeaciones
+	RepetidaEnArchivo bool            `json:"repetida_en_archivo"` // misma obra antes en el archivo
+}
+
+// catalogoReferencias agrupa las referencias del docente por obra.
+func catalogoReferencias(ctx context.Context, db *pgxpool.Pool, docenteID int) ([]*grupoReferencia, map[string]*grupoReferencia, error) {
+	rows, err := db.Query(
+		ctx,
+		`
+SELECT
+  r.id, r.planeacion_id, p.nombre_planeacion, r.cita_apa, r.tipo,
+  r.tipo_fuente, r.autores, r.anio, r.titulo, r.fuente, r.editorial, r.edicion,
+  r.volumen, r.numero, r.paginas, r.doi, r.isbn, r.url
+FROM planeacion_referencias r
+JOIN planeaciones p ON p.id = r.planeacion_id
+WHERE p.docente_id = $1 AND p.deleted_at IS NULL
+ORDER BY r.id
+		`,
+		docenteID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		lista  []*grupoReferencia
+		indice = map[string]*grupoReferencia{}
+	)
+	for rows.Next() {
+		var (
+			uso                                            usoReferencia
+			cita                                           string
+			tipoFuente, titulo, fuente, editorial, edicion *string
+			volumen, numero, paginas, doi, isbn, url       *string
+			autoresRaw                                     []byte
+			anio                                           *int
+		)
+		if err := rows.Scan(
+			&uso.ReferenciaID, &uso.PlaneacionID, &uso.NombrePlaneacion, &cita, &uso.Tipo,
+			&tipoFuente, &autoresRaw, &anio, &titulo, &fuente, &editorial, &edicion,
+			&volumen, &numero, &paginas, &doi, &isbn, &url,
+		); err != nil {
+			return nil, nil, err
+		}
+
+		var datos *ReferenciaBibliografica
+		if titulo != nil && *titulo != "" {
+			datos = &ReferenciaBibliografica{
+				TipoFuente: deref(tipoFuente),
+				Anio:       anio,
+				Titulo:     *titulo,
+				Fuente:     deref(fuente),
+				Editorial:  deref(editorial),
+				Edicion:    deref(edicion),
+				Volumen:    deref(volumen),
+				Numero:     deref(numero),
+				Paginas:    deref(paginas),
+				DOI:        deref(doi),
+				ISBN:       deref(isbn),
+				URL:        deref(url),
+			}
+			if len(autoresRaw) > 0 {
+				_ = json.Unmarshal(autoresRaw, &datos.Autores)
+			}
+		}
+
+		var clave string
+		if datos != nil {
+			clave = claveReferencia(*datos, cita)
+		} else {
+			clave = claveReferencia(ReferenciaBibliografica{}, cita)
+		}
+
+		g, ok := indice[clave]
+		if !ok {
+			g = &grupoReferencia{Clave: clave, CitaAPA: cita, Datos: datos}
+			indice[clave] = g
+			lista = append(lista, g)
+		}
+		if g.Datos == nil && datos != nil {
+			g.Datos, g.CitaAPA = datos, cita
+		}
+		g.Usos = append(g.Usos, uso)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return lista, indice, nil
+}
+
+func deref(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// GET /api/referencias
+// Query: q (texto), duplicadas=1 (solo obras usadas más de una vez)
+func (h *ReferenciasHandler) Catalogo(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	lista, _, err := catalogoReferencias(c, h.DB, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	q := normalizarTexto(c.Query("q"))
+	soloDuplicadas := c.Query("duplicadas") == "1" || c.Query("duplicadas") == "true"
+
+	items := make([]*grupoReferencia, 0, len(lista))
+	for _, g := range lista {
+		if soloDuplicadas && len(g.Usos) < 2 {
+			continue
+		}
+		if q != "" && !strings.Contains(normalizarTexto(g.CitaAPA), q) {
+			continue
+		}
+		items = append(items, g)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+// POST /api/referencias/formatear
+// Body: datos estructurados → { cita_apa, clave, datos }
+func (h *ReferenciasHandler) Formatear(c *gin.Context) {
+	if _, err := getClaimsFromHeader(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body ReferenciaBibliografica
+	if _, ok := decodeJSONBody(c, &body); !ok {
+		return
+	}
+
+	body.normalizar()
+	cita := formatAPA(body)
+	if cita == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "titulo es obligatorio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cita_apa": cita,
+		"clave":    claveReferencia(body, cita),
+		"datos":    body,
+	})
+}
+
+// POST /api/referencias/importar
+// Body: { "formato": "bibtex" | "ris", "contenido": "..." }
+// No guarda nada: regresa las referencias listas para agregarse a una planeación,
+// marcando las que ya existen en tus planeaciones. Las que no pasarían la
+// validación al guardar (ISBN, largos) van en "errores", no en "items".
+func (h *ReferenciasHandler) Importar(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes+4096)
+
+	var body importarReferenciasRequest
+	if _, ok := decodeJSONBody(c, &body); !ok {
+		return
+	}
+	if strings.TrimSpace(body.Contenido) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "contenido es obligatorio"})
+		return
+	}
+	if len(body.Contenido) > maxImportBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "contenido demasiado grande (máx. 1 MiB)"})
+		return
+	}
+
+	formato := strings.ToLower(strings.TrimSpace(body.Formato))
+	if formato == "" {
+		formato = detectarFormato(body.Contenido)
+	}
+
+	var (
+		refs []entradaImportada
+		errs []errorImportacion
+	)
+	switch formato {
+	case "bibtex", "bib":
+		formato = "bibtex"
+		refs, errs = parseBibTeX(body.Contenido)
+	case "ris":
+		refs, errs = parseRIS(body.Contenido)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "formato inválido (bibtex | ris)"})
+		return
+	}
+
+	_, indice, err := catalogoReferencias(c, h.DB, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	vistas := map[string]bool{}
+	items := make([]referenciaImportada, 0, len(refs))
+	for _, e := range refs {
+		r := e.Ref
+		if err := r.validar(); err != nil {
+			errs = append(errs, errorImportacion{Entrada: e.Entrada, Error: err.Error()})
+			continue
+		}
+		cita := formatAPA(r)
+		clave := claveReferencia(r, cita)
+
+		item := referenciaImportada{
+			ReferenciaBibliografica: r,
+			CitaAPA:                 cita,
+			Clave:                   clave,
+			Existentes:              []usoReferencia{},
+			RepetidaEnArchivo:       vistas[clave],
+		}
+		if g, ok := indice[clave]; ok {
+			item.Existentes = g.Usos
+		}
+		vistas[clave] = true
+		items = append(items, item)
+	}
+	if errs == nil {
+		errs = []errorImportacion{}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Entrada < errs[j].Entrada })
+
+	c.JSON(http.StatusOK, gin.H{
+		"formato": formato,
+		"items":   items,
+		"errores": errs,
+	})
+}
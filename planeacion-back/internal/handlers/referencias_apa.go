@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// =============================
+// Referencias bibliográficas estructuradas → cita APA 7 (convenciones en español:
+// "y", "s.f.", "ed.", "pp.", "En").
+// =============================
+
+// Tipos de fuente soportados
+const (
+	FuenteLibro    = "libro"
+	FuenteArticulo = "articulo"
+	FuenteCapitulo = "capitulo"
+	FuenteWeb      = "web"
+	FuenteOtro     = "otro"
+)
+
+// Autor de una obra. Sin nombres → autor corporativo (se usa Apellidos tal cual).
+type Autor struct {
+	Apellidos string `json:"apellidos"`
+	Nombres   string `json:"nombres,omitempty"`
+}
+
+// ReferenciaBibliografica: datos estructurados de una referencia.
+type ReferenciaBibliografica struct {
+	TipoFuente string  `json:"tipo_fuente,omitempty"`
+	Autores    []Autor `json:"autores,omitempty"`
+	Anio       *int    `json:"anio,omitempty"`
+	Titulo     string  `json:"titulo,omitempty"`
+	Fuente     string  `json:"fuente,omitempty"` // revista, libro que contiene el capítulo o sitio web
+	Editorial  string  `json:"editorial,omitempty"`
+	Edicion    string  `json:"edicion,omitempty"`
+	Volumen    string  `json:"volumen,omitempty"`
+	Numero     string  `json:"numero,omitempty"`
+	Paginas    string  `json:"paginas,omitempty"`
+	DOI        string  `json:"doi,omitempty"`
+	ISBN       string  `json:"isbn,omitempty"`
+	URL        string  `json:"url,omitempty"`
+}
+
+var (
+	reDOIPrefijo = regexp.MustCompile(`(?i)^(https?://(dx\.)?doi\.org/|doi:\s*)`)
+	reNoISBN     = regexp.MustCompile(`[^0-9Xx]`)
+	reNoAlnum    = regexp.MustCompile(`[^a-z0-9]+`)
+	reSoloDigito = regexp.MustCompile(`^\d+$`)
+	reISBN       = regexp.MustCompile(`^(\d{9}[\dX]|\d{13})$`)
+)
+
+// Largos máximos de las columnas varchar de planeacion_referencias (007)
+var largosReferencia = []struct {
+	campo string
+	max   int
+	valor func(r *ReferenciaBibliografica) string
+}{
+	{"edicion", 50, func(r *ReferenciaBibliografica) string { return r.Edicion }},
+	{"volumen", 30, func(r *ReferenciaBibliografica) string { return r.Volumen }},
+	{"numero", 30, func(r *ReferenciaBibliografica) string { return r.Numero }},
+	{"paginas", 50, func(r *ReferenciaBibliografica) string { return r.Paginas }},
+	{"doi", 255, func(r *ReferenciaBibliografica) string { return r.DOI }},
+}
+
+// normalizar limpia espacios, DOI e ISBN, y deduce el tipo si falta.
+func (r *ReferenciaBibliografica) normalizar() {
+	r.TipoFuente = strings.ToLower(strings.TrimSpace(r.TipoFuente))
+	r.Titulo = strings.TrimSpace(r.Titulo)
+	r.Fuente = strings.TrimSpace(r.Fuente)
+	r.Editorial = strings.TrimSpace(r.Editorial)
+	r.Edicion = strings.TrimSpace(r.Edicion)
+	r.Volumen = strings.TrimSpace(r.Volumen)
+	r.Numero = strings.TrimSpace(r.Numero)
+	r.Paginas = strings.ReplaceAll(strings.TrimSpace(r.Paginas), "--", "–")
+	r.DOI = strings.TrimSpace(reDOIPrefijo.ReplaceAllString(strings.TrimSpace(r.DOI), ""))
+	r.ISBN = strings.ToUpper(reNoISBN.ReplaceAllString(r.ISBN, ""))
+	r.URL = strings.TrimSpace(r.URL)
+
+	autores := make([]Autor, 0, len(r.Autores))
+	for _, a := range r.Autores {
+		a.Apellidos = strings.TrimSpace(a.Apellidos)
+		a.Nombres = strings.TrimSpace(a.Nombres)
+		if a.Apellidos == "" && a.Nombres == "" {
+			continue
+		}
+		if a.Apellidos == "" {
+			a.Apellidos, a.Nombres = a.Nombres, ""
+		}
+		autores = append(autores, a)
+	}
+	r.Autores = autores
+
+	switch r.TipoFuente {
+	case FuenteLibro, FuenteArticulo, FuenteCapitulo, FuenteWeb, FuenteOtro:
+	default:
+		switch {
+		case r.Volumen != "" || (r.Fuente != "" && r.Editorial == "" && r.URL == ""):
+			r.TipoFuente = FuenteArticulo
+		case r.ISBN != "" || r.Editorial != "":
+			r.TipoFuente = FuenteLibro
+		case r.URL != "":
+			r.TipoFuente = FuenteWeb
+		default:
+			r.TipoFuente = FuenteOtro
+		}
+	}
+}
+
+// validar revisa (ya normalizada) que el ISBN tenga 10 o 13 dígitos y que
+// ningún campo exceda el largo de su columna.
+func (r *ReferenciaBibliografica) validar() error {
+	if r.ISBN != "" && !reISBN.MatchString(r.ISBN) {
+		return errors.New("isbn inválido: debe tener 10 dígitos (el último puede ser X) o 13 dígitos")
+	}
+	for _, l := range largosReferencia {
+		if utf8.RuneCountInString(l.valor(r)) > l.max {
+			return errors.New(l.campo + " excede " + strconv.Itoa(l.max) + " caracteres")
+		}
+	}
+	return nil
+}
+
+// tieneDatos: hay suficiente información estructurada para generar la cita.
+func (r *ReferenciaBibliografica) tieneDatos() bool {
+	return r.Titulo != ""
+}
+
+// formatAPA genera la cita APA 7. Regresa "" si no hay título.
+func formatAPA(r ReferenciaBibliografica) string {
+	r.normalizar()
+	if !r.tieneDatos() {
+		return ""
+	}
+
+	anio := "s.f."
+	if r.Anio != nil && *r.Anio > 0 {
+		anio = strconv.Itoa(*r.Anio)
+	}
+
+	titulo := r.Titulo
+	if r.TipoFuente == FuenteLibro && r.Edicion != "" {
+		titulo = strings.TrimRight(titulo, ".") + " (" + edicionAPA(r.Edicion) + ")"
+	}
+
+	var partes []string
+	if autores := autoresAPA(r.Autores); autores != "" {
+		partes = append(partes, conPunto(autores), "("+anio+").", conPunto(titulo))
+	} else {
+		// Sin autor: el título ocupa su lugar
+		partes = append(partes, conPunto(titulo), "("+anio+").")
+	}
+
+	switch r.TipoFuente {
+	case FuenteArticulo:
+		if r.Fuente != "" {
+			fuente := r.Fuente
+			if r.Volumen != "" {
+				fuente += ", " + r.Volumen
+				if r.Numero != "" {
+					fuente += "(" + r.Numero + ")"
+				}
+			}
+			if r.Paginas != "" {
+				fuente += ", " + r.Paginas
+			}
+			partes = append(partes, conPunto(fuente))
+		}
+	case FuenteCapitulo:
+		if r.Fuente != "" {
+			en := "En " + r.Fuente
+			if r.Paginas != "" {
+				en += " (pp. " + r.Paginas + ")"
+			}
+			partes = append(partes, conPunto(en))
+		}
+		if r.Editorial != "" {
+			partes = append(partes, conPunto(r.Editorial))
+		}
+	case FuenteLibro:
+		if r.Editorial != "" {
+			partes = append(partes, conPunto(r.Editorial))
+		}
+	default:
+		// Web / otro: el sitio se omite si es el mismo que el autor
+		if r.Fuente != "" && (len(r.Autores) != 1 || !strings.EqualFold(r.Fuente, r.Autores[0].Apellidos)) {
+			partes = append(partes, conPunto(r.Fuente))
+		}
+		if r.Editorial != "" {
+			partes = append(partes, conPunto(r.Editorial))
+		}
+	}
+
+	if r.DOI != "" {
+		partes = append(partes, "https://doi.org/"+r.DOI)
+	} else if r.URL != "" {
+		partes = append(partes, r.URL)
+	}
+
+	return strings.Join(partes, " ")
+}
+
+// autoresAPA: "Apellido, A. B., Apellido, C., y Apellido, D."
+// (más de 20: los primeros 19, ". . ." y el último)
+func autoresAPA(autores []Autor) string {
+	nombres := make([]string, 0, len(autores))
+	for _, a := range autores {
+		if a.Nombres == "" {
+			nombres = append(nombres, a.Apellidos)
+			continue
+		}
+		nombres = append(nombres, a.Apellidos+", "+iniciales(a.Nombres))
+	}
+
+	switch n := len(nombres); {
+	case n == 0:
+		return ""
+	case n == 1:
+		return nombres[0]
+	case n <= 20:
+		return strings.Join(nombres[:n-1], ", ") + ", y " + nombres[n-1]
+	default:
+		return strings.Join(nombres[:19], ", ") + ", . . . " + nombres[n-1]
+	}
+}
+
+// iniciales: "José María" → "J. M."; "Jean-Paul" → "J.-P."
+func iniciales(nombres string) string {
+	var out []string
+	for _, parte := range strings.Fields(nombres) {
+		var guion []string
+		for _, p := range strings.Split(parte, "-") {
+			r, _ := utf8.DecodeRuneInString(p)
+			if r == utf8.RuneError || !unicode.IsLetter(r) {
+				continue
+			}
+			guion = append(guion, string(unicode.ToUpper(r))+".")
+		}
+		if len(guion) > 0 {
+			out = append(out, strings.Join(guion, "-"))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// edicionAPA: "2" → "2.ª ed."; texto libre se respeta.
+func edicionAPA(ed string) string {
+	if reSoloDigito.MatchString(ed) {
+		return ed + ".ª ed."
+	}
+	return ed
+}
+
+func conPunto(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return s
+	}
+	switch s[len(s)-1] {
+	case '.', '?', '!':
+		return s
+	}
+	return s + "."
+}
+
+// quitarAcentos: "Programación" → "Programacion" (conserva mayúsculas).
+func quitarAcentos(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+// normalizarTexto: minúsculas, sin acentos ni signos, espacios simples.
+func normalizarTexto(s string) string {
+	s = strings.ToLower(quitarAcentos(s))
+	return strings.TrimSpace(reNoAlnum.ReplaceAllString(s, " "))
+}
+
+// claveReferencia identifica una obra para detectar duplicados:
+// DOI > ISBN > título+año+primer autor > cita libre normalizada.
+func claveReferencia(r ReferenciaBibliografica, citaAPA string) string {
+	r.normalizar()
+	switch {
+	case r.DOI != "":
+		return "doi:" + strings.ToLower(r.DOI)
+	case len(r.ISBN) == 10 || len(r.ISBN) == 13:
+		return "isbn:" + r.ISBN
+	case r.Titulo != "":
+		clave := "t:" + normalizarTexto(r.Titulo)
+		if r.Anio != nil {
+			clave += "|" + strconv.Itoa(*r.Anio)
+		}
+		if len(r.Autores) > 0 {
+			clave += "|" + normalizarTexto(r.Autores[0].Apellidos)
+		}
+		return clave
+	}
+	return "c:" + normalizarTexto(citaAPA)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func anioRef(n int) *int { return &n }
+
+func TestFormatAPA(t *testing.T) {
+	casos := []struct {
+		nombre string
+		ref    ReferenciaBibliografica
+		cita   string
+	}{
+		{
+			nombre: "sin título",
+			ref:    ReferenciaBibliografica{Autores: []Autor{{Apellidos: "Pérez"}}},
+			cita:   "",
+		},
+		{
+			nombre: "libro con edición",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteLibro,
+				Autores:    []Autor{{Apellidos: "Cormen", Nombres: "Thomas H."}},
+				Anio:       anioRef(2009),
+				Titulo:     "Introduction to algorithms",
+				Edicion:    "3",
+				Editorial:  "MIT Press",
+			},
+			cita: "Cormen, T. H. (2009). Introduction to algorithms (3.ª ed.). MIT Press.",
+		},
+		{
+			nombre: "edición en texto libre",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteLibro,
+				Autores:    []Autor{{Apellidos: "Knuth", Nombres: "Donald"}},
+				Anio:       anioRef(1997),
+				Titulo:     "The art of computer programming.",
+				Edicion:    "Edición revisada",
+			},
+			cita: "Knuth, D. (1997). The art of computer programming (Edición revisada).",
+		},
+		{
+			nombre: "sin año",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteLibro,
+				Autores:    []Autor{{Apellidos: "García", Nombres: "Ana"}},
+				Titulo:     "Cálculo",
+			},
+			cita: "García, A. (s.f.). Cálculo.",
+		},
+		{
+			nombre: "dos autores e iniciales compuestas",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteLibro,
+				Autores: []Autor{
+					{Apellidos: "López Mateos", Nombres: "José María"},
+					{Apellidos: "Sartre", Nombres: "Jean-Paul"},
+				},
+				Anio:   anioRef(2020),
+				Titulo: "Ensayos",
+			},
+			cita: "López Mateos, J. M., y Sartre, J.-P. (2020). Ensayos.",
+		},
+		{
+			nombre: "autor corporativo",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteWeb,
+				Autores:    []Autor{{Apellidos: "Organización Mundial de la Salud"}},
+				Anio:       anioRef(2021),
+				Titulo:     "Informe mundial",
+				Fuente:     "Organización Mundial de la Salud",
+				URL:        "https://who.int/informe",
+			},
+			cita: "Organización Mundial de la Salud. (2021). Informe mundial. https://who.int/informe",
+		},
+		{
+			nombre: "sin autor: el título ocupa su lugar",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteWeb,
+				Anio:       anioRef(2022),
+				Titulo:     "Guía de estilo",
+				Fuente:     "Sitio",
+			},
+			cita: "Guía de estilo. (2022). Sitio.",
+		},
+		{
+			nombre: "artículo con volumen, número, páginas y DOI",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteArticulo,
+				Autores:    []Autor{{Apellidos: "Díaz", Nombres: "Luis"}},
+				Anio:       anioRef(2018),
+				Titulo:     "¿Qué es la programación?",
+				Fuente:     "Revista de Computación",
+				Volumen:    "12",
+				Numero:     "3",
+				Paginas:    "10--20",
+				DOI:        "https://doi.org/10.1000/XYZ",
+			},
+			cita: "Díaz, L. (2018). ¿Qué es la programación? Revista de Computación, 12(3), 10–20. https://doi.org/10.1000/XYZ",
+		},
+		{
+			nombre: "capítulo",
+			ref: ReferenciaBibliografica{
+				TipoFuente: FuenteCapitulo,
+				Autores:    []Autor{{Apellidos: "Ruiz", Nombres: "Eva"}},
+				Anio:       anioRef(2015),
+				Titulo:     "Redes",
+				Fuente:     "Manual de sistemas",
+				Paginas:    "5-9",
+				Editorial:  "Trillas",
+			},
+			cita: "Ruiz, E. (2015). Redes. En Manual de sistemas (pp. 5-9). Trillas.",
+		},
+		{
+			nombre: "tipo deducido: con ISBN es libro",
+			ref: ReferenciaBibliografica{
+				Autores: []Autor{{Apellidos: "Soto", Nombres: "Raúl"}},
+				Anio:    anioRef(2001),
+				Titulo:  "Física",
+				ISBN:    "978-0-262-03384-8",
+				Edicion: "2",
+			},
+			cita: "Soto, R. (2001). Física (2.ª ed.).",
+		},
+	}
+
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			if got := formatAPA(tc.ref); got != tc.cita {
+				t.Errorf("formatAPA =\n  %q\nse esperaba\n  %q", got, tc.cita)
+			}
+		})
+	}
+}
+
+func TestAutoresAPA(t *testing.T) {
+	n := func(k int) []Autor {
+		out := make([]Autor, k)
+		for i := range out {
+			out[i] = Autor{Apellidos: "A" + strconv.Itoa(i+1), Nombres: "Beto"}
+		}
+		return out
+	}
+
+	casos := []struct {
+		nombre  string
+		autores []Autor
+		want    string
+	}{
+		{"ninguno", nil, ""},
+		{"uno", n(1), "A1, B."},
+		{"tres", n(3), "A1, B., A2, B., y A3, B."},
+		{"veinte", n(20), listaAutores(1, 19) + ", y A20, B."},
+		{"más de veinte: 19, puntos y el último", n(25), listaAutores(1, 19) + ", . . . A25, B."},
+	}
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			if got := autoresAPA(tc.autores); got != tc.want {
+				t.Errorf("autoresAPA = %q, se esperaba %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func listaAutores(desde, hasta int) string {
+	var partes []string
+	for i := desde; i <= hasta; i++ {
+		partes = append(partes, "A"+strconv.Itoa(i)+", B.")
+	}
+	return strings.Join(partes, ", ")
+}
+
+func TestValidarReferencia(t *testing.T) {
+	casos := []struct {
+		nombre string
+		ref    ReferenciaBibliografica
+		ok     bool
+	}{
+		{"sin ISBN", ReferenciaBibliografica{Titulo: "x"}, true},
+		{"ISBN-10 con X", ReferenciaBibliografica{Titulo: "x", ISBN: "0-8044-2957-x"}, true},
+		{"ISBN-13 con guiones", ReferenciaBibliografica{Titulo: "x", ISBN: "978-0-262-03384-8"}, true},
+		{"ISSN no es ISBN", ReferenciaBibliografica{Titulo: "x", ISBN: "1234-5678"}, false},
+		{"X fuera del final", ReferenciaBibliografica{Titulo: "x", ISBN: "X123456789"}, false},
+		{"páginas demasiado largas", ReferenciaBibliografica{Titulo: "x", Paginas: strings.Repeat("1", 51)}, false},
+	}
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			tc.ref.normalizar()
+			if err := tc.ref.validar(); (err == nil) != tc.ok {
+				t.Errorf("validar() = %v, se esperaba ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestClaveReferencia(t *testing.T) {
+	casos := []struct {
+		nombre string
+		ref    ReferenciaBibliografica
+		cita   string
+		want   string
+	}{
+		{"DOI primero", ReferenciaBibliografica{DOI: "doi: 10.1/ABC", ISBN: "9780262033848", Titulo: "x"}, "", "doi:10.1/abc"},
+		{"ISBN", ReferenciaBibliografica{ISBN: "978-0-262-03384-8", Titulo: "x"}, "", "isbn:9780262033848"},
+		{
+			"título, año y primer autor sin acentos",
+			ReferenciaBibliografica{Titulo: "Programación  Básica", Anio: anioRef(2020), Autores: []Autor{{Apellidos: "Núñez"}}},
+			"",
+			"t:programacion basica|2020|nunez",
+		},
+		{"cita libre", ReferenciaBibliografica{}, "Pérez, J. (2001). Álgebra.", "c:perez j 2001 algebra"},
+	}
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			if got := claveReferencia(tc.ref, tc.cita); got != tc.want {
+				t.Errorf("claveReferencia = %q, se esperaba %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// =============================
+// Importación de referencias desde BibTeX y RIS
+// =============================
+
+// Error de una entrada que no se pudo importar
+type errorImportacion struct {
+	Entrada int    `json:"entrada"` // 1-based, en orden de aparición
+	Error   string `json:"error"`
+}
+
+// Referencia leída del archivo, con su número de entrada para reportar errores
+type entradaImportada struct {
+	Entrada int
+	Ref     ReferenciaBibliografica
+}
+
+var (
+	reAnio     = regexp.MustCompile(`\d{4}`)
+	reRISLinea = regexp.MustCompile(`^([A-Z][A-Z0-9])\s{1,2}-\s?(.*)$`)
+)
+
+// detectarFormato: "ris" si hay líneas "TY  - ", si no "bibtex".
+func detectarFormato(contenido string) string {
+	for _, l := range strings.Split(contenido, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if strings.HasPrefix(l, "@") {
+			return "bibtex"
+		}
+		if strings.HasPrefix(l, "TY  -") || strings.HasPrefix(l, "TY -") {
+			return "ris"
+		}
+	}
+	return "bibtex"
+}
+
+// ─────────────────────────────
+// BibTeX
+// ─────────────────────────────
+
+func parseBibTeX(contenido string) ([]entradaImportada, []errorImportacion) {
+	var (
+		out     []entradaImportada
+		errs    []errorImportacion
+		entrada int
+	)
+
+	s := contenido
+	for {
+		at := strings.IndexByte(s, '@')
+		if at < 0 {
+			break
+		}
+		s = s[at+1:]
+
+		open := strings.IndexAny(s, "{(")
+		if open < 0 {
+			break
+		}
+		tipo := strings.ToLower(strings.TrimSpace(s[:open]))
+		closeCh := byte('}')
+		if s[open] == '(' {
+			closeCh = ')'
+		}
+
+		end := cierreBalanceado(s, open, closeCh)
+		if end < 0 {
+			entrada++
+			errs = append(errs, errorImportacion{Entrada: entrada, Error: "entrada @" + tipo + " sin cerrar"})
+			break
+		}
+		cuerpo := s[open+1 : end]
+		s = s[end+1:]
+
+		switch tipo {
+		case "comment", "string", "preamble":
+			continue
+		}
+		entrada++
+
+		campos, err := camposBibTeX(cuerpo)
+		if err != nil {
+			errs = append(errs, errorImportacion{Entrada: entrada, Error: err.Error()})
+			continue
+		}
+
+		ref := ReferenciaBibliografica{
+			TipoFuente: tipoBibTeX(tipo, campos),
+			Titulo:     campos["title"],
+			Editorial:  primero(campos["publisher"], campos["institution"], campos["organization"], campos["school"]),
+			Edicion:    campos["edition"],
+			Volumen:    campos["volume"],
+			Numero:     campos["number"],
+			Paginas:    campos["pages"],
+			DOI:        campos["doi"],
+			ISBN:       campos["isbn"],
+			URL:        campos["url"],
+		}
+		switch ref.TipoFuente {
+		case FuenteArticulo:
+			ref.Fuente = primero(campos["journal"], campos["journaltitle"])
+		case FuenteCapitulo:
+			ref.Fuente = campos["booktitle"]
+		default:
+			ref.Fuente = primero(campos["howpublished"], campos["journal"])
+		}
+		if y := reAnio.FindString(primero(campos["year"], campos["date"])); y != "" {
+			n, _ := strconv.Atoi(y)
+			ref.Anio = &n
+		}
+		if a := campos["author"]; a != "" {
+			for _, nombre := range dividirAutoresBibTeX(a) {
+				ref.Autores = append(ref.Autores, autorDesdeTexto(nombre))
+			}
+		}
+
+		ref.normalizar()
+		if !ref.tieneDatos() {
+			errs = append(errs, errorImportacion{Entrada: entrada, Error: "entrada sin título"})
+			continue
+		}
+		out = append(out, entradaImportada{Entrada: entrada, Ref: ref})
+	}
+
+	return out, errs
+}
+
+// cierreBalanceado: índice del cierre que corresponde a s[open]
+// (las llaves internas se balancean; un ')' solo cierra fuera de llaves).
+func cierreBalanceado(s string, open int, closeCh byte) int {
+	llaves := 0
+	for i := open + 1; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			llaves++
+		case '}':
+			if llaves == 0 && closeCh == '}' {
+				return i
+			}
+			llaves--
+		case ')':
+			if llaves == 0 && closeCh == ')' {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// camposBibTeX: "clave, campo = {valor}, campo = \"valor\", campo = 2020"
+func camposBibTeX(cuerpo string) (map[string]string, error) {
+	campos := map[string]string{}
+
+	// Saltar la clave de cita
+	coma := strings.IndexByte(cuerpo, ',')
+	if coma < 0 {
+		return campos, nil
+	}
+	s := cuerpo[coma+1:]
+
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		if s == "" {
+			break
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		nombre := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t\r\n")
+		if s == "" {
+			return nil, fmt.Errorf("campo %q sin valor", nombre)
+		}
+
+		var valor string
+		switch s[0] {
+		case '{':
+			end := cierreBalanceado(s, 0, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("campo %q sin cerrar", nombre)
+			}
+			valor, s = s[1:end], s[end+1:]
+		case '"':
+			end := -1
+			depth := 0
+			for i := 1; i < len(s); i++ {
+				if s[i] == '{' {
+					depth++
+				} else if s[i] == '}' {
+					depth--
+				} else if s[i] == '"' && depth == 0 && s[i-1] != '\\' {
+					end = i
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("campo %q sin cerrar", nombre)
+			}
+			valor, s = s[1:end], s[end+1:]
+		default:
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			valor, s = s[:end], s[end:]
+		}
+
+		if nombre == "author" {
+			// Sin limpiar: las llaves marcan autores corporativos
+			campos[nombre] = strings.Join(strings.Fields(valor), " ")
+			continue
+		}
+		campos[nombre] = limpiarLaTeX(valor)
+	}
+
+	return campos, nil
+}
+
+var latexAcentos = strings.NewReplacer(
+	`\'a`, "á", `\'e`, "é", `\'i`, "í", `\'\i`, "í", `\'o`, "ó", `\'u`, "ú",
+	`\'A`, "Á", `\'E`, "É", `\'I`, "Í", `\'O`, "Ó", `\'U`, "Ú",
+	`\~n`, "ñ", `\~N`, "Ñ", `\"u`, "ü", `\"U`, "Ü", `\"o`, "ö", `\"a`, "ä",
+	`\`+"`a", "à", `\`+"`e", "è", `\c{c}`, "ç", `\c c`, "ç",
+	`\&`, "&", `\%`, "%", `\_`, "_", `\$`, "$", `--`, "–", `~`, " ",
+)
+
+// Acento con argumento entre llaves: \'{o}, \~{n}, \'{\i}
+var reLaTeXAcentoArg = regexp.MustCompile(`\\([` + "`" + `'"~^])\{(\\?[A-Za-z])\}`)
+
+// limpiarLaTeX: acentos comunes, sin llaves ni espacios repetidos.
+func limpiarLaTeX(s string) string {
+	s = strings.ReplaceAll(s, `{\`, `\`)
+	s = reLaTeXAcentoArg.ReplaceAllString(s, `\${1}${2}`)
+	s = latexAcentos.Replace(s)
+	s = strings.NewReplacer("{", "", "}", "").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func tipoBibTeX(tipo string, campos map[string]string) string {
+	switch tipo {
+	case "article":
+		return FuenteArticulo
+	case "book", "booklet", "manual", "proceedings", "phdthesis", "mastersthesis", "techreport":
+		return FuenteLibro
+	case "incollection", "inbook", "inproceedings", "conference":
+		return FuenteCapitulo
+	case "online", "electronic", "www", "webpage":
+		return FuenteWeb
+	}
+	if campos["url"] != "" {
+		return FuenteWeb
+	}
+	return FuenteOtro
+}
+
+// dividirAutoresBibTeX separa por " and " fuera de llaves (los autores
+// corporativos vienen entre llaves: {Organización Mundial de la Salud}).
+func dividirAutoresBibTeX(s string) []string {
+	var (
+		out   []string
+		depth int
+		start int
+	)
+	low := strings.ToLower(s)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(low[i:], " and ") {
+			out = append(out, s[start:i])
+			start = i + 5
+			i += 4
+		}
+	}
+	return append(out, s[start:])
+}
+
+// autorDesdeTexto: "Apellidos, Nombres" o "Nombres Apellido".
+// Un solo bloque sin coma (o entre llaves) se toma como autor corporativo.
+func autorDesdeTexto(s string) Autor {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		return Autor{Apellidos: limpiarLaTeX(s)}
+	}
+	s = limpiarLaTeX(s)
+
+	if ap, nom, ok := strings.Cut(s, ","); ok {
+		return Autor{Apellidos: strings.TrimSpace(ap), Nombres: strings.TrimSpace(nom)}
+	}
+
+	partes := strings.Fields(s)
+	if len(partes) < 2 {
+		return Autor{Apellidos: s}
+	}
+	// Partículas ("de la", "van") se quedan con el apellido
+	i := len(partes) - 1
+	for i > 1 && esParticula(partes[i-1]) {
+		i--
+	}
+	return Autor{
+		Apellidos: strings.Join(partes[i:], " "),
+		Nombres:   strings.Join(partes[:i], " "),
+	}
+}
+
+func esParticula(p string) bool {
+	if p == "" || !unicode.IsLower([]rune(p)[0]) {
+		return false
+	}
+	switch p {
+	case "de", "del", "la", "las", "los", "y", "van", "von", "der", "da", "di":
+		return true
+	}
+	return false
+}
+
+// ─────────────────────────────
+// RIS
+// ─────────────────────────────
+
+func parseRIS(contenido string) ([]entradaImportada, []errorImportacion) {
+	var (
+		out     []entradaImportada
+		errs    []errorImportacion
+		entrada int
+		actual  map[string][]string
+	)
+
+	cerrar := func() {
+		if actual == nil {
+			return
+		}
+		entrada++
+		ref := refDesdeRIS(actual)
+		actual = nil
+		if !ref.tieneDatos() {
+			errs = append(errs, errorImportacion{Entrada: entrada, Error: "entrada sin título"})
+			return
+		}
+		out = append(out, entradaImportada{Entrada: entrada, Ref: ref})
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(contenido))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		m := reRISLinea.FindStringSubmatch(strings.TrimRight(sc.Text(), "\r"))
+		if m == nil {
+			continue
+		}
+		tag, valor := m[1], strings.TrimSpace(m[2])
+
+		switch tag {
+		case "TY":
+			cerrar()
+			actual = map[string][]string{"TY": {valor}}
+		case "ER":
+			cerrar()
+		default:
+			if actual != nil && valor != "" {
+				actual[tag] = append(actual[tag], valor)
+			}
+		}
+	}
+	if actual != nil {
+		// Archivo sin "ER" final
+		cerrar()
+	}
+
+	return out, errs
+}
+
+func refDesdeRIS(t map[string][]string) ReferenciaBibliografica {
+	get := func(tags ...string) string {
+		for _, tag := range tags {
+			if v := t[tag]; len(v) > 0 {
+				return v[0]
+			}
+		}
+		return ""
+	}
+
+	ref := ReferenciaBibliografica{
+		Titulo:    get("TI", "T1", "CT"),
+		Editorial: get("PB"),
+		Edicion:   get("ET"),
+		Volumen:   get("VL"),
+		Numero:    get("IS"),
+		DOI:       get("DO"),
+		URL:       get("UR", "L2"),
+	}
+
+	switch get("TY") {
+	case "JOUR", "JFULL", "MGZN", "NEWS", "EJOUR":
+		ref.TipoFuente = FuenteArticulo
+		ref.Fuente = get("JO", "JF", "T2", "JA")
+	case "BOOK", "EBOOK", "THES", "RPRT":
+		ref.TipoFuente = FuenteLibro
+		ref.ISBN = get("SN") // en revistas y periódicos SN es el ISSN
+	case "CHAP", "ECHAP", "CONF", "CPAPER":
+		ref.TipoFuente = FuenteCapitulo
+		ref.Fuente = get("BT", "T2")
+		ref.ISBN = get("SN")
+	case "ELEC", "WEB", "BLOG":
+		ref.TipoFuente = FuenteWeb
+		ref.Fuente = get("T2")
+	default:
+		ref.TipoFuente = FuenteOtro
+		ref.Fuente = get("T2")
+	}
+
+	if sp := get("SP"); sp != "" {
+		ref.Paginas = sp
+		if ep := get("EP"); ep != "" {
+			ref.Paginas += "–" + ep
+		}
+	}
+	if y := reAnio.FindString(get("PY", "Y1", "DA")); y != "" {
+		n, _ := strconv.Atoi(y)
+		ref.Anio = &n
+	}
+	for _, tag := range []string{"AU", "A1"} {
+		for _, a := range t[tag] {
+			ref.Autores = append(ref.Autores, autorDesdeTexto(a))
+		}
+	}
+
+	ref.normalizar()
+	return ref
+}
+
+func primero(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
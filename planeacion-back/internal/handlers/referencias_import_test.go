@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimpiarLaTeX(t *testing.T) {
+	casos := []struct {
+		entrada string
+		want    string
+	}{
+		{`Programaci{\'o}n`, "Programación"},
+		{`Programaci\'{o}n`, "Programación"},
+		{`Espa{\~n}a`, "España"},
+		{`ling{\"u}{\'\i}stica`, "lingüística"},
+		{`Fran{\c{c}}ois`, "François"},
+		{`Redes \& sistemas`, "Redes & sistemas"},
+		{`50\% de {ACM}`, "50% de ACM"},
+		{`pp.~10--20`, "pp. 10–20"},
+		{"  {El}   {Título}\n  largo ", "El Título largo"},
+	}
+	for _, tc := range casos {
+		t.Run(tc.entrada, func(t *testing.T) {
+			if got := limpiarLaTeX(tc.entrada); got != tc.want {
+				t.Errorf("limpiarLaTeX(%q) = %q, se esperaba %q", tc.entrada, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCamposBibTeX(t *testing.T) {
+	casos := []struct {
+		nombre string
+		cuerpo string
+		want   map[string]string
+		err    bool
+	}{
+		{
+			nombre: "llaves, comillas y número",
+			cuerpo: `clave, title = {Un {T}ítulo}, journal = "Revista {X}", year = 2020`,
+			want:   map[string]string{"title": "Un Título", "journal": "Revista X", "year": "2020"},
+		},
+		{
+			nombre: "comillas con llaves internas",
+			cuerpo: `clave, title = "El {"}mejor{"} libro"`,
+			want:   map[string]string{"title": `El "mejor" libro`},
+		},
+		{
+			nombre: "nombre de campo sin distinguir mayúsculas",
+			cuerpo: "clave,\n  TITLE = {X},\n  Year = {1999},\n",
+			want:   map[string]string{"title": "X", "year": "1999"},
+		},
+		{
+			nombre: "author conserva llaves de autor corporativo",
+			cuerpo: `clave, author = {{Organización   Mundial} and Pérez, Juan}`,
+			want:   map[string]string{"author": "{Organización Mundial} and Pérez, Juan"},
+		},
+		{
+			nombre: "solo clave",
+			cuerpo: `clave`,
+			want:   map[string]string{},
+		},
+		{nombre: "campo sin cerrar", cuerpo: `clave, title = {abierto`, err: true},
+		{nombre: "comillas sin cerrar", cuerpo: `clave, title = "abierto`, err: true},
+		{nombre: "campo sin valor", cuerpo: `clave, title =`, err: true},
+	}
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			got, err := camposBibTeX(tc.cuerpo)
+			if (err != nil) != tc.err {
+				t.Fatalf("error = %v, se esperaba error=%v", err, tc.err)
+			}
+			if !tc.err && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("campos = %#v, se esperaba %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAutorDesdeTexto(t *testing.T) {
+	casos := []struct {
+		entrada string
+		want    Autor
+	}{
+		{"Pérez, Juan", Autor{Apellidos: "Pérez", Nombres: "Juan"}},
+		{"Juan Pérez", Autor{Apellidos: "Pérez", Nombres: "Juan"}},
+		{"Ludwig van Beethoven", Autor{Apellidos: "van Beethoven", Nombres: "Ludwig"}},
+		{"María de la Cruz", Autor{Apellidos: "de la Cruz", Nombres: "María"}},
+		{"{Organización Mundial de la Salud}", Autor{Apellidos: "Organización Mundial de la Salud"}},
+		{"UNESCO", Autor{Apellidos: "UNESCO"}},
+		{`Mu{\~n}oz, Jos{\'e}`, Autor{Apellidos: "Muñoz", Nombres: "José"}},
+	}
+	for _, tc := range casos {
+		t.Run(tc.entrada, func(t *testing.T) {
+			if got := autorDesdeTexto(tc.entrada); got != tc.want {
+				t.Errorf("autorDesdeTexto(%q) = %+v, se esperaba %+v", tc.entrada, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDividirAutoresBibTeX(t *testing.T) {
+	got := dividirAutoresBibTeX("Pérez, Juan and {Barnes and Noble} AND López, Ana")
+	want := []string{"Pérez, Juan", "{Barnes and Noble}", "López, Ana"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dividirAutoresBibTeX = %q, se esperaba %q", got, want)
+	}
+}
+
+func TestParseBibTeX(t *testing.T) {
+	contenido := `
+@comment{se ignora}
+@string{rev = "No se usa"}
+
+@book{cormen2009,
+  author    = {Cormen, Thomas H. and Leiserson, Charles E.},
+  title     = {Introduction to Algorithms},
+  edition   = {3},
+  publisher = {MIT Press},
+  year      = {2009},
+  isbn      = {978-0-262-03384-8}
+}
+
+@article(diaz2018,
+  author  = "D{\'\i}az, Luis",
+  title   = "Programaci{\'o}n funcional",
+  journal = {Revista de Computaci\'{o}n},
+  volume  = 12, number = 3, pages = {10--20},
+  doi     = {https://doi.org/10.1000/xyz}
+)
+
+@misc{sintitulo, author = {Nadie}}
+
+@book{roto, title = {abierto}
+`
+	refs, errs := parseBibTeX(contenido)
+
+	if len(refs) != 2 {
+		t.Fatalf("refs = %d, se esperaban 2: %+v", len(refs), refs)
+	}
+
+	libro := refs[0]
+	if libro.Entrada != 1 || libro.Ref.TipoFuente != FuenteLibro || libro.Ref.Titulo != "Introduction to Algorithms" ||
+		libro.Ref.Editorial != "MIT Press" || libro.Ref.ISBN != "9780262033848" || libro.Ref.Anio == nil || *libro.Ref.Anio != 2009 {
+		t.Errorf("libro = %+v", libro)
+	}
+	if want := []Autor{{Apellidos: "Cormen", Nombres: "Thomas H."}, {Apellidos: "Leiserson", Nombres: "Charles E."}}; !reflect.DeepEqual(libro.Ref.Autores, want) {
+		t.Errorf("autores = %+v, se esperaba %+v", libro.Ref.Autores, want)
+	}
+
+	art := refs[1]
+	if art.Entrada != 2 || art.Ref.TipoFuente != FuenteArticulo || art.Ref.Titulo != "Programación funcional" ||
+		art.Ref.Fuente != "Revista de Computación" || art.Ref.Paginas != "10–20" || art.Ref.DOI != "10.1000/xyz" ||
+		art.Ref.Volumen != "12" || art.Ref.Numero != "3" {
+		t.Errorf("artículo = %+v", art)
+	}
+	if want := []Autor{{Apellidos: "Díaz", Nombres: "Luis"}}; !reflect.DeepEqual(art.Ref.Autores, want) {
+		t.Errorf("autores = %+v, se esperaba %+v", art.Ref.Autores, want)
+	}
+
+	wantErrs := []errorImportacion{
+		{Entrada: 3, Error: "entrada sin título"},
+		{Entrada: 4, Error: "entrada @book sin cerrar"},
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("errores = %+v, se esperaba %+v", errs, wantErrs)
+	}
+}
+
+func TestParseRIS(t *testing.T) {
+	contenido := "TY  - JOUR\r\n" +
+		"AU  - Díaz, Luis\r\n" +
+		"TI  - Programación funcional\r\n" +
+		"JO  - Revista de Computación\r\n" +
+		"SN  - 1234-5678\r\n" +
+		"SP  - 10\r\n" +
+		"EP  - 20\r\n" +
+		"PY  - 2018///\r\n" +
+		"ER  - \r\n" +
+		"TY  - BOOK\n" +
+		"A1  - Cormen, Thomas H.\n" +
+		"T1  - Introduction to Algorithms\n" +
+		"SN  - 978-0-262-03384-8\n" +
+		"PB  - MIT Press\n" +
+		"ER  -\n" +
+		"TY  - GEN\n" +
+		"AU  - Nadie\n" +
+		"ER  - \n" +
+		"TY  - CHAP\n" +
+		"TI  - Redes\n" +
+		"BT  - Manual de sistemas\n"
+
+	refs, errs := parseRIS(contenido)
+	if len(refs) != 3 {
+		t.Fatalf("refs = %d, se esperaban 3: %+v", len(refs), refs)
+	}
+
+	art := refs[0].Ref
+	if art.TipoFuente != FuenteArticulo || art.ISBN != "" || art.Paginas != "10–20" || art.Anio == nil || *art.Anio != 2018 ||
+		art.Fuente != "Revista de Computación" {
+		t.Errorf("artículo = %+v (el SN de una revista es ISSN, no ISBN)", art)
+	}
+	libro := refs[1].Ref
+	if libro.TipoFuente != FuenteLibro || libro.ISBN != "9780262033848" || libro.Editorial != "MIT Press" {
+		t.Errorf("libro = %+v", libro)
+	}
+	if refs[2].Entrada != 4 || refs[2].Ref.TipoFuente != FuenteCapitulo || refs[2].Ref.Fuente != "Manual de sistemas" {
+		t.Errorf("capítulo sin ER = %+v", refs[2])
+	}
+
+	wantErrs := []errorImportacion{{Entrada: 3, Error: "entrada sin título"}}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("errores = %+v, se esperaba %+v", errs, wantErrs)
+	}
+}
+
+func TestDetectarFormato(t *testing.T) {
+	if got := detectarFormato("\n TY  - BOOK\nER  - "); got != "ris" {
+		t.Errorf("detectarFormato(RIS) = %q", got)
+	}
+	if got := detectarFormato("@book{x, title={y}}"); got != "bibtex" {
+		t.Errorf("detectarFormato(BibTeX) = %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Referencias ↔ unidades temáticas
+// unidades_aplica guarda números de unidad: se valida contra las unidades de la
+// misma planeación y se reasigna cuando las unidades se renumeran.
+// Una lista vacía significa "sin unidad asignada".
+// =============================
+
+// Referencia que apunta a unidades inexistentes
+type referenciaHuerfana struct {
+	ReferenciaID int64   `json:"referencia_id"`
+	CitaAPA      string  `json:"cita_apa"`
+	Unidades     []int32 `json:"unidades_inexistentes"`
+}
+
+// errReferenciasHuerfanas: referencias enviadas con unidades inexistentes (400).
+type errReferenciasHuerfanas []referenciaHuerfana
+
+func (e errReferenciasHuerfanas) Error() string {
+	return "Hay referencias asignadas a unidades temáticas que no existen."
+}
+
+// normalizarUnidadesAplica: positivos, sin repetidos y ordenados.
+func normalizarUnidadesAplica(nums []int32) []int32 {
+	out := make([]int32, 0, len(nums))
+	vistos := make(map[int32]bool, len(nums))
+	for _, n := range nums {
+		if n <= 0 || vistos[n] {
+			continue
+		}
+		vistos[n] = true
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// referenciasHuerfanas lista las referencias con números de unidad que no existen.
+func referenciasHuerfanas(ctx context.Context, tx pgx.Tx, planeacionID int) ([]referenciaHuerfana, error) {
+	rows, err := tx.Query(
+		ctx,
+		`
+SELECT r.id, r.cita_apa, x.faltantes
+FROM planeacion_referencias r
+CROSS JOIN LATERAL (
+  SELECT array_agg(u.n ORDER BY u.n) AS faltantes
+  FROM unnest(r.unidades_aplica) AS u(n)
+  WHERE NOT EXISTS (
+    SELECT 1 FROM unidades_tematicas ut
+    WHERE ut.planeacion_id = r.planeacion_id AND ut.numero = u.n
+  )
+) x
+WHERE r.planeacion_id = $1 AND x.faltantes IS NOT NULL
+ORDER BY r.id
+		`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []referenciaHuerfana
+	for rows.Next() {
+		var h referenciaHuerfana
+		if err := rows.Scan(&h.ReferenciaID, &h.CitaAPA, &h.Unidades); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// verificarReferenciasUnidades: si las referencias se acaban de enviar, los
+// números inexistentes son error del cliente; si no, se podan (cambiaron las unidades).
+func verificarReferenciasUnidades(ctx context.Context, tx pgx.Tx, planeacionID int, enviadas bool) error {
+	if !enviadas {
+		return podarUnidadesAplica(ctx, tx, planeacionID)
+	}
+	huerfanas, err := referenciasHuerfanas(ctx, tx, planeacionID)
+	if err != nil {
+		return err
+	}
+	if len(huerfanas) > 0 {
+		return errReferenciasHuerfanas(huerfanas)
+	}
+	return nil
+}
+
+// podarUnidadesAplica quita de unidades_aplica los números que ya no existen.
+func podarUnidadesAplica(ctx context.Context, tx pgx.Tx, planeacionID int) error {
+	_, err := tx.Exec(
+		ctx,
+		`
+UPDATE planeacion_referencias r
+SET unidades_aplica = (
+  SELECT COALESCE(array_agg(u.n ORDER BY u.n), '{}'::int[])
+  FROM unnest(r.unidades_aplica) AS u(n)
+  WHERE EXISTS (
+    SELECT 1 FROM unidades_tematicas ut
+    WHERE ut.planeacion_id = r.planeacion_id AND ut.numero = u.n
+  )
+)
+WHERE r.planeacion_id = $1 AND cardinality(r.unidades_aplica) > 0
+		`,
+		planeacionID,
+	)
+	return err
+}
+
+// remapUnidadesAplica reasigna números de unidad (viejos[i] → nuevos[i]);
+// los números sin correspondencia se eliminan de la lista.
+func remapUnidadesAplica(ctx context.Context, tx pgx.Tx, planeacionID int, viejos, nuevos []int) error {
+	_, err := tx.Exec(
+		ctx,
+		`
+UPDATE planeacion_referencias r
+SET unidades_aplica = (
+  SELECT COALESCE(array_agg(m.nuevo ORDER BY m.nuevo), '{}'::int[])
+  FROM unnest(r.unidades_aplica) AS u(n)
+  JOIN unnest($2::int[], $3::int[]) AS m(viejo, nuevo) ON m.viejo = u.n
+)
+WHERE r.planeacion_id = $1 AND cardinality(r.unidades_aplica) > 0
+		`,
+		planeacionID,
+		viejos,
+		nuevos,
+	)
+	return err
+}
+
+// unidadesSinReferenciaBasica: números de unidad sin ninguna referencia "Básica" asignada.
+func unidadesSinReferenciaBasica(ctx context.Context, db consultaDB, planeacionID int) ([]int, error) {
+	rows, err := db.Query(
+		ctx,
+		`
+SELECT ut.numero
+FROM unidades_tematicas ut
+WHERE ut.planeacion_id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM planeacion_referencias r
+    WHERE r.planeacion_id = ut.planeacion_id
+      AND ut.numero = ANY(r.unidades_aplica)
+      AND lower(btrim(r.tipo)) IN ('básica', 'basica')
+  )
+ORDER BY ut.numero
+		`,
+		planeacionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []int{}
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
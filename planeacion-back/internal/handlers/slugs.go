@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Slugs públicos
+// - Se generan al finalizar por primera vez (nombre + asignatura + id),
+//   con acentos transliterados ("Programación Básica" -> "programacion-basica").
+// - El docente puede elegir uno propio (PUT /api/planeaciones/:id/slug).
+// - Los slugs anteriores quedan en planeacion_slugs_historial y
+//   GET /api/public/planeaciones/slug/:slug responde 301 al actual.
+// =============================
+
+const (
+	minLargoSlug = 3
+	maxLargoSlug = 80
+)
+
+var reSlugValido = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validarSlug revisa un slug elegido por el docente.
+func validarSlug(s string) error {
+	switch {
+	case len(s) < minLargoSlug || len(s) > maxLargoSlug:
+		return errors.New("el slug debe tener entre " + strconv.Itoa(minLargoSlug) + " y " + strconv.Itoa(maxLargoSlug) + " caracteres")
+	case !reSlugValido.MatchString(s):
+		return errors.New("el slug solo admite minúsculas sin acentos, números y guiones entre palabras")
+	case strings.Trim(s, "0123456789-") == "":
+		// solo números se confundiría con un id
+		return errors.New("el slug debe contener al menos una letra")
+	}
+	return nil
+}
+
+// recortarSlug limita el largo sin dejar un guion al final.
+func recortarSlug(s string) string {
+	if len(s) > maxLargoSlug {
+		s = strings.TrimRight(s[:maxLargoSlug], "-")
+	}
+	return s
+}
+
+// slugEnUso: true si otra planeación tiene el slug o lo tuvo (historial).
+func slugEnUso(ctx context.Context, tx pgx.Tx, slug string, planeacionID int) (bool, error) {
+	var enUso bool
+	err := tx.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM planeaciones WHERE slug = $1 AND id <> $2)
+		     OR EXISTS (SELECT 1 FROM planeacion_slugs_historial WHERE slug = $1 AND planeacion_id <> $2)`,
+		slug,
+		planeacionID,
+	).Scan(&enUso)
+	return enUso, err
+}
+
+// generarSlug arma el slug de la primera publicación. Si ya está ocupado
+// (p. ej. por un slug personalizado de otra planeación) agrega -2, -3, ...
+func generarSlug(ctx context.Context, tx pgx.Tx, planeacionID int, nombre, asignatura string) (string, error) {
+	return slugLibre(baseSlug(planeacionID, nombre, asignatura), func(candidato string) (bool, error) {
+		return slugEnUso(ctx, tx, candidato, planeacionID)
+	})
+}
+
+// baseSlug: nombre + asignatura + id dentro de maxLargoSlug. Si el texto no
+// deja letras transliterables (p. ej. otro alfabeto) antepone "planeacion",
+// para que el slug no quede solo en números (validarSlug).
+func baseSlug(planeacionID int, nombre, asignatura string) string {
+	sufijo := "-" + strconv.Itoa(planeacionID)
+	base := slugify(strings.TrimSpace(nombre) + "-" + strings.TrimSpace(asignatura))
+	if strings.Trim(base, "0123456789-") == "" {
+		base = strings.TrimRight("planeacion-"+base, "-")
+	}
+	if len(base)+len(sufijo) > maxLargoSlug {
+		base = strings.TrimRight(base[:maxLargoSlug-len(sufijo)], "-")
+	}
+	return base + sufijo
+}
+
+// slugLibre regresa base o el primer base-2, base-3, ... que no esté en uso,
+// recortando base para que el sufijo quepa en maxLargoSlug.
+func slugLibre(base string, enUso func(string) (bool, error)) (string, error) {
+	candidato := base
+	for n := 2; ; n++ {
+		ocupado, err := enUso(candidato)
+		if err != nil {
+			return "", err
+		}
+		if !ocupado {
+			return candidato, nil
+		}
+		sufijo := "-" + strconv.Itoa(n)
+		candidato = base
+		if len(candidato)+len(sufijo) > maxLargoSlug {
+			candidato = strings.TrimRight(candidato[:maxLargoSlug-len(sufijo)], "-")
+		}
+		candidato += sufijo
+	}
+}
+
+// =============================
+// PUT /api/planeaciones/:id/slug
+// Body: { "slug": "programacion-basica-2026" }
+// El slug anterior sigue funcionando (301 al nuevo).
+// =============================
+
+type slugRequest struct {
+	Slug string `json:"slug"`
+}
+
+func (h *PlaneacionesHandler) PutSlug(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id inválido"})
+		return
+	}
+
+	var body slugRequest
+	if _, ok := decodeJSONBody(c, &body); !ok {
+		return
+	}
+
+	nuevo := strings.ToLower(strings.TrimSpace(body.Slug))
+	if err := validarSlug(nuevo); err != nil {
+		resp := gin.H{"error": err.Error()}
+		if sugerido := recortarSlug(slugify(body.Slug)); validarSlug(sugerido) == nil {
+			resp["sugerencia"] = sugerido
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	var (
+		anterior *string
+		status   string
+	)
+	err = tx.QueryRow(
+		c,
+		`SELECT slug, status::text FROM planeaciones
+		 WHERE id = $1 AND docente_id = $2 AND deleted_at IS NULL FOR UPDATE`,
+		id,
+		claims.UserID,
+	).Scan(&anterior, &status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if status == "archivada" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Planeación archivada. Para cambiar el slug debes desarchivarla primero.",
+			"hint":  "POST /api/planeaciones/:id/desarchivar",
+		})
+		return
+	}
+
+	if anterior != nil && *anterior == nuevo {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "slug": nuevo})
+		return
+	}
+
+	enUso, err := slugEnUso(c, tx, nuevo, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+	if enUso {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ese slug ya está en uso"})
+		return
+	}
+
+	// El slug actual pasa al historial; si el nuevo era uno anterior de esta
+	// misma planeación, deja de ser redirección.
+	if anterior != nil && strings.TrimSpace(*anterior) != "" {
+		if _, err := tx.Exec(
+			c,
+			`INSERT INTO planeacion_slugs_historial (slug, planeacion_id) VALUES ($1, $2)
+			 ON CONFLICT (slug) DO UPDATE SET reemplazado_at = now()`,
+			*anterior,
+			id,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar el historial de slugs: " + err.Error()})
+			return
+		}
+	}
+	if _, err := tx.Exec(
+		c,
+		`DELETE FROM planeacion_slugs_historial WHERE slug = $1 AND planeacion_id = $2`,
+		nuevo,
+		id,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el historial de slugs: " + err.Error()})
+		return
+	}
+
+	if _, err := tx.Exec(
+		c,
+		`UPDATE planeaciones SET slug = $2, updated_at = now() WHERE id = $1`,
+		id,
+		nuevo,
+	); err != nil {
+		if esViolacionUnica(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ese slug ya está en uso"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar slug: " + err.Error()})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionSlug, "planeacion", int64(id), gin.H{
+		"slug": gin.H{"de": anterior, "a": nuevo},
+	})); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
+		return
+	}
+	if status == "finalizada" {
+		invalidarCachePublica()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "slug": nuevo, "anterior": anterior})
+}
+
+// =============================
+// Redirección de slugs anteriores
+// =============================
+
+// redirigirSlugAnterior responde 301 si slug está en el historial de una
+// planeación publicada que sigue teniendo slug, o 410 si esa planeación se
+// envió a la papelera, igual que su slug actual (regresa true). Si nunca fue
+// pública regresa false y el llamador responde como con cualquier slug
+// desconocido, sin revelar el slug actual. La ruta se conserva y solo cambia
+// el segmento del slug (sirve para el documento y el .ics).
+func (h *PublicPlaneacionesHandler) redirigirSlugAnterior(c *gin.Context, slug string) (bool, error) {
+	var (
+		actual    string
+		eliminada bool
+	)
+	err := h.DB.QueryRow(
+		c,
+		`SELECT p.slug, p.deleted_at IS NOT NULL FROM planeacion_slugs_historial sh
+		 JOIN planeaciones p ON p.id = sh.planeacion_id
+		 WHERE sh.slug = $1 AND p.slug IS NOT NULL AND p.slug <> ''
+		   AND (`+sqlPublicada+` OR `+sqlEliminadaPublicada+`)`,
+		slug,
+	).Scan(&actual, &eliminada)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if eliminada {
+		responderPlaneacionEliminada(c)
+		return true, nil
+	}
+
+	destino := strings.Replace(c.Request.URL.Path, "/slug/"+slug, "/slug/"+actual, 1)
+	if c.Request.URL.RawQuery != "" {
+		destino += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, destino)
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	casos := []struct {
+		entrada string
+		want    string
+	}{
+		{"Programación Básica", "programacion-basica"},
+		{"  Diseño de Compiladores  ", "diseno-de-compiladores"},
+		{"Lingüística", "linguistica"},
+		{"ÁÉÍÓÚ ÑÜ", "aeiou-nu"},
+		{"C++ / Java: 2ª parte!", "c-java-2-parte"},
+		{"---Ya--con---guiones---", "ya-con-guiones"},
+		{"Программирование", ""},
+		{"数学 101", "101"},
+		{"", ""},
+	}
+	for _, tc := range casos {
+		t.Run(tc.entrada, func(t *testing.T) {
+			if got := slugify(tc.entrada); got != tc.want {
+				t.Errorf("slugify(%q) = %q, se esperaba %q", tc.entrada, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidarSlug(t *testing.T) {
+	casos := []struct {
+		slug string
+		ok   bool
+	}{
+		{"programacion-basica-2026", true},
+		{"abc", true},
+		{strings.Repeat("a", maxLargoSlug), true},
+		{"ab", false},
+		{strings.Repeat("a", maxLargoSlug+1), false},
+		{"Programacion", false},
+		{"programación", false},
+		{"doble--guion", false},
+		{"-inicio", false},
+		{"fin-", false},
+		{"con espacio", false},
+		{"2026", false},
+		{"12-34", false},
+	}
+	for _, tc := range casos {
+		t.Run(tc.slug, func(t *testing.T) {
+			if err := validarSlug(tc.slug); (err == nil) != tc.ok {
+				t.Errorf("validarSlug(%q) = %v, se esperaba ok=%v", tc.slug, err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestRecortarSlug(t *testing.T) {
+	s := strings.Repeat("a", maxLargoSlug-1) + "-bbb"
+	if got := recortarSlug(s); got != strings.Repeat("a", maxLargoSlug-1) {
+		t.Errorf("recortarSlug dejó %q (%d)", got, len(got))
+	}
+	if got := recortarSlug("corto"); got != "corto" {
+		t.Errorf("recortarSlug(corto) = %q", got)
+	}
+}
+
+func TestBaseSlug(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		asignatura string
+		want       string
+	}{
+		{"Planeación 2026", "Cálculo", "planeacion-2026-calculo-42"},
+		{"Solo nombre", "", "solo-nombre-42"},
+		{"", "", "planeacion-42"},
+		{"Программа", "数学", "planeacion-42"},
+		{"数学 101", "", "planeacion-101-42"},
+	}
+	for _, tc := range casos {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := baseSlug(42, tc.nombre, tc.asignatura); got != tc.want {
+				t.Errorf("baseSlug = %q, se esperaba %q", got, tc.want)
+			}
+			if err := validarSlug(baseSlug(42, tc.nombre, tc.asignatura)); err != nil {
+				t.Errorf("baseSlug no pasa validarSlug: %v", err)
+			}
+		})
+	}
+
+	largo := baseSlug(12345, strings.Repeat("palabra ", 30), "x")
+	if len(largo) > maxLargoSlug || !strings.HasSuffix(largo, "-12345") || strings.Contains(largo, "--") {
+		t.Errorf("baseSlug largo = %q (%d)", largo, len(largo))
+	}
+}
+
+func TestSlugLibre(t *testing.T) {
+	ocupados := func(slugs ...string) func(string) (bool, error) {
+		m := map[string]bool{}
+		for _, s := range slugs {
+			m[s] = true
+		}
+		return func(s string) (bool, error) { return m[s], nil }
+	}
+
+	casos := []struct {
+		nombre   string
+		base     string
+		ocupados []string
+		want     string
+	}{
+		{"libre", "curso-1", nil, "curso-1"},
+		{"primera colisión", "curso-1", []string{"curso-1"}, "curso-1-2"},
+		{"varias colisiones", "curso-1", []string{"curso-1", "curso-1-2", "curso-1-3"}, "curso-1-4"},
+		{
+			"el sufijo cabe en el largo máximo",
+			strings.Repeat("a", maxLargoSlug),
+			[]string{strings.Repeat("a", maxLargoSlug)},
+			strings.Repeat("a", maxLargoSlug-2) + "-2",
+		},
+		{
+			"sin guion doble al recortar",
+			strings.Repeat("a", maxLargoSlug-3) + "-bc",
+			[]string{strings.Repeat("a", maxLargoSlug-3) + "-bc"},
+			strings.Repeat("a", maxLargoSlug-3) + "-2",
+		},
+	}
+	for _, tc := range casos {
+		t.Run(tc.nombre, func(t *testing.T) {
+			got, err := slugLibre(tc.base, ocupados(tc.ocupados...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("slugLibre = %q, se esperaba %q", got, tc.want)
+			}
+			if len(got) > maxLargoSlug {
+				t.Errorf("slugLibre excede %d caracteres: %d", maxLargoSlug, len(got))
+			}
+		})
+	}
+
+	falla := errors.New("sin conexión")
+	if _, err := slugLibre("x", func(string) (bool, error) { return false, falla }); !errors.Is(err, falla) {
+		t.Errorf("slugLibre no propagó el error: %v", err)
+	}
+}
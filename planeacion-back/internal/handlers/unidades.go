@@ -17,12 +17,18 @@ type UnidadesHandler struct {
 
 // RegisterUnidadesRoutes registra rutas de catálogo de unidades académicas.
 func RegisterUnidadesRoutes(rg *gin.RouterGroup, h *UnidadesHandler) {
-	// GET /api/unidades        → lista todas las unidades_academicas
+	// GET /api/unidades        → lista las unidades_academicas activas
 	rg.GET("/unidades", h.GetAllUnidades)
 
 	// GET /api/unidades/:id    → obtiene una unidad por id
 	rg.GET("/unidades/:id", h.GetUnidadByID)
 
+	// Catálogos académicos de la unidad (ver catalogos.go)
+	rg.GET("/unidades/:id/programas", h.ListProgramas)                        // GET /api/unidades/:id/programas
+	rg.GET("/unidades/:id/unidades-aprendizaje", h.BuscarUnidadesAprendizaje) // GET /api/unidades/:id/unidades-aprendizaje?q=&programa_id=&academia_id=&semestre=
+	rg.GET("/programas/:id/academias", h.ListAcademias)                       // GET /api/programas/:id/academias
+	rg.GET("/academias/:id/unidades-aprendizaje", h.ListUnidadesAprendizaje)  // GET /api/academias/:id/unidades-aprendizaje
+
 	// Ping opcional
 	rg.GET("/unidades/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"ok": true, "msg": "unidades handler listo"})
@@ -34,8 +40,9 @@ func (h *UnidadesHandler) GetAllUnidades(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	const query = `
-		SELECT id, nombre, abreviatura
+		SELECT id, nombre, COALESCE(abreviatura, ''), activa
 		FROM public.unidades_academicas
+		WHERE activa
 		ORDER BY nombre;
 	`
 
@@ -53,7 +60,7 @@ func (h *UnidadesHandler) GetAllUnidades(c *gin.Context) {
 
 	for rows.Next() {
 		var u models.UnidadAcademica
-		if err := rows.Scan(&u.ID, &u.Nombre, &u.Abreviatura); err != nil {
+		if err := rows.Scan(&u.ID, &u.Nombre, &u.Abreviatura, &u.Activa); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "error al leer fila de public.unidades_academicas",
 				"msg":   err.Error(),
@@ -91,13 +98,13 @@ func (h *UnidadesHandler) GetUnidadByID(c *gin.Context) {
 	}
 
 	const query = `
-		SELECT id, nombre, abreviatura
+		SELECT id, nombre, COALESCE(abreviatura, ''), activa
 		FROM public.unidades_academicas
 		WHERE id = $1;
 	`
 
 	var u models.UnidadAcademica
-	err = h.DB.QueryRow(ctx, query, id).Scan(&u.ID, &u.Nombre, &u.Abreviatura)
+	err = h.DB.QueryRow(ctx, query, id).Scan(&u.ID, &u.Nombre, &u.Abreviatura, &u.Activa)
 	if err != nil {
 		// No usamos pgx.ErrNoRows explícito para mantenerlo simple por ahora
 		c.JSON(http.StatusNotFound, gin.H{
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/vsalazars/planeacion-back/internal/models"
+)
+
+// =============================
+// Administración de unidades académicas (UnidadesHandler, solo admin)
+// Las unidades no se borran: DELETE las desactiva (activa = false) y dejan
+// de ofrecerse en GET /api/unidades, registro y cambio de unidad.
+// =============================
+
+type unidadAcademicaRequest struct {
+	Nombre      string `json:"nombre"`
+	Abreviatura string `json:"abreviatura"`
+	Activa      *bool  `json:"activa"` // solo PUT; omitido = sin cambio
+}
+
+func (r *unidadAcademicaRequest) validar() error {
+	r.Nombre = strings.Join(strings.Fields(r.Nombre), " ")
+	r.Abreviatura = strings.Join(strings.Fields(r.Abreviatura), " ")
+	if r.Nombre == "" {
+		return errors.New("nombre es obligatorio")
+	}
+	if len([]rune(r.Nombre)) > 255 {
+		return errors.New("nombre admite máximo 255 caracteres")
+	}
+	if r.Abreviatura == "" {
+		return errors.New("abreviatura es obligatoria")
+	}
+	if len([]rune(r.Abreviatura)) > 50 {
+		return errors.New("abreviatura admite máximo 50 caracteres")
+	}
+	return nil
+}
+
+// Estadísticas de una unidad académica
+type estadisticasUnidad struct {
+	Usuarios                int `json:"usuarios"`
+	UsuariosActivos         int `json:"usuarios_activos"`
+	Planeaciones            int `json:"planeaciones"`
+	PlaneacionesFinalizadas int `json:"planeaciones_finalizadas"`
+}
+
+type unidadAcademicaAdmin struct {
+	models.UnidadAcademica
+	Estadisticas estadisticasUnidad `json:"estadisticas"`
+}
+
+const unidadAcademicaCols = `id, nombre, COALESCE(abreviatura, ''), activa`
+
+func scanUnidadAcademica(row pgx.Row, u *models.UnidadAcademica) error {
+	return row.Scan(&u.ID, &u.Nombre, &u.Abreviatura, &u.Activa)
+}
+
+// Conteos por unidad (ua = alias de unidades_academicas)
+const estadisticasUnidadSQL = `
+	(SELECT COUNT(*) FROM public.usuarios us WHERE us.unidad_id = ua.id),
+	(SELECT COUNT(*) FROM public.usuarios us WHERE us.unidad_id = ua.id AND us.is_active),
+	(SELECT COUNT(*) FROM public.planeaciones p WHERE p.unidad_academica_id = ua.id AND p.deleted_at IS NULL),
+	(SELECT COUNT(*) FROM public.planeaciones p WHERE p.unidad_academica_id = ua.id AND ` + sqlPublicada + ` AND p.deleted_at IS NULL)`
+
+func responderErrorUnidad(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "unidad_academica no encontrada"})
+	case esViolacionUnica(err):
+		c.JSON(http.StatusConflict, gin.H{"error": "ya existe una unidad académica con esa abreviatura"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+			"msg":   err.Error(),
+		})
+	}
+}
+
+// GET /api/admin/unidades?incluir_inactivas=1
+// Unidades con sus estadísticas (por defecto solo activas).
+func (h *UnidadesHandler) ListUnidadesAdmin(c *gin.Context) {
+	where := "WHERE ua.activa"
+	if c.Query("incluir_inactivas") == "1" {
+		where = ""
+	}
+
+	rows, err := h.DB.Query(
+		c.Request.Context(),
+		`SELECT ua.id, ua.nombre, COALESCE(ua.abreviatura, ''), ua.activa,`+estadisticasUnidadSQL+`
+		 FROM public.unidades_academicas ua
+		 `+where+`
+		 ORDER BY ua.activa DESC, ua.nombre`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al consultar public.unidades_academicas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]unidadAcademicaAdmin, 0)
+	for rows.Next() {
+		var u unidadAcademicaAdmin
+		if err := rows.Scan(
+			&u.ID, &u.Nombre, &u.Abreviatura, &u.Activa,
+			&u.Estadisticas.Usuarios, &u.Estadisticas.UsuariosActivos,
+			&u.Estadisticas.Planeaciones, &u.Estadisticas.PlaneacionesFinalizadas,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "error al leer fila de public.unidades_academicas",
+				"msg":   err.Error(),
+			})
+			return
+		}
+		items = append(items, u)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "error al iterar resultados de public.unidades_academicas",
+			"msg":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// POST /api/admin/unidades
+// Body: { "nombre": "Escuela Superior de Cómputo", "abreviatura": "ESCOM" }
+func (h *UnidadesHandler) CreateUnidad(c *gin.Context) {
+	var req unidadAcademicaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var u models.UnidadAcademica
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanUnidadAcademica(tx.QueryRow(
+			c.Request.Context(),
+			`INSERT INTO public.unidades_academicas (nombre, abreviatura)
+			 VALUES ($1, $2)
+			 RETURNING `+unidadAcademicaCols,
+			req.Nombre,
+			req.Abreviatura,
+		), &u)
+		return eventoDeSesion(c, accionUnidadAcademicaCrear, "unidad_academica", int64(u.ID), aMapa(u)), err
+	})
+	if err != nil {
+		responderErrorUnidad(c, "error al crear unidad académica", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, u)
+}
+
+// PUT /api/admin/unidades/:id
+// Body: { "nombre": "...", "abreviatura": "...", "activa": true }
+func (h *UnidadesHandler) UpdateUnidad(c *gin.Context) {
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+	var req unidadAcademicaRequest
+	if _, ok := decodeJSONBody(c, &req); !ok {
+		return
+	}
+	if err := req.validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var u models.UnidadAcademica
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := scanUnidadAcademica(tx.QueryRow(
+			c.Request.Context(),
+			`UPDATE public.unidades_academicas
+			 SET nombre = $2, abreviatura = $3, activa = COALESCE($4, activa)
+			 WHERE id = $1
+			 RETURNING `+unidadAcademicaCols,
+			id,
+			req.Nombre,
+			req.Abreviatura,
+			boolOrNil(req.Activa),
+		), &u)
+		return eventoDeSesion(c, accionUnidadAcademicaEditar, "unidad_academica", int64(u.ID), aMapa(u)), err
+	})
+	if err != nil {
+		responderErrorUnidad(c, "error al actualizar unidad académica", err)
+		return
+	}
+	invalidarCachePublica()
+
+	c.JSON(http.StatusOK, u)
+}
+
+// DELETE /api/admin/unidades/:id
+// Baja lógica: usuarios, planeaciones y catálogos se conservan.
+// Se reactiva con PUT { "activa": true }.
+func (h *UnidadesHandler) DesactivarUnidad(c *gin.Context) {
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var u unidadAcademicaAdmin
+	err := conAuditoria(c, h.DB, func(tx pgx.Tx) (eventoAuditoria, error) {
+		err := tx.QueryRow(
+			c.Request.Context(),
+			`UPDATE public.unidades_academicas ua SET activa = false
+			 WHERE ua.id = $1
+			 RETURNING ua.id, ua.nombre, COALESCE(ua.abreviatura, ''), ua.activa,`+estadisticasUnidadSQL,
+			id,
+		).Scan(
+			&u.ID, &u.Nombre, &u.Abreviatura, &u.Activa,
+			&u.Estadisticas.Usuarios, &u.Estadisticas.UsuariosActivos,
+			&u.Estadisticas.Planeaciones, &u.Estadisticas.PlaneacionesFinalizadas,
+		)
+		return eventoDeSesion(c, accionUnidadAcademicaDesactivar, "unidad_academica", int64(id), gin.H{"activa": false}), err
+	})
+	if err != nil {
+		responderErrorUnidad(c, "error al desactivar unidad académica", err)
+		return
+	}
+	invalidarCachePublica()
+
+	c.JSON(http.StatusOK, u)
+}
+
+// GET /api/admin/unidades/:id/estadisticas
+func (h *UnidadesHandler) EstadisticasUnidad(c *gin.Context) {
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var u unidadAcademicaAdmin
+	err := h.DB.QueryRow(
+		c.Request.Context(),
+		`SELECT ua.id, ua.nombre, COALESCE(ua.abreviatura, ''), ua.activa,`+estadisticasUnidadSQL+`
+		 FROM public.unidades_academicas ua
+		 WHERE ua.id = $1`,
+		id,
+	).Scan(
+		&u.ID, &u.Nombre, &u.Abreviatura, &u.Activa,
+		&u.Estadisticas.Usuarios, &u.Estadisticas.UsuariosActivos,
+		&u.Estadisticas.Planeaciones, &u.Estadisticas.PlaneacionesFinalizadas,
+	)
+	if err != nil {
+		responderErrorUnidad(c, "error al consultar estadísticas de la unidad académica", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
@@ -0,0 +1,1062 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================
+// Unidades temáticas y sesiones didácticas por número
+// /api/planeaciones/:id/unidades/:numero[/sesiones/:n]
+// Editan una sola fila (los ids se conservan); la numeración se mantiene
+// contigua 1..n al crear, borrar o reordenar.
+// =============================
+
+// Objeto JSON de una sesión (alias sd), mismo formato que GetOne
+const sesionJSONSQL = `json_build_object(
+  'id', sd.id,
+  'numero_sesion', sd.numero_sesion,
+  'temas_subtemas', sd.temas_subtemas,
+  'actividades', json_build_object(
+    'inicio', sd.actividades_inicio,
+    'desarrollo', sd.actividades_desarrollo,
+    'cierre', sd.actividades_cierre
+  ),
+  'recursos', sd.recursos,
+  'evidencias', sd.evidencias,
+  'instrumentos', sd.instrumentos,
+  'valor_porcentual', sd.valor_porcentual,
+  'fecha', sd.fecha,
+  'hora_inicio', to_char(sd.hora_inicio, 'HH24:MI'),
+  'hora_fin', to_char(sd.hora_fin, 'HH24:MI'),
+  'espacio', sd.espacio
+)`
+
+// Objeto JSON de una unidad (alias ut) con sus bloques
+const unidadJSONSQL = `json_build_object(
+  'id', ut.id,
+  'numero', ut.numero,
+  'nombre_unidad_tematica', ut.nombre_unidad_tematica,
+  'unidad_competencia', ut.unidad_competencia,
+  'periodo_desarrollo', json_build_object('del', ut.periodo_del, 'al', ut.periodo_al),
+  'horas', json_build_object(
+    'aula', ut.horas_aula,
+    'laboratorio', ut.horas_laboratorio,
+    'taller', ut.horas_taller,
+    'clinica', ut.horas_clinica,
+    'otro', ut.horas_otro
+  ),
+  'sesiones_por_espacio', json_build_object(
+    'aula', ut.sesiones_aula,
+    'laboratorio', ut.sesiones_laboratorio,
+    'taller', ut.sesiones_taller,
+    'clinica', ut.sesiones_clinica,
+    'otro', ut.sesiones_otro
+  ),
+  'sesiones_totales', ut.sesiones_totales,
+  'porcentaje', ut.porcentaje,
+  'periodo_registro_eval', ut.periodo_registro_eval,
+  'aprendizajes_esperados', ut.aprendizajes_esperados,
+  'precisiones', ut.precisiones,
+  'bloques', COALESCE(
+    (
+      SELECT json_agg(` + sesionJSONSQL + ` ORDER BY sd.numero_sesion)
+      FROM sesiones_didacticas sd
+      WHERE sd.unidad_tematica_id = ut.id
+    ),
+    '[]'::json
+  )
+)`
+
+var errUnidadNoEncontrada = errors.New("Unidad temática no encontrada")
+var errSesionNoEncontrada = errors.New("Sesión no encontrada")
+
+// Payloads de edición parcial (null/omitido conserva el valor)
+type unidadPatchRequest struct {
+	NombreUnidadTematica  *string                    `json:"nombre_unidad_tematica"`
+	UnidadCompetencia     *string                    `json:"unidad_competencia"`
+	PeriodoDesarrollo     *PeriodoDesarrolloPayload  `json:"periodo_desarrollo"`
+	Horas                 *HorasPayload              `json:"horas"`
+	SesionesPorEspacio    *SesionesPorEspacioPayload `json:"sesiones_por_espacio"`
+	SesionesTotales       *int                       `json:"sesiones_totales"`
+	AprendizajesEsperados *[]string                  `json:"aprendizajes_esperados"`
+	Precisiones           *string                    `json:"precisiones"`
+	Porcentaje            *int                       `json:"porcentaje"`
+	PeriodoRegistroEval   *string                    `json:"periodo_registro_eval"`
+}
+
+type actividadesPatch struct {
+	Inicio     *string `json:"inicio"`
+	Desarrollo *string `json:"desarrollo"`
+	Cierre     *string `json:"cierre"`
+}
+
+type sesionPatchRequest struct {
+	TemasSubtemas   *string           `json:"temas_subtemas"`
+	Actividades     *actividadesPatch `json:"actividades"`
+	Recursos        *[]string         `json:"recursos"`
+	Evidencias      *[]string         `json:"evidencias"`
+	Instrumentos    *[]string         `json:"instrumentos"`
+	ValorPorcentual *int              `json:"valor_porcentual"`
+
+	ProgramacionSesion
+}
+
+// Body de reordenamiento: números actuales en el nuevo orden
+type reordenRequest struct {
+	Orden []int `json:"orden"`
+}
+
+// validarPorcentaje: el porcentaje de la unidad (si viene) va de 0 a 100.
+func validarPorcentaje(p *int) error {
+	if p != nil && (*p < 0 || *p > 100) {
+		return errors.New("El porcentaje de la unidad debe estar entre 0 y 100.")
+	}
+	return nil
+}
+
+// validarBloques: porcentajes no negativos y que no excedan 100 por unidad.
+func validarBloques(bloques []SesionBloquePayload) error {
+	sumPct := 0
+	vistos := make(map[int]bool, len(bloques))
+	for _, b := range bloques {
+		if b.ValorPorcentual < 0 {
+			return errors.New("El valor porcentual de una sesión no puede ser negativo.")
+		}
+		if vistos[b.NumeroSesion] {
+			return fmt.Errorf("La sesión %d está repetida en la unidad.", b.NumeroSesion)
+		}
+		vistos[b.NumeroSesion] = true
+		if err := b.ProgramacionSesion.validar(); err != nil {
+			return fmt.Errorf("Sesión %d: %w", b.NumeroSesion, err)
+		}
+		sumPct += b.ValorPorcentual
+	}
+	if sumPct > 100 {
+		return errors.New("La suma de valores porcentuales de las sesiones de una unidad no debe exceder 100.")
+	}
+	return nil
+}
+
+// validarUnidadesTematicas: números de unidad únicos + validarBloques en cada una.
+func validarUnidadesTematicas(uts []UnidadTematicaPayload) error {
+	vistos := make(map[int]bool, len(uts))
+	for _, ut := range uts {
+		if vistos[ut.Numero] {
+			return fmt.Errorf("La unidad temática %d está repetida.", ut.Numero)
+		}
+		vistos[ut.Numero] = true
+		if err := validarPorcentaje(ut.Porcentaje); err != nil {
+			return fmt.Errorf("Unidad %d: %w", ut.Numero, err)
+		}
+		if err := validarBloques(ut.Bloques); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertarUnidadTematica inserta la unidad con sus bloques y regresa su id.
+func insertarUnidadTematica(ctx context.Context, tx pgx.Tx, planeacionID int, ut UnidadTematicaPayload) (int64, error) {
+	var perDel, perAl *string
+	if ut.PeriodoDesarrollo.Del != nil {
+		s := strings.TrimSpace(*ut.PeriodoDesarrollo.Del)
+		if s != "" {
+			perDel = &s
+		}
+	}
+	if ut.PeriodoDesarrollo.Al != nil {
+		s := strings.TrimSpace(*ut.PeriodoDesarrollo.Al)
+		if s != "" {
+			perAl = &s
+		}
+	}
+
+	sumPct := 0
+	for _, b := range ut.Bloques {
+		sumPct += b.ValorPorcentual
+	}
+	porc := ut.Porcentaje
+	if porc == nil {
+		porc = &sumPct
+	}
+
+	var unidadID int64
+	err := tx.QueryRow(
+		ctx,
+		`
+INSERT INTO unidades_tematicas (
+  planeacion_id,
+  numero,
+  nombre_unidad_tematica,
+  unidad_competencia,
+  periodo_del,
+  periodo_al,
+  horas_aula,
+  horas_laboratorio,
+  horas_taller,
+  horas_clinica,
+  horas_otro,
+  sesiones_aula,
+  sesiones_laboratorio,
+  sesiones_taller,
+  sesiones_clinica,
+  sesiones_otro,
+  sesiones_totales,
+  porcentaje,
+  periodo_registro_eval,
+  aprendizajes_esperados,
+  precisiones
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,$8,$9,$10,$11,
+  $12,$13,$14,$15,$16,
+  $17,
+  $18,
+  $19,
+  $20,
+  $21
+)
+RETURNING id
+		`,
+		planeacionID,
+		ut.Numero,
+		strings.TrimSpace(ut.NombreUnidadTematica),
+		strings.TrimSpace(ut.UnidadCompetencia),
+		perDel,
+		perAl,
+		floatOrNil(ut.Horas.Aula),
+		floatOrNil(ut.Horas.Laboratorio),
+		floatOrNil(ut.Horas.Taller),
+		floatOrNil(ut.Horas.Clinica),
+		floatOrNil(ut.Horas.Otro),
+		intOrNil(ut.SesionesPorEspacio.Aula),
+		intOrNil(ut.SesionesPorEspacio.Laboratorio),
+		intOrNil(ut.SesionesPorEspacio.Taller),
+		intOrNil(ut.SesionesPorEspacio.Clinica),
+		intOrNil(ut.SesionesPorEspacio.Otro),
+		intOrNil(ut.SesionesTotales),
+		intOrNil(porc),
+		strOrNil(ut.PeriodoRegistroEval),
+		ut.AprendizajesEsperados,
+		strOrNil(ut.Precisiones),
+	).Scan(&unidadID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, b := range ut.Bloques {
+		if _, err := insertarSesion(ctx, tx, unidadID, b); err != nil {
+			return 0, fmt.Errorf("sesión %d: %w", b.NumeroSesion, err)
+		}
+	}
+	return unidadID, nil
+}
+
+// insertarSesion inserta una sesión didáctica y regresa su id.
+func insertarSesion(ctx context.Context, tx pgx.Tx, unidadID int64, b SesionBloquePayload) (int64, error) {
+	var sesionID int64
+	err := tx.QueryRow(
+		ctx,
+		`
+INSERT INTO sesiones_didacticas (
+  unidad_tematica_id,
+  numero_sesion,
+  temas_subtemas,
+  actividades_inicio,
+  actividades_desarrollo,
+  actividades_cierre,
+  recursos,
+  evidencias,
+  instrumentos,
+  valor_porcentual,
+  fecha,
+  hora_inicio,
+  hora_fin,
+  espacio
+) VALUES (
+  $1,$2,$3,$4,$5,$6,$7,$8,$9,$10,
+  NULLIF(btrim($11), '')::date,
+  NULLIF(btrim($12), '')::time,
+  NULLIF(btrim($13), '')::time,
+  NULLIF(btrim($14), '')
+)
+RETURNING id
+		`,
+		unidadID,
+		b.NumeroSesion,
+		strings.TrimSpace(b.TemasSubtemas),
+		strings.TrimSpace(b.Actividades.Inicio),
+		strings.TrimSpace(b.Actividades.Desarrollo),
+		strings.TrimSpace(b.Actividades.Cierre),
+		b.Recursos,
+		b.Evidencias,
+		b.Instrumentos,
+		b.ValorPorcentual,
+		strOrNil(b.Fecha),
+		strOrNil(b.HoraInicio),
+		strOrNil(b.HoraFin),
+		strOrNil(b.Espacio),
+	).Scan(&sesionID)
+	return sesionID, err
+}
+
+// validarPorcentajeUnidad revisa, ya con el cambio aplicado, la suma de la unidad.
+func validarPorcentajeUnidad(ctx context.Context, tx pgx.Tx, unidadID int64) error {
+	var suma int
+	if err := tx.QueryRow(
+		ctx,
+		`SELECT COALESCE(SUM(valor_porcentual), 0) FROM sesiones_didacticas WHERE unidad_tematica_id = $1`,
+		unidadID,
+	).Scan(&suma); err != nil {
+		return err
+	}
+	if suma > 100 {
+		return fmt.Errorf("La suma de valores porcentuales de las sesiones de una unidad no debe exceder 100 (quedaría en %d).", suma)
+	}
+	return nil
+}
+
+// unidadIDPorNumero busca la unidad dentro de la planeación.
+func unidadIDPorNumero(ctx context.Context, tx pgx.Tx, planeacionID, numero int) (int64, error) {
+	var unidadID int64
+	err := tx.QueryRow(
+		ctx,
+		`SELECT id FROM unidades_tematicas WHERE planeacion_id = $1 AND numero = $2`,
+		planeacionID,
+		numero,
+	).Scan(&unidadID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, errUnidadNoEncontrada
+	}
+	return unidadID, err
+}
+
+func unidadJSON(ctx context.Context, q pgx.Tx, unidadID int64) (json.RawMessage, error) {
+	var raw []byte
+	err := q.QueryRow(ctx, `SELECT `+unidadJSONSQL+` FROM unidades_tematicas ut WHERE ut.id = $1`, unidadID).Scan(&raw)
+	return raw, err
+}
+
+func sesionJSON(ctx context.Context, q pgx.Tx, sesionID int64) (json.RawMessage, error) {
+	var raw []byte
+	err := q.QueryRow(ctx, `SELECT `+sesionJSONSQL+` FROM sesiones_didacticas sd WHERE sd.id = $1`, sesionID).Scan(&raw)
+	return raw, err
+}
+
+// esPermutacion: orden contiene exactamente 1..n una vez cada uno.
+func esPermutacion(orden []int, n int) bool {
+	if len(orden) != n {
+		return false
+	}
+	vistos := make([]bool, n+1)
+	for _, v := range orden {
+		if v < 1 || v > n || vistos[v] {
+			return false
+		}
+		vistos[v] = true
+	}
+	return true
+}
+
+func paramPositivo(c *gin.Context, name string) (int, bool) {
+	n, err := strconv.Atoi(c.Param(name))
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " inválido"})
+		return 0, false
+	}
+	return n, true
+}
+
+// respondEdicionError traduce errores de búsqueda (404) o de BD (500).
+func respondEdicionError(c *gin.Context, msg string, err error) {
+	if errors.Is(err, errUnidadNoEncontrada) || errors.Is(err, errSesionNoEncontrada) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg + ": " + err.Error()})
+}
+
+// =============================
+// GET /api/planeaciones/:id/unidades
+// =============================
+func (h *PlaneacionesHandler) ListUnidades(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var raw []byte
+	err = h.DB.QueryRow(
+		c,
+		`
+SELECT COALESCE(
+  (SELECT json_agg(`+unidadJSONSQL+` ORDER BY ut.numero)
+   FROM unidades_tematicas ut
+   WHERE ut.planeacion_id = p.id),
+  '[]'::json
+)
+FROM planeaciones p
+WHERE p.id = $1 AND p.docente_id = $2 AND p.deleted_at IS NULL
+		`,
+		id,
+		claims.UserID,
+	).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Planeación no encontrada o no pertenece al usuario"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, json.RawMessage(raw))
+}
+
+// =============================
+// GET /api/planeaciones/:id/unidades/:numero
+// =============================
+func (h *PlaneacionesHandler) GetUnidad(c *gin.Context) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+
+	var raw []byte
+	err = h.DB.QueryRow(
+		c,
+		`
+SELECT `+unidadJSONSQL+`
+FROM unidades_tematicas ut
+JOIN planeaciones p ON p.id = ut.planeacion_id
+WHERE p.id = $1 AND p.docente_id = $2 AND p.deleted_at IS NULL AND ut.numero = $3
+		`,
+		id,
+		claims.UserID,
+		numero,
+	).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errUnidadNoEncontrada.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, json.RawMessage(raw))
+}
+
+// =============================
+// POST /api/planeaciones/:id/unidades
+// Body: unidad (mismo formato que en PUT, bloques opcionales).
+// numero omitido/0 → al final; si no, se inserta en esa posición.
+// =============================
+func (h *PlaneacionesHandler) CreateUnidad(c *gin.Context) {
+	var body UnidadTematicaPayload
+	h.editarUnidades(c, &body, http.StatusCreated, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		if err := validarPorcentaje(body.Porcentaje); err != nil {
+			return nil, errValidacion{err}
+		}
+		if err := validarBloques(body.Bloques); err != nil {
+			return nil, errValidacion{err}
+		}
+
+		var total int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM unidades_tematicas WHERE planeacion_id = $1`, id).Scan(&total); err != nil {
+			return nil, err
+		}
+		if body.Numero <= 0 || body.Numero > total+1 {
+			body.Numero = total + 1
+		}
+		if body.Numero <= total {
+			if _, err := tx.Exec(
+				ctx,
+				`UPDATE unidades_tematicas SET numero = numero + 1 WHERE planeacion_id = $1 AND numero >= $2`,
+				id,
+				body.Numero,
+			); err != nil {
+				return nil, err
+			}
+
+			viejos, nuevos := make([]int, total), make([]int, total)
+			for i := range viejos {
+				viejos[i], nuevos[i] = i+1, i+1
+				if i+1 >= body.Numero {
+					nuevos[i] = i + 2
+				}
+			}
+			if err := remapUnidadesAplica(ctx, tx, id, viejos, nuevos); err != nil {
+				return nil, err
+			}
+		}
+
+		unidadID, err := insertarUnidadTematica(ctx, tx, id, body)
+		if err != nil {
+			return nil, err
+		}
+		if err := verificarPeriodosUnidades(ctx, tx, id, body.Numero); err != nil {
+			return nil, err
+		}
+		return unidadJSON(ctx, tx, unidadID)
+	})
+}
+
+// =============================
+// PATCH /api/planeaciones/:id/unidades/:numero
+// Solo campos de la unidad (los bloques van por /sesiones; el número por /reorden).
+// =============================
+func (h *PlaneacionesHandler) UpdateUnidad(c *gin.Context) {
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+
+	var body unidadPatchRequest
+	h.editarUnidades(c, &body, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		if err := validarPorcentaje(body.Porcentaje); err != nil {
+			return nil, errValidacion{err}
+		}
+
+		unidadID, err := unidadIDPorNumero(ctx, tx, id, numero)
+		if err != nil {
+			return nil, err
+		}
+
+		per := body.PeriodoDesarrollo
+		if per == nil {
+			per = &PeriodoDesarrolloPayload{}
+		}
+		horas := body.Horas
+		if horas == nil {
+			horas = &HorasPayload{}
+		}
+		ses := body.SesionesPorEspacio
+		if ses == nil {
+			ses = &SesionesPorEspacioPayload{}
+		}
+		var aprendizajes any
+		if body.AprendizajesEsperados != nil {
+			aprendizajes = *body.AprendizajesEsperados
+		}
+
+		// Fechas: "" las limpia, null/omitido las conserva
+		_, err = tx.Exec(
+			ctx,
+			`
+UPDATE unidades_tematicas
+SET
+  nombre_unidad_tematica = COALESCE(btrim($2), nombre_unidad_tematica),
+  unidad_competencia     = COALESCE(btrim($3), unidad_competencia),
+  periodo_del            = CASE WHEN $4::text IS NULL THEN periodo_del ELSE NULLIF(btrim($4::text), '')::date END,
+  periodo_al             = CASE WHEN $5::text IS NULL THEN periodo_al ELSE NULLIF(btrim($5::text), '')::date END,
+  horas_aula             = COALESCE($6, horas_aula),
+  horas_laboratorio      = COALESCE($7, horas_laboratorio),
+  horas_taller           = COALESCE($8, horas_taller),
+  horas_clinica          = COALESCE($9, horas_clinica),
+  horas_otro             = COALESCE($10, horas_otro),
+  sesiones_aula          = COALESCE($11, sesiones_aula),
+  sesiones_laboratorio   = COALESCE($12, sesiones_laboratorio),
+  sesiones_taller        = COALESCE($13, sesiones_taller),
+  sesiones_clinica       = COALESCE($14, sesiones_clinica),
+  sesiones_otro          = COALESCE($15, sesiones_otro),
+  sesiones_totales       = COALESCE($16, sesiones_totales),
+  porcentaje             = COALESCE($17, porcentaje),
+  periodo_registro_eval  = COALESCE($18, periodo_registro_eval),
+  aprendizajes_esperados = COALESCE($19, aprendizajes_esperados),
+  precisiones            = COALESCE($20, precisiones)
+WHERE id = $1
+			`,
+			unidadID,
+			strOrNil(body.NombreUnidadTematica),
+			strOrNil(body.UnidadCompetencia),
+			strOrNil(per.Del),
+			strOrNil(per.Al),
+			floatOrNil(horas.Aula),
+			floatOrNil(horas.Laboratorio),
+			floatOrNil(horas.Taller),
+			floatOrNil(horas.Clinica),
+			floatOrNil(horas.Otro),
+			intOrNil(ses.Aula),
+			intOrNil(ses.Laboratorio),
+			intOrNil(ses.Taller),
+			intOrNil(ses.Clinica),
+			intOrNil(ses.Otro),
+			intOrNil(body.SesionesTotales),
+			intOrNil(body.Porcentaje),
+			strOrNil(body.PeriodoRegistroEval),
+			aprendizajes,
+			strOrNil(body.Precisiones),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := verificarPeriodosUnidades(ctx, tx, id, numero); err != nil {
+			return nil, err
+		}
+		return unidadJSON(ctx, tx, unidadID)
+	})
+}
+
+// =============================
+// DELETE /api/planeaciones/:id/unidades/:numero
+// Borra la unidad (y sus sesiones) y recorre las siguientes.
+// =============================
+func (h *PlaneacionesHandler) DeleteUnidad(c *gin.Context) {
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+
+	h.editarUnidades(c, nil, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		var total int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM unidades_tematicas WHERE planeacion_id = $1`, id).Scan(&total); err != nil {
+			return nil, err
+		}
+
+		cmd, err := tx.Exec(ctx, `DELETE FROM unidades_tematicas WHERE planeacion_id = $1 AND numero = $2`, id, numero)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return nil, errUnidadNoEncontrada
+		}
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE unidades_tematicas SET numero = numero - 1 WHERE planeacion_id = $1 AND numero > $2`,
+			id,
+			numero,
+		); err != nil {
+			return nil, err
+		}
+
+		// Las referencias pierden la unidad borrada y recorren las siguientes
+		var viejos, nuevos []int
+		for n := 1; n <= total; n++ {
+			switch {
+			case n < numero:
+				viejos, nuevos = append(viejos, n), append(nuevos, n)
+			case n > numero:
+				viejos, nuevos = append(viejos, n), append(nuevos, n-1)
+			}
+		}
+		if err := remapUnidadesAplica(ctx, tx, id, viejos, nuevos); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(`{"ok":true}`), nil
+	})
+}
+
+// =============================
+// POST /api/planeaciones/:id/unidades/reorden
+// Body: { "orden": [3, 1, 2] } → la unidad 3 pasa a ser la 1, etc.
+// =============================
+func (h *PlaneacionesHandler) ReordenarUnidades(c *gin.Context) {
+	var body reordenRequest
+	h.editarUnidades(c, &body, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		var total int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM unidades_tematicas WHERE planeacion_id = $1`, id).Scan(&total); err != nil {
+			return nil, err
+		}
+		if !esPermutacion(body.Orden, total) {
+			return nil, errValidacion{fmt.Errorf("orden debe incluir una vez cada número de unidad del 1 al %d", total)}
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			`
+UPDATE unidades_tematicas ut
+SET numero = o.pos
+FROM unnest($2::int[]) WITH ORDINALITY AS o(numero, pos)
+WHERE ut.planeacion_id = $1 AND ut.numero = o.numero AND ut.numero <> o.pos
+			`,
+			id,
+			body.Orden,
+		); err != nil {
+			return nil, err
+		}
+
+		nuevos := make([]int, total)
+		for i := range nuevos {
+			nuevos[i] = i + 1
+		}
+		if err := remapUnidadesAplica(ctx, tx, id, body.Orden, nuevos); err != nil {
+			return nil, err
+		}
+
+		var raw []byte
+		err := tx.QueryRow(
+			ctx,
+			`SELECT COALESCE(json_agg(`+unidadJSONSQL+` ORDER BY ut.numero), '[]'::json)
+			 FROM unidades_tematicas ut WHERE ut.planeacion_id = $1`,
+			id,
+		).Scan(&raw)
+		return raw, err
+	})
+}
+
+// =============================
+// POST /api/planeaciones/:id/unidades/:numero/sesiones
+// numero_sesion omitido/0 → al final; si no, se inserta en esa posición.
+// =============================
+func (h *PlaneacionesHandler) CreateSesion(c *gin.Context) {
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+
+	var body SesionBloquePayload
+	h.editarUnidades(c, &body, http.StatusCreated, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		if body.ValorPorcentual < 0 {
+			return nil, errValidacion{errors.New("El valor porcentual de una sesión no puede ser negativo.")}
+		}
+		if err := body.ProgramacionSesion.validar(); err != nil {
+			return nil, errValidacion{err}
+		}
+
+		unidadID, err := unidadIDPorNumero(ctx, tx, id, numero)
+		if err != nil {
+			return nil, err
+		}
+
+		var total int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM sesiones_didacticas WHERE unidad_tematica_id = $1`, unidadID).Scan(&total); err != nil {
+			return nil, err
+		}
+		if body.NumeroSesion <= 0 || body.NumeroSesion > total+1 {
+			body.NumeroSesion = total + 1
+		}
+		if body.NumeroSesion <= total {
+			if _, err := tx.Exec(
+				ctx,
+				`UPDATE sesiones_didacticas SET numero_sesion = numero_sesion + 1 WHERE unidad_tematica_id = $1 AND numero_sesion >= $2`,
+				unidadID,
+				body.NumeroSesion,
+			); err != nil {
+				return nil, err
+			}
+		}
+
+		sesionID, err := insertarSesion(ctx, tx, unidadID, body)
+		if err != nil {
+			return nil, err
+		}
+		if err := validarPorcentajeUnidad(ctx, tx, unidadID); err != nil {
+			return nil, errValidacion{err}
+		}
+		return sesionJSON(ctx, tx, sesionID)
+	})
+}
+
+// =============================
+// PATCH /api/planeaciones/:id/unidades/:numero/sesiones/:n
+// =============================
+func (h *PlaneacionesHandler) UpdateSesion(c *gin.Context) {
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+	n, ok := paramPositivo(c, "n")
+	if !ok {
+		return
+	}
+
+	var body sesionPatchRequest
+	h.editarUnidades(c, &body, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		if body.ValorPorcentual != nil && *body.ValorPorcentual < 0 {
+			return nil, errValidacion{errors.New("El valor porcentual de una sesión no puede ser negativo.")}
+		}
+		if err := body.ProgramacionSesion.validar(); err != nil {
+			return nil, errValidacion{err}
+		}
+
+		unidadID, err := unidadIDPorNumero(ctx, tx, id, numero)
+		if err != nil {
+			return nil, err
+		}
+
+		act := body.Actividades
+		if act == nil {
+			act = &actividadesPatch{}
+		}
+		arr := func(p *[]string) any {
+			if p == nil {
+				return nil
+			}
+			return *p
+		}
+
+		var sesionID int64
+		err = tx.QueryRow(
+			ctx,
+			`
+UPDATE sesiones_didacticas
+SET
+  temas_subtemas         = COALESCE(btrim($3), temas_subtemas),
+  actividades_inicio     = COALESCE(btrim($4), actividades_inicio),
+  actividades_desarrollo = COALESCE(btrim($5), actividades_desarrollo),
+  actividades_cierre     = COALESCE(btrim($6), actividades_cierre),
+  recursos               = COALESCE($7, recursos),
+  evidencias             = COALESCE($8, evidencias),
+  instrumentos           = COALESCE($9, instrumentos),
+  valor_porcentual       = COALESCE($10, valor_porcentual),
+  fecha                  = CASE WHEN $11::text IS NULL THEN fecha ELSE NULLIF(btrim($11::text), '')::date END,
+  hora_inicio            = CASE WHEN $12::text IS NULL THEN hora_inicio ELSE NULLIF(btrim($12::text), '')::time END,
+  hora_fin               = CASE WHEN $13::text IS NULL THEN hora_fin ELSE NULLIF(btrim($13::text), '')::time END,
+  espacio                = CASE WHEN $14::text IS NULL THEN espacio ELSE NULLIF(btrim($14::text), '') END
+WHERE unidad_tematica_id = $1 AND numero_sesion = $2
+RETURNING id
+			`,
+			unidadID,
+			n,
+			strOrNil(body.TemasSubtemas),
+			strOrNil(act.Inicio),
+			strOrNil(act.Desarrollo),
+			strOrNil(act.Cierre),
+			arr(body.Recursos),
+			arr(body.Evidencias),
+			arr(body.Instrumentos),
+			intOrNil(body.ValorPorcentual),
+			strOrNil(body.Fecha),
+			strOrNil(body.HoraInicio),
+			strOrNil(body.HoraFin),
+			strOrNil(body.Espacio),
+		).Scan(&sesionID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, errSesionNoEncontrada
+			}
+			return nil, err
+		}
+
+		// Si solo llega una de las horas, la otra es la guardada
+		if body.HoraInicio != nil || body.HoraFin != nil {
+			if err := verificarHorarioSesion(ctx, tx, sesionID); err != nil {
+				return nil, err
+			}
+		}
+		if err := validarPorcentajeUnidad(ctx, tx, unidadID); err != nil {
+			return nil, errValidacion{err}
+		}
+		return sesionJSON(ctx, tx, sesionID)
+	})
+}
+
+// =============================
+// DELETE /api/planeaciones/:id/unidades/:numero/sesiones/:n
+// =============================
+func (h *PlaneacionesHandler) DeleteSesion(c *gin.Context) {
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+	n, ok := paramPositivo(c, "n")
+	if !ok {
+		return
+	}
+
+	h.editarUnidades(c, nil, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		unidadID, err := unidadIDPorNumero(ctx, tx, id, numero)
+		if err != nil {
+			return nil, err
+		}
+
+		cmd, err := tx.Exec(ctx, `DELETE FROM sesiones_didacticas WHERE unidad_tematica_id = $1 AND numero_sesion = $2`, unidadID, n)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return nil, errSesionNoEncontrada
+		}
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE sesiones_didacticas SET numero_sesion = numero_sesion - 1 WHERE unidad_tematica_id = $1 AND numero_sesion > $2`,
+			unidadID,
+			n,
+		); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(`{"ok":true}`), nil
+	})
+}
+
+// =============================
+// POST /api/planeaciones/:id/unidades/:numero/sesiones/reorden
+// Body: { "orden": [2, 1, 3] }
+// =============================
+func (h *PlaneacionesHandler) ReordenarSesiones(c *gin.Context) {
+	numero, ok := paramPositivo(c, "numero")
+	if !ok {
+		return
+	}
+
+	var body reordenRequest
+	h.editarUnidades(c, &body, http.StatusOK, func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error) {
+		unidadID, err := unidadIDPorNumero(ctx, tx, id, numero)
+		if err != nil {
+			return nil, err
+		}
+
+		var total int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM sesiones_didacticas WHERE unidad_tematica_id = $1`, unidadID).Scan(&total); err != nil {
+			return nil, err
+		}
+		if !esPermutacion(body.Orden, total) {
+			return nil, errValidacion{fmt.Errorf("orden debe incluir una vez cada número de sesión del 1 al %d", total)}
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			`
+UPDATE sesiones_didacticas sd
+SET numero_sesion = o.pos
+FROM unnest($2::int[]) WITH ORDINALITY AS o(numero, pos)
+WHERE sd.unidad_tematica_id = $1 AND sd.numero_sesion = o.numero AND sd.numero_sesion <> o.pos
+			`,
+			unidadID,
+			body.Orden,
+		); err != nil {
+			return nil, err
+		}
+		return unidadJSON(ctx, tx, unidadID)
+	})
+}
+
+// errValidacion marca errores de datos del cliente (400).
+type errValidacion struct{ err error }
+
+func (e errValidacion) Error() string { return e.err.Error() }
+
+// respondValidacionError responde 400 si err es un error de datos del cliente.
+func respondValidacionError(c *gin.Context, err error) bool {
+	var (
+		ve errValidacion
+		rh errReferenciasHuerfanas
+		ic errInconsistente
+		fp errUnidadesFueraDePeriodo
+	)
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{"error": ve.Error()})
+	case errors.As(err, &rh):
+		c.JSON(http.StatusBadRequest, gin.H{"error": rh.Error(), "referencias": []referenciaHuerfana(rh)})
+	case errors.As(err, &ic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": ic.Error(), "reporte": reporteConsistencia(ic)})
+	case errors.As(err, &fp):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fp.Error(), "periodo": fp.Periodo, "unidades": fp.Unidades})
+	default:
+		return false
+	}
+	return true
+}
+
+// editarUnidades: flujo común (auth, bloqueo de la planeación, decodificación
+// opcional, cambio y commit). body=nil para operaciones sin cuerpo.
+func (h *PlaneacionesHandler) editarUnidades(
+	c *gin.Context,
+	body any,
+	status int,
+	editar func(ctx context.Context, tx pgx.Tx, id int) (json.RawMessage, error),
+) {
+	claims, err := getClaimsFromHeader(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := paramPositivo(c, "id")
+	if !ok {
+		return
+	}
+
+	var advertencias []JSONFieldError
+	if body != nil {
+		if advertencias, ok = decodeJSONBody(c, body); !ok {
+			return
+		}
+	}
+
+	tx, err := h.DB.BeginTx(c, pgx.TxOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo iniciar transacción: " + err.Error()})
+		return
+	}
+	defer tx.Rollback(c)
+
+	if !lockPlaneacionEditable(c, tx, id, claims.UserID) {
+		return
+	}
+
+	out, err := editar(c, tx, id)
+	if err != nil {
+		if respondValidacionError(c, err) {
+			return
+		}
+		respondEdicionError(c, "No se pudo guardar", err)
+		return
+	}
+
+	if _, err := tx.Exec(c, `UPDATE planeaciones SET updated_at = now() WHERE id = $1`, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar planeación: " + err.Error()})
+		return
+	}
+
+	if err := registrarAuditoria(c, tx, eventoDeClaims(claims, accionPlaneacionEditar, "planeacion", int64(id), resumenSolicitud(c, body))); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar auditoría: " + err.Error()})
+		return
+	}
+
+	if err := tx.Commit(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo confirmar transacción: " + err.Error()})
+		return
+	}
+
+	if len(advertencias) > 0 {
+		c.JSON(status, conAdvertencias(out, advertencias))
+		return
+	}
+	c.JSON(status, out)
+}
+
+// conAdvertencias agrega "advertencias" al recurso de la respuesta, como en
+// los demás PATCH. Si el recurso no es un objeto (reorden regresa la lista),
+// va envuelto en {"resultado": ..., "advertencias": ...}.
+func conAdvertencias(out json.RawMessage, advertencias []JSONFieldError) any {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(out, &obj); err != nil || obj == nil {
+		return gin.H{"resultado": out, "advertencias": advertencias}
+	}
+	resp := make(gin.H, len(obj)+1)
+	for k, v := range obj {
+		resp[k] = v
+	}
+	resp["advertencias"] = advertencias
+	return resp
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/vsalazars/planeacion-back/internal/handlers"
 )
@@ -18,7 +19,17 @@ func getBearer(auth string) string {
 	return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+func setClaims(c *gin.Context, claims *handlers.PlaneacionClaims) {
+	c.Set("claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("unidad_id", claims.UnidadID)
+}
+
+// AuthMiddleware acepta un JWT de sesión (header o cookie) o un token
+// personal de API (Authorization: Bearer pat_...; en feeds .ics también ?token=pat_...).
+func AuthMiddleware(db *pgxpool.Pool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1) Preferir Authorization: Bearer
 		tokenStr := getBearer(c.GetHeader("Authorization"))
@@ -30,12 +41,43 @@ func AuthMiddleware() gin.HandlerFunc {
 			}
 		}
 
+		// 2b) Suscripción a calendario (.ics): las apps no envían headers,
+		// se acepta ?token= solo con token personal de API
+		if tokenStr == "" && c.Request.Method == http.MethodGet && strings.HasSuffix(c.Request.URL.Path, ".ics") {
+			if q := strings.TrimSpace(c.Query("token")); strings.HasPrefix(q, handlers.APITokenPrefix) {
+				tokenStr = q
+			}
+		}
+
 		if tokenStr == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "token no proporcionado"})
 			c.Abort()
 			return
 		}
 
+		// 3) Token personal de API
+		if strings.HasPrefix(tokenStr, handlers.APITokenPrefix) {
+			claims, scope, err := handlers.ResolveAPIToken(c.Request.Context(), db, tokenStr)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+
+			if scope != handlers.ScopeReadWrite && !handlers.IsReadOnlyMethod(c.Request.Method) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "el token solo tiene permisos de lectura"})
+				c.Abort()
+				return
+			}
+
+			setClaims(c, claims)
+			c.Set("auth_method", "api_token")
+			c.Set("token_scope", scope)
+
+			c.Next()
+			return
+		}
+
 		secret := os.Getenv("JWT_SECRET")
 		if strings.TrimSpace(secret) == "" {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -69,10 +111,8 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-		c.Set("unidad_id", claims.UnidadID)
+		setClaims(c, claims)
+		c.Set("auth_method", "jwt")
 
 		c.Next()
 	}
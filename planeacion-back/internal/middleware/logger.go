@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Valor de ?token= en la URL (feeds .ics con token personal de API)
+var reTokenQuery = regexp.MustCompile(`([?&]token=)[^&]*`)
+
+// Logger es el log de acceso de gin con el mismo formato, pero sin el valor
+// de ?token=: las apps de calendario consultan el .ics cada pocos minutos y
+// el token personal quedaría vigente en el log.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{Formatter: formatoSinToken})
+}
+
+func formatoSinToken(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		ocultarToken(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+// ocultarToken reemplaza el valor de ?token= (o &token=) por "REDACTED".
+func ocultarToken(path string) string {
+	return reTokenQuery.ReplaceAllString(path, "${1}REDACTED")
+}
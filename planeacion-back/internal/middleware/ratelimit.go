@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cubeta de fichas de una IP
+type cubeta struct {
+	fichas float64
+	ultimo time.Time
+}
+
+type limitador struct {
+	mu       sync.Mutex
+	cubetas  map[string]*cubeta
+	tasa     float64 // fichas por segundo
+	rafaga   float64
+	limpieza time.Time
+}
+
+func envFloat(nombre string, def float64) float64 {
+	if f, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(nombre)), 64); err == nil && f >= 0 {
+		return f
+	}
+	return def
+}
+
+// permitir descuenta una ficha; si no hay, regresa cuánto esperar.
+func (l *limitador) permitir(ip string, ahora time.Time) (bool, float64, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Cubetas llenas e inactivas ya no aportan nada
+	if ahora.Sub(l.limpieza) > time.Minute {
+		for k, b := range l.cubetas {
+			if ahora.Sub(b.ultimo).Seconds()*l.tasa >= l.rafaga {
+				delete(l.cubetas, k)
+			}
+		}
+		l.limpieza = ahora
+	}
+
+	b := l.cubetas[ip]
+	if b == nil {
+		b = &cubeta{fichas: l.rafaga, ultimo: ahora}
+		l.cubetas[ip] = b
+	}
+	b.fichas = math.Min(l.rafaga, b.fichas+ahora.Sub(b.ultimo).Seconds()*l.tasa)
+	b.ultimo = ahora
+
+	if b.fichas < 1 {
+		espera := time.Duration((1 - b.fichas) / l.tasa * float64(time.Second))
+		return false, b.fichas, espera
+	}
+	b.fichas--
+	return true, b.fichas, 0
+}
+
+// RateLimitPublico limita solicitudes por IP con una cubeta de fichas.
+// PUBLIC_RATE_LIMIT_RPS: fichas por segundo (default 5; 0 = sin límite).
+// PUBLIC_RATE_LIMIT_BURST: tamaño de la cubeta (default 20).
+func RateLimitPublico() gin.HandlerFunc {
+	tasa := envFloat("PUBLIC_RATE_LIMIT_RPS", 5)
+	if tasa <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	rafaga := math.Max(1, envFloat("PUBLIC_RATE_LIMIT_BURST", 20))
+
+	l := &limitador{
+		cubetas:  map[string]*cubeta{},
+		tasa:     tasa,
+		rafaga:   rafaga,
+		limpieza: time.Now(),
+	}
+	limite := strconv.Itoa(int(rafaga))
+
+	return func(c *gin.Context) {
+		ok, restantes, espera := l.permitir(c.ClientIP(), time.Now())
+		c.Header("X-RateLimit-Limit", limite)
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(restantes)))
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(espera.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "demasiadas solicitudes, intenta de nuevo en unos segundos",
+			})
+			return
+		}
+		c.Next()
+	}
+}
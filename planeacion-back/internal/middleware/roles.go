@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole restringe un grupo de rutas a los roles indicados.
+// Debe ir después de AuthMiddleware (usa el "role" que éste deja en el contexto).
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "no tienes permisos para esta operación"})
+		c.Abort()
+	}
+}
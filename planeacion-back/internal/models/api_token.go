@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// APIToken representa la tabla public.api_tokens (token personal de acceso).
+// El hash nunca se expone en JSON.
+type APIToken struct {
+	ID         int64      `db:"id" json:"id"`
+	UsuarioID  int        `db:"usuario_id" json:"usuario_id"`
+	Nombre     string     `db:"nombre" json:"nombre"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Prefijo    string     `db:"prefijo" json:"prefijo"`
+	Scope      string     `db:"scope" json:"scope"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
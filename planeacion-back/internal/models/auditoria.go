@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RegistroAuditoria representa la tabla public.audit_log.
+type RegistroAuditoria struct {
+	ID         int64           `db:"id" json:"id"`
+	ActorID    *int            `db:"actor_id" json:"actor_id"`
+	ActorEmail *string         `db:"actor_email" json:"actor_email"`
+	Accion     string          `db:"accion" json:"accion"`
+	Entidad    string          `db:"entidad" json:"entidad"`
+	EntidadID  *int64          `db:"entidad_id" json:"entidad_id"`
+	IP         *string         `db:"ip" json:"ip"`
+	UserAgent  *string         `db:"user_agent" json:"user_agent"`
+	Cambios    json.RawMessage `db:"cambios" json:"cambios"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PeriodoEscolar representa la tabla public.periodos_escolares.
+// UnidadAcademicaID nil = periodo general. Fechas en formato YYYY-MM-DD.
+type PeriodoEscolar struct {
+	ID                int64     `db:"id" json:"id"`
+	UnidadAcademicaID *int      `db:"unidad_academica_id" json:"unidad_academica_id"`
+	Clave             string    `db:"clave" json:"clave"`
+	Nombre            *string   `db:"nombre" json:"nombre"`
+	FechaInicio       string    `db:"fecha_inicio" json:"fecha_inicio"`
+	FechaFin          string    `db:"fecha_fin" json:"fecha_fin"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// DiaInhabil representa la tabla public.dias_inhabiles (un día o un rango).
+type DiaInhabil struct {
+	ID          int64   `db:"id" json:"id"`
+	PeriodoID   int64   `db:"periodo_id" json:"periodo_id"`
+	FechaDel    string  `db:"fecha_del" json:"fecha_del"`
+	FechaAl     string  `db:"fecha_al" json:"fecha_al"`
+	Descripcion *string `db:"descripcion" json:"descripcion"`
+}
+
+// VentanaEvaluacion representa la tabla public.ventanas_evaluacion.
+type VentanaEvaluacion struct {
+	ID        int64  `db:"id" json:"id"`
+	PeriodoID int64  `db:"periodo_id" json:"periodo_id"`
+	Nombre    string `db:"nombre" json:"nombre"`
+	FechaDel  string `db:"fecha_del" json:"fecha_del"`
+	FechaAl   string `db:"fecha_al" json:"fecha_al"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ProgramaAcademico representa la tabla public.programas_academicos.
+type ProgramaAcademico struct {
+	ID                int64     `db:"id" json:"id"`
+	UnidadAcademicaID int       `db:"unidad_academica_id" json:"unidad_academica_id"`
+	Clave             *string   `db:"clave" json:"clave"`
+	Nombre            string    `db:"nombre" json:"nombre"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Academia representa la tabla public.academias (pertenece a un programa).
+type Academia struct {
+	ID         int64     `db:"id" json:"id"`
+	ProgramaID int64     `db:"programa_id" json:"programa_id"`
+	Nombre     string    `db:"nombre" json:"nombre"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// UnidadAprendizaje representa la tabla public.unidades_aprendizaje (pertenece a una academia).
+type UnidadAprendizaje struct {
+	ID            int64     `db:"id" json:"id"`
+	AcademiaID    int64     `db:"academia_id" json:"academia_id"`
+	Clave         *string   `db:"clave" json:"clave"`
+	Nombre        string    `db:"nombre" json:"nombre"`
+	Semestre      *int      `db:"semestre" json:"semestre"`
+	CreditosTepic *float64  `db:"creditos_tepic" json:"creditos_tepic"`
+	CreditosSatca *float64  `db:"creditos_satca" json:"creditos_satca"`
+	HorasTeoria   *float64  `db:"horas_teoria" json:"horas_teoria"`
+	HorasPractica *float64  `db:"horas_practica" json:"horas_practica"`
+	HorasTotal    *float64  `db:"horas_total" json:"horas_total"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
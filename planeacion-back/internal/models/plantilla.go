@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PlantillaPrograma representa la tabla public.plantillas_programa
+// (programa sintético oficial de una unidad de aprendizaje).
+// DatosGenerales, Unidades y Referencias usan el formato de PUT /api/planeaciones/:id.
+type PlantillaPrograma struct {
+	ID                  int64           `db:"id" json:"id"`
+	UnidadAcademicaID   int             `db:"unidad_academica_id" json:"unidad_academica_id"`
+	UnidadAprendizajeID *int64          `db:"unidad_aprendizaje_id" json:"unidad_aprendizaje_id"`
+	Nombre              string          `db:"nombre" json:"nombre"`
+	Version             *string         `db:"version" json:"version"`
+	Vigente             bool            `db:"vigente" json:"vigente"`
+	DatosGenerales      json.RawMessage `db:"datos_generales" json:"datos_generales"`
+	Unidades            json.RawMessage `db:"unidades" json:"unidades"`
+	Referencias         json.RawMessage `db:"referencias" json:"referencias"`
+	CreatedBy           *int            `db:"created_by" json:"created_by"`
+	CreatedAt           time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt           time.Time       `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SolicitudCambioUnidad representa la tabla public.solicitudes_cambio_unidad.
+type SolicitudCambioUnidad struct {
+	ID                   int64      `db:"id" json:"id"`
+	UsuarioID            int        `db:"usuario_id" json:"usuario_id"`
+	UnidadOrigenID       int        `db:"unidad_origen_id" json:"unidad_origen_id"`
+	UnidadDestinoID      int        `db:"unidad_destino_id" json:"unidad_destino_id"`
+	Motivo               *string    `db:"motivo" json:"motivo"`
+	Status               string     `db:"status" json:"status"`
+	ResueltaPor          *int       `db:"resuelta_por" json:"resuelta_por"`
+	ResueltaAt           *time.Time `db:"resuelta_at" json:"resuelta_at"`
+	ComentarioResolucion *string    `db:"comentario_resolucion" json:"comentario_resolucion"`
+	CreatedAt            time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time  `db:"updated_at" json:"updated_at"`
+}
@@ -1,8 +1,10 @@
 package models
 
 // UnidadAcademica representa la tabla public.unidades_academicas.
+// Activa = false es la baja lógica (no se borran unidades con usuarios).
 type UnidadAcademica struct {
 	ID          int    `json:"id"`
 	Nombre      string `json:"nombre"`
 	Abreviatura string `json:"abreviatura"`
+	Activa      bool   `json:"activa"`
 }
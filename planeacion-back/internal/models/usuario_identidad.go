@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UsuarioIdentidad representa la tabla public.usuario_identidades
+// (identidad externa, p. ej. Google, vinculada a un usuario).
+type UsuarioIdentidad struct {
+	ID          int64      `db:"id" json:"id"`
+	UsuarioID   int        `db:"usuario_id" json:"usuario_id"`
+	Provider    string     `db:"provider" json:"provider"`
+	Subject     string     `db:"subject" json:"-"`
+	Email       *string    `db:"email" json:"email"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
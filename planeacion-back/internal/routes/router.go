@@ -3,6 +3,8 @@ package routes
 import (
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,10 +16,14 @@ import (
 )
 
 func SetupRouter(db *pgxpool.Pool) *gin.Engine {
-	r := gin.Default()
-
-	// Evitar warning de proxies
-	if err := r.SetTrustedProxies(nil); err != nil {
+	// Como gin.Default(), pero el log de acceso oculta ?token= de los feeds .ics
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
+
+	// Proxies de confianza (TRUSTED_PROXIES="10.0.0.0/8,127.0.0.1"). Detrás de
+	// un proxy, sin esto ClientIP() es la IP del proxy y todo el tráfico comparte
+	// la misma cubeta del límite de peticiones. Vacío = no confiar en ninguno.
+	if err := r.SetTrustedProxies(proxiesDeConfianza()); err != nil {
 		log.Fatal("❌ Error configurando proxies:", err)
 	}
 
@@ -30,7 +36,7 @@ func SetupRouter(db *pgxpool.Pool) *gin.Engine {
 			"http://127.0.0.1:3000",
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Strict-JSON"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -66,25 +72,78 @@ func SetupRouter(db *pgxpool.Pool) *gin.Engine {
 	unidadesHandler := &handlers.UnidadesHandler{DB: db}
 	handlers.RegisterUnidadesRoutes(api, unidadesHandler)
 
+	// ---- CALENDARIO ACADÉMICO (consulta pública) ----
+	calendarioHandler := &handlers.CalendarioHandler{DB: db}
+	handlers.RegisterCalendarioRoutes(api, calendarioHandler)
+
+	// ---- API PÚBLICA /api/public/* (sin sesión) ----
+	// Rate limit por IP + cache en proceso con ETag/Last-Modified
+	publico := api.Group("/")
+	publico.Use(middleware.RateLimitPublico(), handlers.CachePublica())
+
 	// ✅ ---- PLANEACIONES PÚBLICAS (sin sesión) ----
 	publicPlaneacionesHandler := &handlers.PublicPlaneacionesHandler{DB: db}
-	handlers.RegisterPublicPlaneacionesRoutes(api, publicPlaneacionesHandler)
+	handlers.RegisterPublicPlaneacionesRoutes(publico, publicPlaneacionesHandler)
 
-		// ✅ ---- STATS PÚBLICAS (sin sesión) ----
+	// ✅ ---- STATS PÚBLICAS (sin sesión) ----
 	publicStatsHandler := &handlers.PublicStatsHandler{DB: db}
-	handlers.RegisterPublicStatsRoutes(api, publicStatsHandler)
-
-
+	handlers.RegisterPublicStatsRoutes(publico, publicStatsHandler)
 
 	// ==========================
 	// Grupo PROTEGIDO
 	// ==========================
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(db))
+
+	// ---- USUARIO ACTUAL ----
+	handlers.RegisterMeRoutes(protected, authHandler)
 
 	planeacionesHandler := &handlers.PlaneacionesHandler{DB: db}
 	handlers.RegisterPlaneacionesRoutes(protected, planeacionesHandler)
 
-	
+	// ---- CATÁLOGO DE REFERENCIAS DEL DOCENTE ----
+	referenciasHandler := &handlers.ReferenciasHandler{DB: db}
+	handlers.RegisterReferenciasRoutes(protected, referenciasHandler)
+
+	// ---- TOKENS PERSONALES DE API ----
+	apiTokensHandler := &handlers.APITokensHandler{DB: db}
+	handlers.RegisterAPITokensRoutes(protected, apiTokensHandler)
+
+	// ---- PERFIL DEL USUARIO ACTUAL ----
+	perfilHandler := &handlers.PerfilHandler{DB: db}
+	handlers.RegisterPerfilRoutes(protected, perfilHandler)
+	handlers.RegisterIdentidadesRoutes(protected, perfilHandler)
+
+	// ---- PLANTILLAS DE PROGRAMA SINTÉTICO ----
+	plantillasHandler := &handlers.PlantillasHandler{DB: db}
+	handlers.RegisterPlantillasRoutes(protected, plantillasHandler)
+
+	// ==========================
+	// Grupo ADMIN (rol admin)
+	// ==========================
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequireRole("admin"))
+
+	handlers.RegisterPerfilAdminRoutes(admin, perfilHandler)
+	handlers.RegisterCalendarioAdminRoutes(admin, calendarioHandler)
+	handlers.RegisterUnidadesAdminRoutes(admin, unidadesHandler)
+	handlers.RegisterPlantillasAdminRoutes(admin, plantillasHandler)
+	handlers.RegisterPublicStatsAdminRoutes(admin, publicStatsHandler)
+
+	// ---- BITÁCORA DE AUDITORÍA ----
+	auditoriaHandler := &handlers.AuditoriaHandler{DB: db}
+	handlers.RegisterAuditoriaAdminRoutes(admin, auditoriaHandler)
+
 	return r
 }
+
+// proxiesDeConfianza lee TRUSTED_PROXIES (IPs o CIDRs separados por comas).
+func proxiesDeConfianza() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
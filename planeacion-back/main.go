@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 
+	"github.com/vsalazars/planeacion-back/internal/handlers"
 	"github.com/vsalazars/planeacion-back/internal/routes"
 )
 
@@ -68,18 +73,47 @@ func connectDB(ctx context.Context) *pgxpool.Pool {
 func main() {
 	// 1. Cargar variables
 	loadEnv()
-	ctx := context.Background()
+
+	// SIGINT/SIGTERM cancelan ctx: se deja de aceptar conexiones y se
+	// espera a las peticiones y a la purga en curso antes de cerrar la BD
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// 2. Conectar BD
 	db = connectDB(ctx)
 	defer db.Close()
 
+	// Purga periódica de la papelera de planeaciones
+	purga := handlers.IniciarPurgaPapelera(ctx, db)
+
 	// 3. Levantar router
 	port := getEnv("PORT", "8080")
-	r := routes.SetupRouter(db)
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: routes.SetupRouter(db),
+	}
 
-	log.Printf("🚀 Servidor escuchando en :%s\n", port)
-	if err := r.Run(":" + port); err != nil {
+	errServidor := make(chan error, 1)
+	go func() {
+		log.Printf("🚀 Servidor escuchando en :%s\n", port)
+		errServidor <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errServidor:
+		stop()
+		<-purga
+		db.Close()
 		log.Fatal("❌ Error al iniciar servidor:", err)
+	case <-ctx.Done():
+		log.Println("🛑 Deteniendo servidor...")
+		ctxApagado, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctxApagado); err != nil {
+			log.Println("⚠️ Apagado forzado del servidor:", err)
+		}
 	}
+
+	<-purga
+	log.Println("👋 Servidor detenido")
 }
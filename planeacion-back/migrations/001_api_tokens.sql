@@ -0,0 +1,24 @@
+-- =============================
+-- 001: Tokens personales de API
+-- Tokens de larga duración para scripts e integraciones.
+-- Solo se guarda el hash SHA-256; el valor en claro se muestra una vez al crearlo.
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.api_tokens (
+    id bigserial PRIMARY KEY,
+    usuario_id integer NOT NULL,
+    nombre character varying(100) NOT NULL,
+    token_hash character(64) NOT NULL,
+    prefijo character varying(16) NOT NULL,
+    scope character varying(20) DEFAULT 'read'::character varying NOT NULL,
+    expires_at timestamp with time zone,
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT api_tokens_scope_check CHECK (scope IN ('read', 'read_write')),
+    CONSTRAINT api_tokens_token_hash_key UNIQUE (token_hash),
+    CONSTRAINT api_tokens_usuario_id_fkey FOREIGN KEY (usuario_id)
+        REFERENCES public.usuarios(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_usuario ON public.api_tokens USING btree (usuario_id);
@@ -0,0 +1,38 @@
+-- =============================
+-- 002: Solicitudes de cambio de unidad académica
+-- El usuario solicita el cambio; un admin la aprueba o rechaza.
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.solicitudes_cambio_unidad (
+    id bigserial PRIMARY KEY,
+    usuario_id integer NOT NULL,
+    unidad_origen_id integer NOT NULL,
+    unidad_destino_id integer NOT NULL,
+    motivo text,
+    status character varying(20) DEFAULT 'pendiente'::character varying NOT NULL,
+    resuelta_por integer,
+    resuelta_at timestamp with time zone,
+    comentario_resolucion text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT solicitudes_cambio_unidad_status_check CHECK (status IN ('pendiente', 'aprobada', 'rechazada', 'cancelada')),
+    CONSTRAINT solicitudes_cambio_unidad_usuario_id_fkey FOREIGN KEY (usuario_id)
+        REFERENCES public.usuarios(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT solicitudes_cambio_unidad_origen_fkey FOREIGN KEY (unidad_origen_id)
+        REFERENCES public.unidades_academicas(id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    CONSTRAINT solicitudes_cambio_unidad_destino_fkey FOREIGN KEY (unidad_destino_id)
+        REFERENCES public.unidades_academicas(id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    CONSTRAINT solicitudes_cambio_unidad_resuelta_por_fkey FOREIGN KEY (resuelta_por)
+        REFERENCES public.usuarios(id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+-- Solo una solicitud pendiente por usuario
+CREATE UNIQUE INDEX IF NOT EXISTS idx_solicitudes_cambio_unidad_pendiente
+    ON public.solicitudes_cambio_unidad USING btree (usuario_id) WHERE (status = 'pendiente');
+
+CREATE INDEX IF NOT EXISTS idx_solicitudes_cambio_unidad_status
+    ON public.solicitudes_cambio_unidad USING btree (status);
+
+DROP TRIGGER IF EXISTS trg_scu_updated_at ON public.solicitudes_cambio_unidad;
+CREATE TRIGGER trg_scu_updated_at BEFORE UPDATE ON public.solicitudes_cambio_unidad
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
@@ -0,0 +1,26 @@
+-- =============================
+-- 003: Identidades externas vinculadas a un usuario
+-- Una identidad se identifica por proveedor + subject (claim "sub"),
+-- no por email: si el email del proveedor cambia, la cuenta se conserva.
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.usuario_identidades (
+    id bigserial PRIMARY KEY,
+    usuario_id integer NOT NULL,
+    provider character varying(30) NOT NULL,
+    subject character varying(255) NOT NULL,
+    email character varying(255),
+    last_login_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT usuario_identidades_provider_subject_key UNIQUE (provider, subject),
+    CONSTRAINT usuario_identidades_usuario_provider_key UNIQUE (usuario_id, provider),
+    CONSTRAINT usuario_identidades_usuario_id_fkey FOREIGN KEY (usuario_id)
+        REFERENCES public.usuarios(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_usuario_identidades_usuario ON public.usuario_identidades USING btree (usuario_id);
+
+DROP TRIGGER IF EXISTS trg_ui_updated_at ON public.usuario_identidades;
+CREATE TRIGGER trg_ui_updated_at BEFORE UPDATE ON public.usuario_identidades
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
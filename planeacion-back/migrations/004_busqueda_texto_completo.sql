@@ -0,0 +1,73 @@
+-- =============================
+-- 004: Búsqueda de texto completo (español) en planeaciones publicadas
+-- search_text: texto plano para ts_headline (fragmentos resaltados)
+-- search_tsv : tsvector ponderado, se recalcula al publicar
+-- =============================
+
+CREATE EXTENSION IF NOT EXISTS unaccent WITH SCHEMA public;
+
+-- Configuración "español sin acentos": programacion == programación
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_ts_config c
+    JOIN pg_namespace n ON n.oid = c.cfgnamespace
+    WHERE c.cfgname = 'es_unaccent' AND n.nspname = 'public'
+  ) THEN
+    CREATE TEXT SEARCH CONFIGURATION public.es_unaccent (COPY = pg_catalog.spanish);
+    ALTER TEXT SEARCH CONFIGURATION public.es_unaccent
+      ALTER MAPPING FOR hword, hword_part, word WITH public.unaccent, pg_catalog.spanish_stem;
+  END IF;
+END
+$$;
+
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS search_text text;
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS search_tsv tsvector;
+
+CREATE INDEX IF NOT EXISTS idx_planeaciones_search_tsv ON public.planeaciones USING gin (search_tsv);
+
+-- Pesos:
+--   A: nombre, asignatura, nombres de unidades temáticas
+--   B: unidad de competencia, aprendizajes esperados
+--   C: temas y subtemas de las sesiones
+--   D: referencias
+CREATE OR REPLACE FUNCTION public.planeacion_refresh_search(pid bigint) RETURNS void
+    LANGUAGE sql
+    AS $$
+WITH doc AS (
+  SELECT
+    concat_ws(' ',
+      p.nombre_planeacion,
+      p.asignatura,
+      (SELECT string_agg(ut.nombre_unidad_tematica, ' ' ORDER BY ut.numero)
+       FROM public.unidades_tematicas ut WHERE ut.planeacion_id = p.id)
+    ) AS a,
+    (SELECT string_agg(
+              concat_ws(' ', ut.unidad_competencia, array_to_string(ut.aprendizajes_esperados, ' ')),
+              ' ' ORDER BY ut.numero)
+     FROM public.unidades_tematicas ut WHERE ut.planeacion_id = p.id) AS b,
+    (SELECT string_agg(sd.temas_subtemas, ' ' ORDER BY ut.numero, sd.numero_sesion)
+     FROM public.sesiones_didacticas sd
+     JOIN public.unidades_tematicas ut ON ut.id = sd.unidad_tematica_id
+     WHERE ut.planeacion_id = p.id) AS c,
+    (SELECT string_agg(r.cita_apa, ' ' ORDER BY r.id)
+     FROM public.planeacion_referencias r WHERE r.planeacion_id = p.id) AS d
+  FROM public.planeaciones p
+  WHERE p.id = pid
+)
+UPDATE public.planeaciones p
+SET
+  search_text = concat_ws(E'\n', doc.a, doc.b, doc.c, doc.d),
+  search_tsv  =
+    setweight(to_tsvector('public.es_unaccent', coalesce(doc.a, '')), 'A') ||
+    setweight(to_tsvector('public.es_unaccent', coalesce(doc.b, '')), 'B') ||
+    setweight(to_tsvector('public.es_unaccent', coalesce(doc.c, '')), 'C') ||
+    setweight(to_tsvector('public.es_unaccent', coalesce(doc.d, '')), 'D')
+FROM doc
+WHERE p.id = pid;
+$$;
+
+-- Backfill de lo ya publicado sin tocar updated_at
+ALTER TABLE public.planeaciones DISABLE TRIGGER trg_planeaciones_updated_at;
+SELECT public.planeacion_refresh_search(id) FROM public.planeaciones WHERE status = 'finalizada';
+ALTER TABLE public.planeaciones ENABLE TRIGGER trg_planeaciones_updated_at;
@@ -0,0 +1,7 @@
+-- =============================
+-- 005: Tipo de unidad de aprendizaje en datos generales
+-- { teorica, practica, teorica_practica, clinica, otro, obligatoria, optativa, topicos_selectos }
+-- (fecha_elaboracion, semanas_por_semestre y docente_autor ya existían)
+-- =============================
+
+ALTER TABLE public.planeacion_datos_generales ADD COLUMN IF NOT EXISTS tipo_unidad jsonb;
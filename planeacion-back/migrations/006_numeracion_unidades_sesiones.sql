@@ -0,0 +1,52 @@
+-- =============================
+-- 006: Numeración única de unidades temáticas y sesiones didácticas
+-- Necesaria para editarlas por número (/unidades/:numero/sesiones/:n).
+-- DEFERRABLE: reordenar/desplazar en una transacción sin choques intermedios.
+-- =============================
+
+-- Corrige duplicados existentes (solo en las unidades/planeaciones afectadas)
+WITH dup AS (
+  SELECT planeacion_id
+  FROM public.unidades_tematicas
+  GROUP BY planeacion_id, numero
+  HAVING COUNT(*) > 1
+), o AS (
+  SELECT id, row_number() OVER (PARTITION BY planeacion_id ORDER BY numero, id) AS rn
+  FROM public.unidades_tematicas
+  WHERE planeacion_id IN (SELECT planeacion_id FROM dup)
+)
+UPDATE public.unidades_tematicas ut
+SET numero = o.rn
+FROM o
+WHERE o.id = ut.id AND ut.numero <> o.rn;
+
+WITH dup AS (
+  SELECT unidad_tematica_id
+  FROM public.sesiones_didacticas
+  GROUP BY unidad_tematica_id, numero_sesion
+  HAVING COUNT(*) > 1
+), o AS (
+  SELECT id, row_number() OVER (PARTITION BY unidad_tematica_id ORDER BY numero_sesion, id) AS rn
+  FROM public.sesiones_didacticas
+  WHERE unidad_tematica_id IN (SELECT unidad_tematica_id FROM dup)
+)
+UPDATE public.sesiones_didacticas sd
+SET numero_sesion = o.rn
+FROM o
+WHERE o.id = sd.id AND sd.numero_sesion <> o.rn;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'unidades_tematicas_planeacion_numero_key') THEN
+    ALTER TABLE public.unidades_tematicas
+      ADD CONSTRAINT unidades_tematicas_planeacion_numero_key
+      UNIQUE (planeacion_id, numero) DEFERRABLE INITIALLY DEFERRED;
+  END IF;
+
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'sesiones_didacticas_unidad_numero_key') THEN
+    ALTER TABLE public.sesiones_didacticas
+      ADD CONSTRAINT sesiones_didacticas_unidad_numero_key
+      UNIQUE (unidad_tematica_id, numero_sesion) DEFERRABLE INITIALLY DEFERRED;
+  END IF;
+END
+$$;
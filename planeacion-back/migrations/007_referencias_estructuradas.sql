@@ -0,0 +1,30 @@
+-- =============================
+-- 007: Datos bibliográficos estructurados en planeacion_referencias
+-- cita_apa se genera desde estos campos cuando hay título;
+-- las referencias capturadas solo como texto libre se conservan tal cual.
+-- tipo (Básica/Complementaria) no cambia; tipo_fuente es la clase de obra.
+-- =============================
+
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS tipo_fuente character varying(20);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS autores jsonb;   -- [{ "apellidos", "nombres" }]
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS anio integer;
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS titulo text;
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS fuente text;     -- revista / libro / sitio
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS editorial text;
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS edicion character varying(50);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS volumen character varying(30);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS numero character varying(30);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS paginas character varying(50);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS doi character varying(255);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS isbn character varying(20);
+ALTER TABLE public.planeacion_referencias ADD COLUMN IF NOT EXISTS url text;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'planeacion_referencias_tipo_fuente_check') THEN
+    ALTER TABLE public.planeacion_referencias
+      ADD CONSTRAINT planeacion_referencias_tipo_fuente_check
+      CHECK (tipo_fuente IS NULL OR tipo_fuente IN ('libro', 'articulo', 'capitulo', 'web', 'otro'));
+  END IF;
+END
+$$;
@@ -0,0 +1,58 @@
+-- =============================
+-- 008: Calendario académico
+-- Periodos escolares (generales o por unidad académica), días inhábiles y
+-- ventanas de evaluación. La planeación usa el periodo cuya clave coincide con
+-- planeaciones.periodo (primero el de su unidad académica, luego el general).
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.periodos_escolares (
+    id bigserial PRIMARY KEY,
+    unidad_academica_id integer,
+    clave character varying(50) NOT NULL,
+    nombre character varying(255),
+    fecha_inicio date NOT NULL,
+    fecha_fin date NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT periodos_escolares_fechas_check CHECK (fecha_fin >= fecha_inicio),
+    CONSTRAINT periodos_escolares_unidad_academica_id_fkey FOREIGN KEY (unidad_academica_id)
+        REFERENCES public.unidades_academicas(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Una clave por unidad académica (NULL = periodo general)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_periodos_escolares_clave
+    ON public.periodos_escolares USING btree (COALESCE(unidad_academica_id, 0), lower(btrim(clave)));
+
+DROP TRIGGER IF EXISTS trg_periodos_escolares_updated_at ON public.periodos_escolares;
+CREATE TRIGGER trg_periodos_escolares_updated_at BEFORE UPDATE ON public.periodos_escolares
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
+
+CREATE TABLE IF NOT EXISTS public.dias_inhabiles (
+    id bigserial PRIMARY KEY,
+    periodo_id bigint NOT NULL,
+    fecha_del date NOT NULL,
+    fecha_al date NOT NULL,
+    descripcion text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT dias_inhabiles_fechas_check CHECK (fecha_al >= fecha_del),
+    CONSTRAINT dias_inhabiles_periodo_id_fkey FOREIGN KEY (periodo_id)
+        REFERENCES public.periodos_escolares(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dias_inhabiles_periodo
+    ON public.dias_inhabiles USING btree (periodo_id, fecha_del);
+
+CREATE TABLE IF NOT EXISTS public.ventanas_evaluacion (
+    id bigserial PRIMARY KEY,
+    periodo_id bigint NOT NULL,
+    nombre character varying(100) NOT NULL,
+    fecha_del date NOT NULL,
+    fecha_al date NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT ventanas_evaluacion_fechas_check CHECK (fecha_al >= fecha_del),
+    CONSTRAINT ventanas_evaluacion_periodo_id_fkey FOREIGN KEY (periodo_id)
+        REFERENCES public.periodos_escolares(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ventanas_evaluacion_periodo
+    ON public.ventanas_evaluacion USING btree (periodo_id, fecha_del);
@@ -0,0 +1,38 @@
+-- =============================
+-- 009: Programación de sesiones
+-- Horario semanal del grupo por planeación y fecha/hora/espacio de cada sesión
+-- (se asignan con POST /api/planeaciones/:id/programar o a mano).
+-- dia_semana: 1 = lunes ... 7 = domingo (ISO).
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.planeacion_horario (
+    id bigserial PRIMARY KEY,
+    planeacion_id bigint NOT NULL,
+    dia_semana smallint NOT NULL,
+    hora_inicio time NOT NULL,
+    hora_fin time NOT NULL,
+    espacio character varying(20) DEFAULT 'aula'::character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT planeacion_horario_dia_check CHECK (dia_semana BETWEEN 1 AND 7),
+    CONSTRAINT planeacion_horario_horas_check CHECK (hora_fin > hora_inicio),
+    CONSTRAINT planeacion_horario_espacio_check CHECK (espacio IN ('aula', 'laboratorio', 'taller', 'clinica', 'otro')),
+    CONSTRAINT planeacion_horario_planeacion_id_fkey FOREIGN KEY (planeacion_id)
+        REFERENCES public.planeaciones(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_planeacion_horario_planeacion
+    ON public.planeacion_horario USING btree (planeacion_id, dia_semana, hora_inicio);
+
+ALTER TABLE public.sesiones_didacticas ADD COLUMN IF NOT EXISTS fecha date;
+ALTER TABLE public.sesiones_didacticas ADD COLUMN IF NOT EXISTS hora_inicio time;
+ALTER TABLE public.sesiones_didacticas ADD COLUMN IF NOT EXISTS hora_fin time;
+ALTER TABLE public.sesiones_didacticas ADD COLUMN IF NOT EXISTS espacio character varying(20);
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'sesiones_didacticas_espacio_check') THEN
+    ALTER TABLE public.sesiones_didacticas
+      ADD CONSTRAINT sesiones_didacticas_espacio_check
+      CHECK (espacio IS NULL OR espacio IN ('aula', 'laboratorio', 'taller', 'clinica', 'otro'));
+  END IF;
+END $$;
@@ -0,0 +1,271 @@
+-- =============================
+-- 010: Catálogos académicos
+-- unidades_academicas → programas_academicos → academias → unidades_aprendizaje
+-- (semestre, créditos y horas). planeaciones referencia el catálogo; el texto
+-- libre (programa_academico, academia, asignatura) se conserva como copia del
+-- nombre para búsqueda y documentos.
+-- El texto existente se migra por similitud de trigramas (pg_trgm + unaccent).
+-- =============================
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+CREATE EXTENSION IF NOT EXISTS unaccent WITH SCHEMA public;
+
+-- Forma normalizada para comparar nombres (sin acentos, minúsculas, espacios simples)
+CREATE OR REPLACE FUNCTION public.normalizar_catalogo(t text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$
+  SELECT lower(regexp_replace(btrim(public.unaccent('public.unaccent'::regdictionary, COALESCE(t, ''))), '\s+', ' ', 'g'))
+$$;
+
+CREATE TABLE IF NOT EXISTS public.programas_academicos (
+    id bigserial PRIMARY KEY,
+    unidad_academica_id integer NOT NULL,
+    clave character varying(50),
+    nombre character varying(255) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT programas_academicos_unidad_academica_id_fkey FOREIGN KEY (unidad_academica_id)
+        REFERENCES public.unidades_academicas(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_programas_academicos_nombre
+    ON public.programas_academicos USING btree (unidad_academica_id, public.normalizar_catalogo(nombre));
+CREATE INDEX IF NOT EXISTS idx_programas_academicos_nombre_trgm
+    ON public.programas_academicos USING gin (public.normalizar_catalogo(nombre) public.gin_trgm_ops);
+
+DROP TRIGGER IF EXISTS trg_programas_academicos_updated_at ON public.programas_academicos;
+CREATE TRIGGER trg_programas_academicos_updated_at BEFORE UPDATE ON public.programas_academicos
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
+
+CREATE TABLE IF NOT EXISTS public.academias (
+    id bigserial PRIMARY KEY,
+    programa_id bigint NOT NULL,
+    nombre character varying(255) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT academias_programa_id_fkey FOREIGN KEY (programa_id)
+        REFERENCES public.programas_academicos(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_academias_nombre
+    ON public.academias USING btree (programa_id, public.normalizar_catalogo(nombre));
+CREATE INDEX IF NOT EXISTS idx_academias_nombre_trgm
+    ON public.academias USING gin (public.normalizar_catalogo(nombre) public.gin_trgm_ops);
+
+DROP TRIGGER IF EXISTS trg_academias_updated_at ON public.academias;
+CREATE TRIGGER trg_academias_updated_at BEFORE UPDATE ON public.academias
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
+
+CREATE TABLE IF NOT EXISTS public.unidades_aprendizaje (
+    id bigserial PRIMARY KEY,
+    academia_id bigint NOT NULL,
+    clave character varying(50),
+    nombre character varying(255) NOT NULL,
+    semestre integer,
+    creditos_tepic numeric(5,2),
+    creditos_satca numeric(5,2),
+    horas_teoria numeric(5,2),
+    horas_practica numeric(5,2),
+    horas_total numeric(5,2),
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT unidades_aprendizaje_semestre_check CHECK (semestre IS NULL OR semestre BETWEEN 1 AND 20),
+    CONSTRAINT unidades_aprendizaje_academia_id_fkey FOREIGN KEY (academia_id)
+        REFERENCES public.academias(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_unidades_aprendizaje_nombre
+    ON public.unidades_aprendizaje USING btree (academia_id, public.normalizar_catalogo(nombre));
+CREATE INDEX IF NOT EXISTS idx_unidades_aprendizaje_nombre_trgm
+    ON public.unidades_aprendizaje USING gin (public.normalizar_catalogo(nombre) public.gin_trgm_ops);
+
+DROP TRIGGER IF EXISTS trg_unidades_aprendizaje_updated_at ON public.unidades_aprendizaje;
+CREATE TRIGGER trg_unidades_aprendizaje_updated_at BEFORE UPDATE ON public.unidades_aprendizaje
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
+
+-- Referencias desde planeaciones (borrar del catálogo no borra planeaciones)
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS programa_id bigint;
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS academia_id bigint;
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS unidad_aprendizaje_id bigint;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'planeaciones_programa_id_fkey') THEN
+    ALTER TABLE public.planeaciones ADD CONSTRAINT planeaciones_programa_id_fkey
+      FOREIGN KEY (programa_id) REFERENCES public.programas_academicos(id) ON DELETE SET NULL;
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'planeaciones_academia_id_fkey') THEN
+    ALTER TABLE public.planeaciones ADD CONSTRAINT planeaciones_academia_id_fkey
+      FOREIGN KEY (academia_id) REFERENCES public.academias(id) ON DELETE SET NULL;
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'planeaciones_unidad_aprendizaje_id_fkey') THEN
+    ALTER TABLE public.planeaciones ADD CONSTRAINT planeaciones_unidad_aprendizaje_id_fkey
+      FOREIGN KEY (unidad_aprendizaje_id) REFERENCES public.unidades_aprendizaje(id) ON DELETE SET NULL;
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_planeaciones_programa ON public.planeaciones USING btree (programa_id);
+CREATE INDEX IF NOT EXISTS idx_planeaciones_academia ON public.planeaciones USING btree (academia_id);
+CREATE INDEX IF NOT EXISTS idx_planeaciones_unidad_aprendizaje ON public.planeaciones USING btree (unidad_aprendizaje_id);
+
+-- =============================
+-- vincular_catalogos: liga las planeaciones sin referencia (p_planeacion NULL = todas)
+-- con la entrada del catálogo más parecida a su texto libre, dentro de su
+-- unidad académica y respetando la jerarquía ya ligada. Regresa filas ligadas.
+-- =============================
+CREATE OR REPLACE FUNCTION public.vincular_catalogos(p_planeacion bigint DEFAULT NULL, p_umbral real DEFAULT 0.6)
+    RETURNS integer
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+  n integer := 0;
+  k integer;
+BEGIN
+  -- Programas
+  WITH m AS (
+    SELECT p.id, (
+      SELECT pa.id
+      FROM public.programas_academicos pa
+      WHERE pa.unidad_academica_id = p.unidad_academica_id
+        AND public.similarity(public.normalizar_catalogo(pa.nombre), public.normalizar_catalogo(dg.programa_academico)) >= p_umbral
+      ORDER BY public.similarity(public.normalizar_catalogo(pa.nombre), public.normalizar_catalogo(dg.programa_academico)) DESC, pa.id
+      LIMIT 1
+    ) AS cat
+    FROM public.planeaciones p
+    JOIN public.planeacion_datos_generales dg ON dg.planeacion_id = p.id
+    WHERE p.programa_id IS NULL
+      AND btrim(COALESCE(dg.programa_academico, '')) <> ''
+      AND (p_planeacion IS NULL OR p.id = p_planeacion)
+  )
+  UPDATE public.planeaciones p SET programa_id = m.cat
+  FROM m WHERE m.id = p.id AND m.cat IS NOT NULL;
+  GET DIAGNOSTICS k = ROW_COUNT;
+  n := n + k;
+
+  -- Academias (del programa ligado o, si no hay, de la unidad académica)
+  WITH m AS (
+    SELECT p.id, (
+      SELECT a.id
+      FROM public.academias a
+      JOIN public.programas_academicos pa ON pa.id = a.programa_id
+      WHERE pa.unidad_academica_id = p.unidad_academica_id
+        AND (p.programa_id IS NULL OR a.programa_id = p.programa_id)
+        AND public.similarity(public.normalizar_catalogo(a.nombre), public.normalizar_catalogo(dg.academia)) >= p_umbral
+      ORDER BY public.similarity(public.normalizar_catalogo(a.nombre), public.normalizar_catalogo(dg.academia)) DESC, a.id
+      LIMIT 1
+    ) AS cat
+    FROM public.planeaciones p
+    JOIN public.planeacion_datos_generales dg ON dg.planeacion_id = p.id
+    WHERE p.academia_id IS NULL
+      AND btrim(COALESCE(dg.academia, '')) <> ''
+      AND (p_planeacion IS NULL OR p.id = p_planeacion)
+  )
+  UPDATE public.planeaciones p SET academia_id = m.cat
+  FROM m WHERE m.id = p.id AND m.cat IS NOT NULL;
+  GET DIAGNOSTICS k = ROW_COUNT;
+  n := n + k;
+
+  -- Unidades de aprendizaje (planeaciones.asignatura)
+  WITH m AS (
+    SELECT p.id, (
+      SELECT ua.id
+      FROM public.unidades_aprendizaje ua
+      JOIN public.academias a ON a.id = ua.academia_id
+      JOIN public.programas_academicos pa ON pa.id = a.programa_id
+      WHERE pa.unidad_academica_id = p.unidad_academica_id
+        AND (p.academia_id IS NULL OR ua.academia_id = p.academia_id)
+        AND (p.programa_id IS NULL OR a.programa_id = p.programa_id)
+        AND public.similarity(public.normalizar_catalogo(ua.nombre), public.normalizar_catalogo(p.asignatura)) >= p_umbral
+      ORDER BY public.similarity(public.normalizar_catalogo(ua.nombre), public.normalizar_catalogo(p.asignatura)) DESC, ua.id
+      LIMIT 1
+    ) AS cat
+    FROM public.planeaciones p
+    WHERE p.unidad_aprendizaje_id IS NULL
+      AND btrim(COALESCE(p.asignatura, '')) <> ''
+      AND (p_planeacion IS NULL OR p.id = p_planeacion)
+  )
+  UPDATE public.planeaciones p SET unidad_aprendizaje_id = m.cat
+  FROM m WHERE m.id = p.id AND m.cat IS NOT NULL;
+  GET DIAGNOSTICS k = ROW_COUNT;
+  n := n + k;
+
+  RETURN n;
+END;
+$$;
+
+-- =============================
+-- Migración del texto libre: cada variante (de la más usada a la menos) se
+-- agrega al catálogo solo si no se parece a una entrada existente; después
+-- se ligan las planeaciones a la entrada más parecida.
+-- =============================
+DO $$
+DECLARE
+  r record;
+BEGIN
+  FOR r IN
+    SELECT p.unidad_academica_id AS ua, btrim(dg.programa_academico) AS nombre, count(*) AS n
+    FROM public.planeaciones p
+    JOIN public.planeacion_datos_generales dg ON dg.planeacion_id = p.id
+    WHERE btrim(COALESCE(dg.programa_academico, '')) <> ''
+    GROUP BY 1, 2
+    ORDER BY 3 DESC, 2
+  LOOP
+    IF NOT EXISTS (
+      SELECT 1 FROM public.programas_academicos pa
+      WHERE pa.unidad_academica_id = r.ua
+        AND public.similarity(public.normalizar_catalogo(pa.nombre), public.normalizar_catalogo(r.nombre)) >= 0.6
+    ) THEN
+      INSERT INTO public.programas_academicos (unidad_academica_id, nombre) VALUES (r.ua, r.nombre)
+      ON CONFLICT DO NOTHING;
+    END IF;
+  END LOOP;
+  PERFORM public.vincular_catalogos();
+
+  FOR r IN
+    SELECT p.programa_id AS programa, btrim(dg.academia) AS nombre, count(*) AS n
+    FROM public.planeaciones p
+    JOIN public.planeacion_datos_generales dg ON dg.planeacion_id = p.id
+    WHERE p.programa_id IS NOT NULL AND btrim(COALESCE(dg.academia, '')) <> ''
+    GROUP BY 1, 2
+    ORDER BY 3 DESC, 2
+  LOOP
+    IF NOT EXISTS (
+      SELECT 1 FROM public.academias a
+      WHERE a.programa_id = r.programa
+        AND public.similarity(public.normalizar_catalogo(a.nombre), public.normalizar_catalogo(r.nombre)) >= 0.6
+    ) THEN
+      INSERT INTO public.academias (programa_id, nombre) VALUES (r.programa, r.nombre)
+      ON CONFLICT DO NOTHING;
+    END IF;
+  END LOOP;
+  PERFORM public.vincular_catalogos();
+
+  FOR r IN
+    SELECT p.academia_id AS academia, btrim(p.asignatura) AS nombre, count(*) AS n,
+           min(substring(dg.semestre_nivel FROM '\d+')::integer) AS semestre,
+           max(dg.creditos_tepic) AS creditos_tepic, max(dg.creditos_satca) AS creditos_satca,
+           max(dg.horas_teoria) AS horas_teoria, max(dg.horas_practica) AS horas_practica,
+           max(dg.horas_total) AS horas_total
+    FROM public.planeaciones p
+    LEFT JOIN public.planeacion_datos_generales dg ON dg.planeacion_id = p.id
+    WHERE p.academia_id IS NOT NULL AND btrim(COALESCE(p.asignatura, '')) <> ''
+    GROUP BY 1, 2
+    ORDER BY 3 DESC, 2
+  LOOP
+    IF NOT EXISTS (
+      SELECT 1 FROM public.unidades_aprendizaje ua
+      WHERE ua.academia_id = r.academia
+        AND public.similarity(public.normalizar_catalogo(ua.nombre), public.normalizar_catalogo(r.nombre)) >= 0.6
+    ) THEN
+      INSERT INTO public.unidades_aprendizaje
+        (academia_id, nombre, semestre, creditos_tepic, creditos_satca, horas_teoria, horas_practica, horas_total)
+      VALUES (
+        r.academia, r.nombre,
+        CASE WHEN r.semestre BETWEEN 1 AND 20 THEN r.semestre END,
+        r.creditos_tepic, r.creditos_satca, r.horas_teoria, r.horas_practica, r.horas_total
+      )
+      ON CONFLICT DO NOTHING;
+    END IF;
+  END LOOP;
+  PERFORM public.vincular_catalogos();
+END $$;
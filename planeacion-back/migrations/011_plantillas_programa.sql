@@ -0,0 +1,50 @@
+-- =============================
+-- 011: Plantillas de programa sintético
+-- Programa oficial de una unidad de aprendizaje (datos generales, unidades
+-- temáticas y bibliografía) con el mismo formato JSON que PUT /api/planeaciones/:id.
+-- Una planeación creada desde una plantilla guarda plantilla_id para
+-- comparar sus cambios durante la revisión.
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.plantillas_programa (
+    id bigserial PRIMARY KEY,
+    unidad_academica_id integer NOT NULL,
+    unidad_aprendizaje_id bigint,
+    nombre character varying(255) NOT NULL,
+    version character varying(50),
+    vigente boolean DEFAULT true NOT NULL,
+    datos_generales jsonb DEFAULT '{}'::jsonb NOT NULL,  -- DatosGeneralesPayload
+    unidades jsonb DEFAULT '[]'::jsonb NOT NULL,         -- [UnidadTematicaPayload]
+    referencias jsonb DEFAULT '[]'::jsonb NOT NULL,      -- [ReferenciaPayload]
+    created_by integer,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT plantillas_programa_unidad_academica_id_fkey FOREIGN KEY (unidad_academica_id)
+        REFERENCES public.unidades_academicas(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT plantillas_programa_unidad_aprendizaje_id_fkey FOREIGN KEY (unidad_aprendizaje_id)
+        REFERENCES public.unidades_aprendizaje(id) ON DELETE SET NULL,
+    CONSTRAINT plantillas_programa_created_by_fkey FOREIGN KEY (created_by)
+        REFERENCES public.usuarios(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_plantillas_programa_unidad
+    ON public.plantillas_programa USING btree (unidad_academica_id, vigente);
+CREATE INDEX IF NOT EXISTS idx_plantillas_programa_unidad_aprendizaje
+    ON public.plantillas_programa USING btree (unidad_aprendizaje_id);
+
+DROP TRIGGER IF EXISTS trg_plantillas_programa_updated_at ON public.plantillas_programa;
+CREATE TRIGGER trg_plantillas_programa_updated_at BEFORE UPDATE ON public.plantillas_programa
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
+
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS plantilla_id bigint;
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS plantilla_aplicada_at timestamp with time zone;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'planeaciones_plantilla_id_fkey') THEN
+    ALTER TABLE public.planeaciones ADD CONSTRAINT planeaciones_plantilla_id_fkey
+      FOREIGN KEY (plantilla_id) REFERENCES public.plantillas_programa(id) ON DELETE SET NULL;
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_planeaciones_plantilla ON public.planeaciones USING btree (plantilla_id);
@@ -0,0 +1,45 @@
+-- =============================
+-- 012: Administración de unidades académicas
+-- Alta y edición desde /api/admin/unidades. Las unidades no se borran:
+-- se desactivan (activa = false) para conservar usuarios y planeaciones.
+-- La abreviatura es única (sin distinguir mayúsculas ni espacios).
+-- =============================
+
+ALTER TABLE public.unidades_academicas ADD COLUMN IF NOT EXISTS activa boolean DEFAULT true NOT NULL;
+ALTER TABLE public.unidades_academicas ADD COLUMN IF NOT EXISTS created_at timestamp with time zone DEFAULT now() NOT NULL;
+ALTER TABLE public.unidades_academicas ADD COLUMN IF NOT EXISTS updated_at timestamp with time zone DEFAULT now() NOT NULL;
+
+-- Antes del índice único: abreviaturas vacías → NULL y, si dos unidades
+-- comparten abreviatura, la de menor id la conserva y las demás quedan con
+-- sufijo "-<id>" (se listan con NOTICE para corregirlas desde el admin).
+UPDATE public.unidades_academicas SET abreviatura = NULL WHERE btrim(abreviatura) = '';
+
+DO $$
+DECLARE
+  r record;
+BEGIN
+  FOR r IN
+    SELECT id, abreviatura
+    FROM (
+      SELECT id, abreviatura,
+             row_number() OVER (PARTITION BY lower(btrim(abreviatura)) ORDER BY id) AS n
+      FROM public.unidades_academicas
+      WHERE abreviatura IS NOT NULL
+    ) d
+    WHERE n > 1
+  LOOP
+    UPDATE public.unidades_academicas
+    SET abreviatura = left(btrim(r.abreviatura), 50 - length('-' || r.id)) || '-' || r.id
+    WHERE id = r.id;
+    RAISE NOTICE 'unidad académica %: abreviatura duplicada "%" renombrada a "%"',
+      r.id, r.abreviatura, left(btrim(r.abreviatura), 50 - length('-' || r.id)) || '-' || r.id;
+  END LOOP;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_unidades_academicas_abreviatura
+    ON public.unidades_academicas USING btree (lower(btrim(abreviatura)))
+    WHERE abreviatura IS NOT NULL;
+
+DROP TRIGGER IF EXISTS trg_unidades_academicas_updated_at ON public.unidades_academicas;
+CREATE TRIGGER trg_unidades_academicas_updated_at BEFORE UPDATE ON public.unidades_academicas
+    FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
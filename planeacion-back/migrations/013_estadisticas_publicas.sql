@@ -0,0 +1,102 @@
+-- =============================
+-- 013: Estadísticas públicas agregadas
+-- Vistas materializadas que sirven /api/public/stats sin recorrer las tablas
+-- en cada petición. El backend las refresca (CONCURRENTLY) cuando generado_at
+-- es más viejo que STATS_REFRESH_SECONDS, o a pedido en
+-- POST /api/admin/estadisticas/refrescar.
+--
+-- mv_estadisticas_planeaciones: una fila por dimensión
+--   global | unidad | programa | academia | periodo | unidad_periodo
+-- Las llaves sin valor se guardan como 0 / '' ("sin programa", "sin periodo").
+-- =============================
+
+DROP MATERIALIZED VIEW IF EXISTS public.mv_estadisticas_planeaciones;
+
+CREATE MATERIALIZED VIEW public.mv_estadisticas_planeaciones AS
+WITH base AS (
+  SELECT
+    p.id,
+    p.docente_id,
+    p.unidad_academica_id,
+    p.programa_id,
+    p.academia_id,
+    COALESCE(NULLIF(btrim(p.periodo), ''), '') AS periodo,
+    p.status,
+    p.created_at,
+    p.finalizada_at,
+    p.secciones_completas,
+    p.updated_at,
+    (SELECT COUNT(*) FROM public.unidades_tematicas ut WHERE ut.planeacion_id = p.id) AS unidades,
+    (SELECT COUNT(*) FROM public.sesiones_didacticas sd
+       JOIN public.unidades_tematicas ut ON ut.id = sd.unidad_tematica_id
+      WHERE ut.planeacion_id = p.id) AS sesiones
+  FROM public.planeaciones p
+)
+SELECT
+  CASE GROUPING(unidad_academica_id, programa_id, academia_id, periodo)
+    WHEN 15 THEN 'global'
+    WHEN 7  THEN 'unidad'
+    WHEN 3  THEN 'programa'
+    WHEN 1  THEN 'academia'
+    WHEN 14 THEN 'periodo'
+    WHEN 6  THEN 'unidad_periodo'
+  END AS dimension,
+  COALESCE(unidad_academica_id, 0) AS unidad_academica_id,
+  COALESCE(programa_id, 0) AS programa_id,
+  COALESCE(academia_id, 0) AS academia_id,
+  COALESCE(periodo, '') AS periodo,
+  COUNT(*)::bigint AS planeaciones_total,
+  COUNT(*) FILTER (WHERE status = 'finalizada')::bigint AS planeaciones_finalizadas,
+  COUNT(DISTINCT docente_id)::bigint AS docentes_participantes,
+  COALESCE(SUM(unidades), 0)::bigint AS unidades_tematicas_total,
+  COALESCE(SUM(sesiones), 0)::bigint AS sesiones_didacticas_total,
+  AVG(EXTRACT(EPOCH FROM (finalizada_at - created_at)) / 86400.0)
+    FILTER (WHERE status = 'finalizada' AND finalizada_at IS NOT NULL) AS promedio_dias_finalizacion,
+  COUNT(*) FILTER (WHERE (secciones_completas->>'datos')::boolean)::bigint AS secciones_datos,
+  COUNT(*) FILTER (WHERE (secciones_completas->>'relaciones')::boolean)::bigint AS secciones_relaciones,
+  COUNT(*) FILTER (WHERE (secciones_completas->>'organizacion')::boolean)::bigint AS secciones_organizacion,
+  COUNT(*) FILTER (WHERE (secciones_completas->>'plagio')::boolean)::bigint AS secciones_plagio,
+  COUNT(*) FILTER (WHERE (secciones_completas->>'referencias')::boolean)::bigint AS secciones_referencias,
+  MAX(updated_at) AS ultima_actualizacion,
+  MAX(finalizada_at) FILTER (WHERE status = 'finalizada') AS ultima_publicacion,
+  now() AS generado_at
+FROM base
+GROUP BY GROUPING SETS (
+  (),
+  (unidad_academica_id),
+  (unidad_academica_id, programa_id),
+  (unidad_academica_id, programa_id, academia_id),
+  (periodo),
+  (unidad_academica_id, periodo)
+);
+
+-- La fila global existe aunque no haya planeaciones (GROUPING SETS con () siempre la produce).
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_estadisticas_planeaciones_llave
+    ON public.mv_estadisticas_planeaciones (dimension, unidad_academica_id, programa_id, academia_id, periodo);
+
+-- Serie semanal de planeaciones finalizadas (global: unidad_academica_id = 0)
+DROP MATERIALIZED VIEW IF EXISTS public.mv_finalizadas_por_semana;
+
+CREATE MATERIALIZED VIEW public.mv_finalizadas_por_semana AS
+SELECT
+  date_trunc('week', p.finalizada_at)::date AS semana,
+  COALESCE(p.unidad_academica_id, 0) AS unidad_academica_id,
+  COUNT(*)::bigint AS finalizadas
+FROM public.planeaciones p
+WHERE p.status = 'finalizada' AND p.finalizada_at IS NOT NULL
+GROUP BY GROUPING SETS (
+  (date_trunc('week', p.finalizada_at)::date),
+  (date_trunc('week', p.finalizada_at)::date, p.unidad_academica_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_finalizadas_por_semana_llave
+    ON public.mv_finalizadas_por_semana (unidad_academica_id, semana);
+
+CREATE OR REPLACE FUNCTION public.refrescar_estadisticas_publicas() RETURNS void
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  REFRESH MATERIALIZED VIEW CONCURRENTLY public.mv_estadisticas_planeaciones;
+  REFRESH MATERIALIZED VIEW CONCURRENTLY public.mv_finalizadas_por_semana;
+END;
+$$;
@@ -0,0 +1,26 @@
+-- =============================
+-- 014: Bitácora de auditoría
+-- Una fila por operación que modifica datos (planeaciones, autenticación),
+-- escrita en la misma transacción que el cambio. entidad_id no tiene llave
+-- foránea: el registro sobrevive a la eliminación de la entidad.
+-- =============================
+
+CREATE TABLE IF NOT EXISTS public.audit_log (
+    id bigserial PRIMARY KEY,
+    actor_id integer,
+    actor_email character varying(255),
+    accion character varying(50) NOT NULL,   -- planeacion.crear, planeacion.finalizar, auth.login, ...
+    entidad character varying(50) NOT NULL,  -- planeacion | usuario
+    entidad_id bigint,
+    ip character varying(64),
+    user_agent text,
+    cambios jsonb,                           -- resumen compacto (campos, status, conteos)
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT audit_log_actor_id_fkey FOREIGN KEY (actor_id)
+        REFERENCES public.usuarios(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON public.audit_log USING btree (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entidad ON public.audit_log USING btree (entidad, entidad_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON public.audit_log USING btree (actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_accion ON public.audit_log USING btree (accion, created_at DESC);
@@ -0,0 +1,34 @@
+-- =============================
+-- 017: Slugs públicos estables y personalizables
+-- - Un slug por planeación (índice único) con formato a-z0-9 separado por
+--   guiones. La restricción es NOT VALID: los slugs existentes no se revisan.
+-- - planeacion_slugs_historial guarda los slugs anteriores de cada planeación
+--   para responder 301 al slug actual. Un slug del historial no puede
+--   usarlo otra planeación (los enlaces viejos seguirían llegando a ella).
+-- =============================
+
+CREATE UNIQUE INDEX IF NOT EXISTS planeaciones_slug_uniq
+    ON public.planeaciones USING btree (slug)
+    WHERE ((slug IS NOT NULL) AND (slug <> ''::text));
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_constraint WHERE conname = 'planeaciones_slug_formato_check'
+  ) THEN
+    ALTER TABLE public.planeaciones
+      ADD CONSTRAINT planeaciones_slug_formato_check
+      CHECK (slug IS NULL OR slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$') NOT VALID;
+  END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS public.planeacion_slugs_historial (
+    slug text PRIMARY KEY,
+    planeacion_id bigint NOT NULL,
+    reemplazado_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT planeacion_slugs_historial_planeacion_id_fkey FOREIGN KEY (planeacion_id)
+        REFERENCES public.planeaciones(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_planeacion_slugs_historial_planeacion
+    ON public.planeacion_slugs_historial USING btree (planeacion_id);
@@ -0,0 +1,9 @@
+-- =============================
+-- 018: Desvinculación explícita de Google
+-- DELETE /api/me/identidades/google marca google_desvinculado_at; mientras
+-- esté marcado, el login con Google no vuelve a vincular la cuenta por email
+-- (hay que entrar con contraseña y usar POST /api/me/identidades/google).
+-- =============================
+
+ALTER TABLE public.usuarios
+    ADD COLUMN IF NOT EXISTS google_desvinculado_at timestamp with time zone;
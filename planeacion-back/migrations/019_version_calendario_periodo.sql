@@ -0,0 +1,31 @@
+-- =============================
+-- 019: Versión del calendario de un periodo escolar
+-- calendario_modificado_at cambia con cualquier alta, cambio o baja de sus
+-- días inhábiles y ventanas de evaluación (las bajas no dejan otra huella).
+-- El ETag del .ics de una planeación la toma en cuenta.
+-- =============================
+
+ALTER TABLE public.periodos_escolares
+    ADD COLUMN IF NOT EXISTS calendario_modificado_at timestamp with time zone;
+
+CREATE OR REPLACE FUNCTION public.tocar_calendario_periodo() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  IF TG_OP <> 'DELETE' THEN
+    UPDATE public.periodos_escolares SET calendario_modificado_at = now() WHERE id = NEW.periodo_id;
+  END IF;
+  IF TG_OP <> 'INSERT' THEN
+    UPDATE public.periodos_escolares SET calendario_modificado_at = now() WHERE id = OLD.periodo_id;
+  END IF;
+  RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_dias_inhabiles_calendario ON public.dias_inhabiles;
+CREATE TRIGGER trg_dias_inhabiles_calendario AFTER INSERT OR UPDATE OR DELETE ON public.dias_inhabiles
+    FOR EACH ROW EXECUTE FUNCTION public.tocar_calendario_periodo();
+
+DROP TRIGGER IF EXISTS trg_ventanas_evaluacion_calendario ON public.ventanas_evaluacion;
+CREATE TRIGGER trg_ventanas_evaluacion_calendario AFTER INSERT OR UPDATE OR DELETE ON public.ventanas_evaluacion
+    FOR EACH ROW EXECUTE FUNCTION public.tocar_calendario_periodo();
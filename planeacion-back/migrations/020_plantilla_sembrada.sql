@@ -0,0 +1,11 @@
+-- =============================
+-- 020: Contenido sembrado desde la plantilla
+-- Las plantillas se editan en su lugar; para que las diferencias muestren
+-- solo los cambios del docente, la planeación guarda la versión y el
+-- contenido (datos_generales, unidades, referencias) con que se sembró.
+-- Las planeaciones sembradas antes de esta migración se comparan contra el
+-- contenido actual de la plantilla.
+-- =============================
+
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS plantilla_version character varying(50);
+ALTER TABLE public.planeaciones ADD COLUMN IF NOT EXISTS plantilla_contenido jsonb;
@@ -367,8 +367,16 @@ export default function PlaneacionForm({
           programa_academico: data.programa_academico ?? "",
           academia: data.academia ?? "",
           unidad_aprendizaje_nombre: data.unidad_aprendizaje_nombre ?? "",
+          programa_id: data.programa_id ?? null,
+          academia_id: data.academia_id ?? null,
+          unidad_aprendizaje_id: data.unidad_aprendizaje_id ?? null,
           area_formacion: data.area_formacion ?? undefined,
           modalidad: data.modalidad ?? "Escolarizada",
+          fecha_elaboracion: data.fecha_elaboracion ?? "",
+          docente_autor: data.docente_autor ?? "",
+          tipo_unidad: { ...current.tipo_unidad, ...(data.tipo_unidad ?? {}) },
+          semanas_por_semestre:
+            data.semanas_por_semestre ?? current.semanas_por_semestre,
           sesiones_por_semestre:
             data.sesiones_por_semestre ?? current.sesiones_por_semestre,
           sesiones_por_semestre_det: {
@@ -473,6 +481,10 @@ export default function PlaneacionForm({
                       evidencias: Array.isArray(b.evidencias) ? b.evidencias : [],
                       instrumentos: Array.isArray(b.instrumentos) ? b.instrumentos : [],
                       valor_porcentual: Number(b.valor_porcentual ?? 0),
+                      fecha: b.fecha ?? null,
+                      hora_inicio: b.hora_inicio ?? null,
+                      hora_fin: b.hora_fin ?? null,
+                      espacio: b.espacio ?? null,
                     }))
                   : [
                       {
@@ -557,8 +569,15 @@ export default function PlaneacionForm({
       programa_academico: values.programa_academico || null,
       academia: values.academia || null,
       unidad_aprendizaje_nombre: values.unidad_aprendizaje_nombre || null,
+      programa_id: values.programa_id ?? null,
+      academia_id: values.academia_id ?? null,
+      unidad_aprendizaje_id: values.unidad_aprendizaje_id ?? null,
       area_formacion: values.area_formacion || null,
       modalidad: values.modalidad || null,
+      fecha_elaboracion: values.fecha_elaboracion || null,
+      docente_autor: values.docente_autor || null,
+      tipo_unidad: values.tipo_unidad ?? null,
+      semanas_por_semestre: values.semanas_por_semestre ?? null,
       sesiones_por_semestre: values.sesiones_por_semestre ?? null,
       sesiones_aula: values.sesiones_por_semestre_det?.aula ?? null,
       sesiones_laboratorio:
@@ -680,6 +699,10 @@ export default function PlaneacionForm({
                     evidencias: cleanEvidencias,
                     instrumentos: cleanInstrumentos,
                     valor_porcentual: b.valor_porcentual ?? 0,
+                    fecha: b.fecha ?? null,
+                    hora_inicio: b.hora_inicio ?? null,
+                    hora_fin: b.hora_fin ?? null,
+                    espacio: b.espacio ?? null,
                   };
                 })
               : [],
@@ -35,6 +35,11 @@ export const UnidadTematicaSchema = z.object({
     evidencias: z.array(z.string().trim().min(1)).min(1),
     valor_porcentual: z.number().min(0).max(100),
     instrumentos: z.array(z.string().trim().min(1)).min(1),
+    // Programación (la asigna el backend con /programar)
+    fecha: z.string().nullable().optional(),
+    hora_inicio: z.string().nullable().optional(),
+    hora_fin: z.string().nullable().optional(),
+    espacio: z.string().nullable().optional(),
   })).min(1, "Agrega al menos una sesión"),
   precisiones: optStr,
 });
@@ -43,6 +48,10 @@ export const PlaneacionSchema = z.object({
   fecha_elaboracion: z.string().trim().min(1, "Requerido"),
   unidad_academica_id: z.string().trim().min(1, "Requerido"),
   programa_academico: z.string().trim().min(1, "Requerido"),
+  // Referencias al catálogo académico (opcionales; el texto sigue siendo obligatorio)
+  programa_id: z.number().int().positive().nullable().optional(),
+  academia_id: z.number().int().positive().nullable().optional(),
+  unidad_aprendizaje_id: z.number().int().positive().nullable().optional(),
   plan_estudios_anio: z.number().int().positive(),
   unidad_aprendizaje_nombre: z.string().trim().min(1, "Requerido"), // ← SE MANTIENE AQUÍ
   semestre_nivel: z.string().trim().min(1, "Requerido"),